@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sourdough/internal/auth"
+	"sourdough/internal/database"
+	"sourdough/internal/exporter"
+	"sourdough/internal/importer"
+	"sourdough/internal/recipes"
+	"time"
+)
+
+// runCommand runs a CLI subcommand, e.g. `sourdough import -user google:1234 export.paprikarecipes`
+// or `sourdough export -user google:1234`.
+// Commands only need the database, so they work without the web server's configuration.
+func runCommand(db *database.DB, name string, args []string) error {
+	switch name {
+	case "import":
+		return runImport(db, args)
+	case "export":
+		return runExport(db, args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+func runImport(db *database.DB, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	userID := flags.String("user", "", "the user to import recipes for, as stored in users.user_id (e.g. google:1234)")
+	format := flags.String("format", string(importer.FormatAuto), "the export format: auto, sourdough, paprika, mealie, tandoor or cooklang")
+	flags.Parse(args)
+
+	if *userID == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("a user and at least one export file are required")
+	}
+
+	user, err := findUser(db, *userID)
+	if err != nil {
+		return err
+	}
+
+	recipeImporter := importer.NewImporter(recipes.NewRepository(db))
+
+	for _, path := range flags.Args() {
+		report, err := importPath(recipeImporter, user.Id, importer.Format(*format), path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, result := range report.Results {
+			if result.Err != nil {
+				fmt.Printf("FAILED  %s: %v\n", result.Title, result.Err)
+			} else {
+				fmt.Printf("OK      %s (#%d)\n", result.Title, result.RecipeID)
+			}
+		}
+
+		fmt.Printf("%s (%s): %s\n", path, report.Format, report.Summary())
+	}
+
+	return nil
+}
+
+// importPath imports an export file, or a directory of Cooklang files.
+func importPath(recipeImporter *importer.Importer, userID int, format importer.Format, path string) (*importer.Report, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return recipeImporter.ImportDirectory(userID, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return recipeImporter.Import(userID, format, filepath.Base(path), data)
+}
+
+func runExport(db *database.DB, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	userID := flags.String("user", "", "the user to export recipes for, as stored in users.user_id (e.g. google:1234)")
+	output := flags.String("o", exporter.Filename(time.Now()), "where to write the archive")
+	flags.Parse(args)
+
+	if *userID == "" {
+		flags.Usage()
+		return errors.New("a user is required")
+	}
+
+	user, err := findUser(db, *userID)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	manifest, err := exporter.NewExporter(recipes.NewRepository(db)).Export(user.Id, file)
+	if err != nil {
+		os.Remove(*output)
+		return err
+	}
+
+	fmt.Printf("Exported %d recipes to %s\n", len(manifest.Recipes), *output)
+	return nil
+}
+
+func findUser(db *database.DB, userID string) (*auth.User, error) {
+	user, err := auth.NewRepository(db).GetByProviderId(userID)
+	if err != nil {
+		return nil, err
+	} else if user == nil {
+		return nil, fmt.Errorf("no user %q; they need to sign in once first", userID)
+	}
+
+	return user, nil
+}
@@ -1,17 +1,29 @@
 package auth
 
 import (
+	"slices"
 	"sourdough/internal/shared"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type Middleware struct {
-	handler *Handler
+	handler      *Handler
+	adminUserIds []string
 }
 
-func NewMiddleware(handler *Handler) *Middleware {
-	return &Middleware{handler: handler}
+// adminUserIds are provider-qualified ids as stored in users.user_id, e.g. "google:1234".
+// Surrounding spaces and empty entries, as left by splitting "a, b," on commas, are ignored.
+func NewMiddleware(handler *Handler, adminUserIds []string) *Middleware {
+	var ids []string
+	for _, id := range adminUserIds {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	return &Middleware{handler: handler, adminUserIds: ids}
 }
 
 func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
@@ -38,5 +50,15 @@ func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
 	}
 
 	c.Locals("user", userInfo)
+	return c.Next()
+}
+
+// RequireAdmin must run after RequireAuth.
+func (m *Middleware) RequireAdmin(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*shared.UserInfo)
+	if !ok || !slices.Contains(m.adminUserIds, user.UserId) {
+		return c.Status(403).SendString("Forbidden")
+	}
+
 	return c.Next()
 }
\ No newline at end of file
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"sourdough/internal/shared"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	// As read from ADMIN_USER_IDS
+	middleware := NewMiddleware(nil, strings.Split(" google:1234, github:99,", ","))
+
+	tests := []struct {
+		name   string
+		user   *shared.UserInfo
+		status int
+	}{
+		{"admin", &shared.UserInfo{Id: 1, UserId: "google:1234"}, 200},
+		{"admin after a space", &shared.UserInfo{Id: 2, UserId: "github:99"}, 200},
+		{"same id from another provider", &shared.UserInfo{Id: 3, UserId: "github:1234"}, 403},
+		{"not an admin", &shared.UserInfo{Id: 4, UserId: "google:5678"}, 403},
+		{"empty user id", &shared.UserInfo{Id: 5, UserId: ""}, 403},
+		{"signed out", nil, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/admin", func(c *fiber.Ctx) error {
+				if tt.user != nil {
+					c.Locals("user", tt.user)
+				}
+				return c.Next()
+			}, middleware.RequireAdmin, func(c *fiber.Ctx) error {
+				return c.SendString("ok")
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/admin", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
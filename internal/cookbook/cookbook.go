@@ -0,0 +1,230 @@
+package cookbook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sourdough/internal/recipes"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	PAGE_MARGIN       = 20.0
+	LINE_HEIGHT       = 6.0
+	PHOTO_HEIGHT      = 70.0
+	MAX_RECIPES       = 200
+	BODY_FONT         = "Times"
+	HEADING_FONT      = "Helvetica"
+	DEFAULT_BOOK_NAME = "My Cookbook"
+)
+
+var ErrNoRecipes = errors.New("pick at least one recipe for the cookbook")
+
+// Cookbook is a selection of recipes to print as a PDF. Photo is the first photo of each
+// recipe, or nil.
+type Cookbook struct {
+	Title   string
+	Entries []Entry
+}
+
+type Entry struct {
+	Recipe *recipes.Recipe
+	Photo  *recipes.Photo
+}
+
+// Builder collects a user's recipes into a Cookbook.
+type Builder struct {
+	repo *recipes.Repository
+}
+
+func NewBuilder(repo *recipes.Repository) *Builder {
+	return &Builder{repo: repo}
+}
+
+// Build loads the recipes in the order given, skipping any that don't belong to the user.
+func (b *Builder) Build(userID int, title string, recipeIDs []int) (*Cookbook, error) {
+	if len(recipeIDs) > MAX_RECIPES {
+		return nil, fmt.Errorf("a cookbook can have at most %d recipes", MAX_RECIPES)
+	}
+
+	book := &Cookbook{Title: strings.TrimSpace(title)}
+	if book.Title == "" {
+		book.Title = DEFAULT_BOOK_NAME
+	}
+
+	for _, id := range recipeIDs {
+		recipe, err := b.repo.Get(id)
+		if err != nil {
+			return nil, err
+		} else if recipe == nil || recipe.UserID != userID {
+			continue
+		}
+
+		if recipe, err = b.repo.Expand(recipe); err != nil {
+			return nil, err
+		}
+
+		entry := Entry{Recipe: recipe}
+
+		photoIDs, err := b.repo.GetPhotoIDs(recipe.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(photoIDs) > 0 {
+			if entry.Photo, err = b.repo.GetPhoto(photoIDs[0]); err != nil {
+				return nil, err
+			}
+		}
+
+		book.Entries = append(book.Entries, entry)
+	}
+
+	if len(book.Entries) == 0 {
+		return nil, ErrNoRecipes
+	}
+
+	return book, nil
+}
+
+// Filename names the PDF after the cookbook, or the recipe when there's only one.
+func (c *Cookbook) Filename() string {
+	name := c.Title
+	if len(c.Entries) == 1 {
+		name = c.Entries[0].Recipe.Title
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if name == "" {
+		name = DEFAULT_BOOK_NAME
+	}
+
+	return name + ".pdf"
+}
+
+// Render writes the cookbook as a PDF: a cover page, a table of contents, each recipe starting
+// on a new page and an index of ingredients. A single recipe is printed on its own, without the rest.
+func (c *Cookbook) Render(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(PAGE_MARGIN, PAGE_MARGIN, PAGE_MARGIN)
+	pdf.SetAutoPageBreak(true, PAGE_MARGIN)
+	pdf.SetTitle(c.Title, true)
+	pdf.SetCreator("sourdough", true)
+
+	r := &renderer{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+
+	pdf.SetFooterFunc(func() {
+		if pdf.PageNo() == 1 && len(c.Entries) > 1 {
+			return
+		}
+
+		pdf.SetY(-PAGE_MARGIN + 5)
+		pdf.SetFont(HEADING_FONT, "", 9)
+		pdf.CellFormat(0, 5, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	if len(c.Entries) == 1 {
+		r.recipe(c.Entries[0], 0)
+		return output(pdf, w)
+	}
+
+	r.cover(c.Title, len(c.Entries))
+
+	links := make([]int, len(c.Entries))
+	for i := range c.Entries {
+		links[i] = pdf.AddLink()
+	}
+
+	r.contents(c.Entries, links)
+
+	index := map[string][]int{}
+
+	for i, entry := range c.Entries {
+		r.recipe(entry, links[i])
+
+		// The recipe's page number is only known once it's been laid out, so the contents
+		// use a placeholder that's filled in when the PDF is written
+		page := strconv.Itoa(r.firstPage)
+		pdf.RegisterAlias(pageAlias(i), page)
+
+		for _, name := range ingredientNames(entry.Recipe) {
+			index[name] = appendUnique(index[name], r.firstPage)
+		}
+	}
+
+	r.index(index)
+
+	return output(pdf, w)
+}
+
+func output(pdf *fpdf.Fpdf, w io.Writer) error {
+	// Write to a buffer first, so a failure partway through doesn't leave half a PDF behind
+	var buffer bytes.Buffer
+	if err := pdf.Output(&buffer); err != nil {
+		return err
+	}
+
+	_, err := buffer.WriteTo(w)
+	return err
+}
+
+func pageAlias(i int) string {
+	return "{page:" + strconv.Itoa(i) + "}"
+}
+
+// ingredientNames is what the index lists a recipe under: each ingredient without its amount.
+func ingredientNames(recipe *recipes.Recipe) []string {
+	var names []string
+
+	for _, line := range recipe.Ingredients {
+		name := strings.ToLower(strings.TrimSpace(recipes.ParseIngredient(line).Name))
+
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func appendUnique(pages []int, page int) []int {
+	for _, existing := range pages {
+		if existing == page {
+			return pages
+		}
+	}
+
+	return append(pages, page)
+}
+
+func sortedKeys(index map[string][]int) []string {
+	keys := make([]string, 0, len(index))
+	for key := range index {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// allRecipeIDs is a JavaScript array of the recipes' IDs, as strings to match checkbox values.
+func allRecipeIDs(userRecipes []*recipes.Recipe) string {
+	ids := make([]string, len(userRecipes))
+
+	for i, recipe := range userRecipes {
+		ids[i] = strconv.Quote(strconv.Itoa(recipe.ID))
+	}
+
+	return "[" + strings.Join(ids, ",") + "]"
+}
@@ -0,0 +1,74 @@
+package cookbook
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sourdough/internal/recipes"
+	"sourdough/internal/shared"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	builder *Builder
+	repo    *recipes.Repository
+}
+
+func NewHandler(builder *Builder, repo *recipes.Repository) *Handler {
+	return &Handler{builder: builder, repo: repo}
+}
+
+func (h *Handler) GetCookbookForm(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*shared.UserInfo)
+	if !ok {
+		return c.Status(401).Redirect("/login")
+	}
+
+	userRecipes, err := h.repo.GetForUser(user.Id)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := CookbookView(userRecipes)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+// GetCookbookPDF builds a PDF of the recipes in the "recipe" query parameters, in order.
+func (h *Handler) GetCookbookPDF(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*shared.UserInfo)
+	if !ok {
+		return c.Status(401).Redirect("/login")
+	}
+
+	var recipeIDs []int
+	for _, value := range c.Context().QueryArgs().PeekMulti("recipe") {
+		id, err := strconv.Atoi(string(value))
+		if err != nil {
+			return c.Status(400).SendString("Invalid recipe ID")
+		}
+
+		recipeIDs = append(recipeIDs, id)
+	}
+
+	book, err := h.builder.Build(user.Id, c.Query("title"), recipeIDs)
+	if errors.Is(err, ErrNoRecipes) {
+		return c.Status(400).SendString(err.Error())
+	} else if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", book.Filename()))
+
+	if err := book.Render(c.Response().BodyWriter()); err != nil {
+		log.Printf("Failed to render cookbook for user %d: %v", user.Id, err)
+		c.Response().Header.Del("Content-Disposition")
+		c.Set("Content-Type", "text/plain")
+		return c.Status(500).SendString("Failed to create the cookbook")
+	}
+
+	return nil
+}
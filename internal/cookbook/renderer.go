@@ -0,0 +1,224 @@
+package cookbook
+
+import (
+	"bytes"
+	"fmt"
+	"sourdough/internal/recipes"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// renderer lays out the parts of a cookbook. The core PDF fonts only cover Windows-1252, so all
+// text goes through tr first.
+type renderer struct {
+	pdf       *fpdf.Fpdf
+	tr        func(string) string
+	firstPage int
+	photos    int
+}
+
+func (r *renderer) cover(title string, count int) {
+	r.pdf.AddPage()
+
+	_, height := r.pdf.GetPageSize()
+	r.pdf.SetY(height / 3)
+
+	r.pdf.SetFont(HEADING_FONT, "B", 36)
+	r.pdf.MultiCell(0, 16, r.tr(title), "", "C", false)
+
+	r.pdf.Ln(6)
+	r.pdf.SetFont(BODY_FONT, "I", 14)
+	r.pdf.CellFormat(0, 8, fmt.Sprintf("%d recipes", count), "", 1, "C", false, 0, "")
+}
+
+func (r *renderer) contents(entries []Entry, links []int) {
+	r.pdf.AddPage()
+	r.heading("Contents")
+
+	width, _ := r.pdf.GetPageSize()
+	pageColumn := 15.0
+	titleColumn := width - 2*PAGE_MARGIN - pageColumn
+
+	r.pdf.SetFont(BODY_FONT, "", 12)
+
+	for i, entry := range entries {
+		r.pdf.CellFormat(titleColumn, 8, r.truncate(entry.Recipe.Title, titleColumn), "", 0, "L", false, links[i], "")
+		r.pdf.CellFormat(pageColumn, 8, pageAlias(i), "", 1, "L", false, links[i], "")
+	}
+}
+
+func (r *renderer) recipe(entry Entry, link int) {
+	recipe := entry.Recipe
+
+	r.pdf.AddPage()
+	r.firstPage = r.pdf.PageNo()
+
+	if link != 0 {
+		r.pdf.SetLink(link, 0, r.firstPage)
+	}
+
+	r.pdf.Bookmark(r.tr(recipe.Title), 0, -1)
+
+	r.pdf.SetFont(HEADING_FONT, "B", 22)
+	r.pdf.MultiCell(0, 10, r.tr(recipe.Title), "", "L", false)
+
+	var facts []string
+	if recipe.Servings > 0 {
+		facts = append(facts, "Serves "+strconv.Itoa(recipe.Servings))
+	}
+	if recipe.PrepTime != "" {
+		facts = append(facts, "Prep "+recipe.PrepTimeText())
+	}
+	if recipe.CookTime != "" {
+		facts = append(facts, "Cook "+recipe.CookTimeText())
+	}
+	if recipe.ActiveMinutes != nil {
+		facts = append(facts, "Active "+recipe.ActiveTimeText())
+	}
+	if recipe.InactiveMinutes != nil {
+		facts = append(facts, "Inactive "+recipe.InactiveTimeText())
+	}
+
+	if len(facts) > 0 {
+		r.pdf.SetFont(BODY_FONT, "I", 11)
+		r.pdf.MultiCell(0, LINE_HEIGHT, r.tr(strings.Join(facts, "  ·  ")), "", "L", false)
+	}
+
+	r.pdf.Ln(4)
+
+	if entry.Photo != nil {
+		r.photo(entry.Photo)
+	}
+
+	r.subheading("Ingredients")
+	r.pdf.SetFont(BODY_FONT, "", 11)
+
+	for _, group := range recipes.Groups(recipe.Ingredients, recipe.IngredientSections) {
+		r.groupTitle(group.Title)
+		for _, ingredient := range group.Lines {
+			r.listItem("-", ingredient)
+		}
+	}
+
+	r.subheading("Directions")
+	r.pdf.SetFont(BODY_FONT, "", 11)
+
+	step := 1
+	for _, group := range recipes.Groups(recipe.Directions, recipe.DirectionSections) {
+		r.groupTitle(group.Title)
+		for _, direction := range group.Lines {
+			r.listItem(strconv.Itoa(step)+".", direction)
+			step++
+		}
+	}
+
+	if notes := strings.TrimSpace(recipe.Notes); notes != "" {
+		r.subheading("Notes")
+		r.pdf.SetFont(BODY_FONT, "", 11)
+		r.pdf.MultiCell(0, LINE_HEIGHT, r.tr(notes), "", "L", false)
+	}
+}
+
+// photo places the recipe's photo at a fixed height. Formats the PDF library can't embed are left out.
+func (r *renderer) photo(photo *recipes.Photo) {
+	imageType := map[string]string{"image/jpeg": "JPG", "image/png": "PNG", "image/gif": "GIF"}[photo.ContentType]
+	if imageType == "" {
+		return
+	}
+
+	r.photos++
+	name := "photo-" + strconv.Itoa(r.photos)
+	options := fpdf.ImageOptions{ImageType: imageType, ReadDpi: false}
+
+	info := r.pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(photo.Data))
+	if r.pdf.Err() {
+		// A broken photo shouldn't cost the user the whole cookbook
+		r.pdf.ClearError()
+		return
+	}
+
+	width, _ := r.pdf.GetPageSize()
+	photoWidth := min(info.Width()*PHOTO_HEIGHT/info.Height(), width-2*PAGE_MARGIN)
+
+	r.pdf.ImageOptions(name, PAGE_MARGIN, r.pdf.GetY(), photoWidth, 0, true, options, 0, "")
+	r.pdf.Ln(4)
+}
+
+func (r *renderer) index(index map[string][]int) {
+	if len(index) == 0 {
+		return
+	}
+
+	r.pdf.AddPage()
+	r.heading("Index of Ingredients")
+	r.pdf.SetFont(BODY_FONT, "", 11)
+
+	letter := ""
+
+	for _, name := range sortedKeys(index) {
+		if first := strings.ToUpper(string([]rune(name)[0])); first != letter {
+			letter = first
+			r.pdf.Ln(2)
+			r.pdf.SetFont(HEADING_FONT, "B", 12)
+			r.pdf.CellFormat(0, 7, r.tr(letter), "", 1, "L", false, 0, "")
+			r.pdf.SetFont(BODY_FONT, "", 11)
+		}
+
+		var pages []string
+		for _, page := range index[name] {
+			pages = append(pages, strconv.Itoa(page))
+		}
+
+		r.pdf.MultiCell(0, LINE_HEIGHT, r.tr(name+", "+strings.Join(pages, ", ")), "", "L", false)
+	}
+}
+
+func (r *renderer) heading(text string) {
+	r.pdf.SetFont(HEADING_FONT, "B", 22)
+	r.pdf.CellFormat(0, 12, r.tr(text), "", 1, "L", false, 0, "")
+	r.pdf.Ln(4)
+}
+
+func (r *renderer) subheading(text string) {
+	r.pdf.Ln(3)
+	r.pdf.SetFont(HEADING_FONT, "B", 14)
+	r.pdf.CellFormat(0, 8, r.tr(text), "", 1, "L", false, 0, "")
+}
+
+// groupTitle heads a section of ingredients or directions, like "For the dough".
+func (r *renderer) groupTitle(text string) {
+	if text == "" {
+		return
+	}
+
+	r.pdf.Ln(1)
+	r.pdf.SetFont(BODY_FONT, "B", 11)
+	r.pdf.CellFormat(0, LINE_HEIGHT, r.tr(text), "", 1, "L", false, 0, "")
+	r.pdf.SetFont(BODY_FONT, "", 11)
+}
+
+// listItem writes a bulleted or numbered line, with wrapped lines indented past the marker.
+func (r *renderer) listItem(marker, text string) {
+	markerWidth := 8.0
+
+	r.pdf.CellFormat(markerWidth, LINE_HEIGHT, marker, "", 0, "L", false, 0, "")
+	r.pdf.MultiCell(0, LINE_HEIGHT, r.tr(text), "", "L", false)
+	r.pdf.Ln(1)
+}
+
+// truncate shortens text to fit width on one line.
+func (r *renderer) truncate(text string, width float64) string {
+	text = r.tr(text)
+
+	if r.pdf.GetStringWidth(text) <= width {
+		return text
+	}
+
+	for len(text) > 0 && r.pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+
+	return text + "..."
+}
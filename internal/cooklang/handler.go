@@ -0,0 +1,67 @@
+package cooklang
+
+import (
+	"fmt"
+	"sourdough/internal/recipes"
+	"sourdough/internal/shared"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	repo *recipes.Repository
+}
+
+func NewHandler(repo *recipes.Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+func (h *Handler) Export(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*shared.UserInfo)
+	if !ok {
+		return c.Status(401).Redirect("/login")
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).SendString("Invalid recipe ID")
+	}
+
+	recipe, err := h.repo.Get(id)
+
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	} else if recipe == nil {
+		return c.Status(404).SendString("Recipe not found")
+	}
+
+	if user.Id != recipe.UserID {
+		return c.Status(403).SendString("Forbidden")
+	}
+
+	if recipe, err = h.repo.Expand(recipe); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/plain; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", Filename(recipe.Title)))
+	return c.SendString(FromRecipe(recipe).String())
+}
+
+// Filename names a recipe's file after its title, as Cooklang tools do.
+func Filename(title string) string {
+	name := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, title))
+
+	if name == "" {
+		name = "recipe"
+	}
+
+	return name + ".cook"
+}
@@ -0,0 +1,251 @@
+package cooklang
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"sourdough/internal/database"
+	"sourdough/internal/recipes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	firstNumberPattern = regexp.MustCompile(`\d+`)
+	timerPattern       = regexp.MustCompile(`(?i)\b(\d+(?:[.,]\d+)?(?:\s*(?:-|–|to)\s*\d+(?:[.,]\d+)?)?)\s+(hours?|hrs?|minutes?|mins?|seconds?|secs?)\b`)
+)
+
+// ToRecipe maps a Cooklang recipe onto ours. Cooklang has no separate ingredient list, so the
+// ingredients are collected from the steps, in order. The title falls back to the file's name.
+func ToRecipe(cook *Recipe, fallbackTitle string) recipes.Recipe {
+	title := cook.Get("title")
+	if title == "" {
+		title = fallbackTitle
+	}
+
+	ingredients := []string{}
+	for _, item := range cook.Ingredients() {
+		ingredient := recipes.Ingredient{Quantity: item.Quantity, Unit: item.Unit, Name: item.Value, Note: item.Note}.String()
+
+		if !slices.Contains(ingredients, ingredient) {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+
+	directions := []string{}
+	directionSections := []recipes.Section{}
+	section := ""
+
+	for _, step := range cook.Steps {
+		if step.Section != section {
+			section = step.Section
+			directionSections = append(directionSections, recipes.Section{Title: section, Start: len(directions)})
+		}
+
+		if direction := strings.TrimSpace(stepText(step)); direction != "" {
+			directions = append(directions, direction)
+		}
+	}
+
+	notes := cook.Notes
+	if source := cook.Get("source", "source.url"); source != "" {
+		notes = append(notes, "Source: "+source)
+	}
+
+	prepTime := cook.Get("prep time", "time.prep", "prep_time")
+	cookTime := cook.Get("cook time", "time.cook", "cook_time")
+	if prepTime == "" && cookTime == "" {
+		cookTime = cook.Get("time", "duration")
+	}
+
+	servings, _ := strconv.Atoi(firstNumberPattern.FindString(cook.Get("servings", "serves", "yield")))
+
+	return recipes.Recipe{
+		Title:               strings.TrimSpace(title),
+		Ingredients:         database.JSONArray[string](ingredients),
+		NumberOfIngredients: len(ingredients),
+		Directions:          database.JSONArray[string](directions),
+		DirectionSections:   database.JSONArray[recipes.Section](directionSections),
+		Notes:               strings.Join(notes, "\n\n"),
+		PrepTime:            prepTime,
+		CookTime:            cookTime,
+		Servings:            servings,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+}
+
+// stepText reads a step the way the recipe page shows directions, without Cooklang's markup.
+func stepText(step Step) string {
+	var text strings.Builder
+
+	for _, item := range step.Items {
+		if item.Type == ItemTimer && item.Quantity != "" {
+			text.WriteString(strings.TrimSpace(item.Quantity + " " + item.Unit))
+		} else {
+			text.WriteString(item.Value)
+		}
+	}
+
+	return text.String()
+}
+
+// FromRecipe writes our recipe as Cooklang. Each ingredient is marked up where the directions
+// first mention it by name, and times in the directions become timers. Ingredients the
+// directions never mention are gathered into a first step, since Cooklang has nowhere else to put them.
+func FromRecipe(recipe *recipes.Recipe) *Recipe {
+	cook := &Recipe{Metadata: []Metadata{{Key: "title", Value: recipe.Title}}}
+
+	if recipe.Servings > 0 {
+		cook.Metadata = append(cook.Metadata, Metadata{Key: "servings", Value: strconv.Itoa(recipe.Servings)})
+	}
+	if recipe.PrepTime != "" {
+		cook.Metadata = append(cook.Metadata, Metadata{Key: "prep time", Value: recipe.PrepTime})
+	}
+	if recipe.CookTime != "" {
+		cook.Metadata = append(cook.Metadata, Metadata{Key: "cook time", Value: recipe.CookTime})
+	}
+
+	for _, note := range strings.Split(recipe.Notes, "\n") {
+		if note = strings.TrimSpace(note); note != "" {
+			cook.Notes = append(cook.Notes, note)
+		}
+	}
+
+	var ingredients []recipes.Ingredient
+	for _, line := range recipe.Ingredients {
+		if strings.TrimSpace(line) != "" {
+			ingredients = append(ingredients, recipes.ParseIngredient(line))
+		}
+	}
+
+	used := make([]bool, len(ingredients))
+
+	for _, group := range recipes.Groups(recipe.Directions, recipe.DirectionSections) {
+		for _, direction := range group.Lines {
+			cook.Steps = append(cook.Steps, Step{Section: group.Title, Items: markUp(direction, ingredients, used)})
+		}
+	}
+
+	var gather []Item
+	for i, ingredient := range ingredients {
+		if used[i] || ingredient.Name == "" {
+			continue
+		}
+
+		if len(gather) == 0 {
+			gather = append(gather, Item{Type: ItemText, Value: "Gather "})
+		} else {
+			gather = append(gather, Item{Type: ItemText, Value: ", "})
+		}
+
+		gather = append(gather, ingredientItem(ingredient))
+	}
+
+	if len(gather) > 0 {
+		gather = append(gather, Item{Type: ItemText, Value: "."})
+		cook.Steps = append([]Step{{Items: gather}}, cook.Steps...)
+	}
+
+	return cook
+}
+
+type match struct {
+	start, end int
+	item       Item
+}
+
+func markUp(direction string, ingredients []recipes.Ingredient, used []bool) []Item {
+	var matches []match
+
+	// Matches are positions in the direction, so only fold case where that doesn't move anything
+	lower := strings.ToLower(direction)
+	if len(lower) != len(direction) {
+		lower = direction
+	}
+
+	for _, loc := range timerPattern.FindAllStringSubmatchIndex(direction, -1) {
+		matches = append(matches, match{
+			start: loc[0],
+			end:   loc[1],
+			item:  Item{Type: ItemTimer, Quantity: direction[loc[2]:loc[3]], Unit: direction[loc[4]:loc[5]]},
+		})
+	}
+
+	for i, ingredient := range ingredients {
+		if used[i] || ingredient.Name == "" {
+			continue
+		}
+
+		// Directions often shorten "all-purpose flour" to "flour", so fall back to the name's last word
+		name := strings.ToLower(ingredient.Name)
+		start := indexWord(lower, name)
+
+		if fields := strings.Fields(name); start < 0 && len(fields) > 1 {
+			name = fields[len(fields)-1]
+			start = indexWord(lower, name)
+		}
+
+		if start < 0 {
+			continue
+		}
+
+		candidate := match{start: start, end: start + len(name), item: ingredientItem(ingredient)}
+
+		if !slices.ContainsFunc(matches, candidate.overlaps) {
+			matches = append(matches, candidate)
+			used[i] = true
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	var items []Item
+	position := 0
+
+	for _, m := range matches {
+		if m.start > position {
+			items = append(items, Item{Type: ItemText, Value: direction[position:m.start]})
+		}
+
+		items = append(items, m.item)
+		position = m.end
+	}
+
+	if position < len(direction) {
+		items = append(items, Item{Type: ItemText, Value: direction[position:]})
+	}
+
+	return items
+}
+
+func (m match) overlaps(other match) bool {
+	return m.start < other.end && other.start < m.end
+}
+
+func ingredientItem(ingredient recipes.Ingredient) Item {
+	return Item{Type: ItemIngredient, Value: ingredient.Name, Quantity: ingredient.Quantity, Unit: ingredient.Unit, Note: ingredient.Note}
+}
+
+// indexWord finds word in text where it isn't part of a longer word.
+func indexWord(text, word string) int {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], word)
+		if i < 0 {
+			return -1
+		}
+
+		start, end := offset+i, offset+i+len(word)
+
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return start
+		}
+
+		offset = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '_'
+}
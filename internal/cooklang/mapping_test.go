@@ -0,0 +1,50 @@
+package cooklang
+
+import (
+	"reflect"
+	"sourdough/internal/database"
+	"sourdough/internal/recipes"
+	"testing"
+)
+
+func TestRecipeRoundTrip(t *testing.T) {
+	recipe := &recipes.Recipe{
+		Title:       "Shortbread",
+		Ingredients: database.JSONArray[string]{"3/4 cup sugar", "1 1/2 cups flour", "1 cup butter, softened", "salt"},
+		Directions: database.JSONArray[string]{
+			"Cream the butter and sugar.",
+			"Mix in the flour and salt.",
+			"Bake for 20 minutes at 150°C.",
+		},
+		DirectionSections: database.JSONArray[recipes.Section]{},
+		Notes:             "Keeps for a week.",
+		PrepTime:          "15 minutes",
+		Servings:          12,
+	}
+
+	imported := ToRecipe(Parse(FromRecipe(recipe).String()), "")
+
+	if imported.Title != recipe.Title || imported.Servings != recipe.Servings || imported.PrepTime != recipe.PrepTime {
+		t.Errorf("got %q serving %d with %q prep time", imported.Title, imported.Servings, imported.PrepTime)
+	}
+
+	if !reflect.DeepEqual(imported.Directions, recipe.Directions) {
+		t.Errorf("directions:\n got %q\nwant %q", imported.Directions, recipe.Directions)
+	}
+
+	want := map[string]bool{}
+	for _, line := range recipe.Ingredients {
+		want[recipes.ParseIngredient(line).String()] = true
+	}
+
+	for _, line := range imported.Ingredients {
+		if !want[line] {
+			t.Errorf("unexpected ingredient %q", line)
+		}
+		delete(want, line)
+	}
+
+	for line := range want {
+		t.Errorf("missing ingredient %q", line)
+	}
+}
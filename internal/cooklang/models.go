@@ -0,0 +1,64 @@
+package cooklang
+
+// Recipe is a parsed Cooklang file. See https://cooklang.org/docs/spec/.
+type Recipe struct {
+	Metadata []Metadata
+	Steps    []Step
+	Notes    []string
+}
+
+type Metadata struct {
+	Key   string
+	Value string
+}
+
+// Step is one paragraph of a Cooklang recipe. Section is the name of the section it's in, if any.
+type Step struct {
+	Section string
+	Items   []Item
+}
+
+type ItemType int
+
+const (
+	ItemText ItemType = iota
+	ItemIngredient
+	ItemCookware
+	ItemTimer
+)
+
+// Item is a run of plain text or a single component within a step.
+// Text items only use Value, which components use for their name.
+type Item struct {
+	Type     ItemType
+	Value    string
+	Quantity string
+	Unit     string
+	Note     string
+}
+
+func (r *Recipe) Get(keys ...string) string {
+	for _, key := range keys {
+		for _, metadata := range r.Metadata {
+			if metadata.Key == key {
+				return metadata.Value
+			}
+		}
+	}
+
+	return ""
+}
+
+func (r *Recipe) Ingredients() []Item {
+	var ingredients []Item
+
+	for _, step := range r.Steps {
+		for _, item := range step.Items {
+			if item.Type == ItemIngredient {
+				ingredients = append(ingredients, item)
+			}
+		}
+	}
+
+	return ingredients
+}
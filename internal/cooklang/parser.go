@@ -0,0 +1,210 @@
+package cooklang
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	blockCommentPattern = regexp.MustCompile(`(?s)\[-.*?-\]`)
+	lineCommentPattern  = regexp.MustCompile(`--.*$`)
+)
+
+// Parse reads a Cooklang recipe. Cooklang is forgiving by design, so anything that doesn't
+// parse as a component is kept as text rather than rejected.
+func Parse(text string) *Recipe {
+	recipe := &Recipe{}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = parseFrontMatter(recipe, text)
+	text = blockCommentPattern.ReplaceAllString(text, "")
+
+	var section string
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			recipe.Steps = append(recipe.Steps, Step{Section: section, Items: parseStep(strings.Join(paragraph, " "))})
+			paragraph = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, ">>"):
+			key, value, _ := strings.Cut(strings.TrimPrefix(trimmed, ">>"), ":")
+			recipe.Metadata = append(recipe.Metadata, Metadata{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			recipe.Notes = append(recipe.Notes, strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+		case strings.HasPrefix(trimmed, "="):
+			flush()
+			section = strings.TrimSpace(strings.Trim(trimmed, "="))
+		default:
+			trimmed = strings.TrimSpace(lineCommentPattern.ReplaceAllString(trimmed, ""))
+
+			if trimmed == "" {
+				// Only a blank line ends a step, not a line that was all comment
+				if strings.TrimSpace(line) == "" {
+					flush()
+				}
+				continue
+			}
+
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+
+	flush()
+	return recipe
+}
+
+// parseFrontMatter reads the YAML front matter newer Cooklang files use for metadata. Only
+// simple "key: value" lines are understood, which covers the keys the spec defines.
+func parseFrontMatter(recipe *Recipe, text string) string {
+	rest, ok := strings.CutPrefix(strings.TrimLeft(text, "\n"), "---\n")
+	if !ok {
+		return text
+	}
+
+	frontMatter, body, ok := strings.Cut(rest, "\n---")
+	if !ok {
+		return text
+	}
+
+	for _, line := range strings.Split(frontMatter, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) != "" {
+			recipe.Metadata = append(recipe.Metadata, Metadata{Key: strings.TrimSpace(key), Value: strings.Trim(strings.TrimSpace(value), `"'`)})
+		}
+	}
+
+	return strings.TrimPrefix(body, "\n")
+}
+
+func parseStep(text string) []Item {
+	var items []Item
+	var plain strings.Builder
+
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		itemType, isSigil := sigils[runes[i]]
+
+		if isSigil {
+			if item, length := parseComponent(itemType, runes[i+1:]); length > 0 {
+				if plain.Len() > 0 {
+					items = append(items, Item{Type: ItemText, Value: plain.String()})
+					plain.Reset()
+				}
+
+				items = append(items, item)
+				i += length + 1
+				continue
+			}
+		}
+
+		plain.WriteRune(runes[i])
+		i++
+	}
+
+	if plain.Len() > 0 {
+		items = append(items, Item{Type: ItemText, Value: plain.String()})
+	}
+
+	return items
+}
+
+var sigils = map[rune]ItemType{
+	'@': ItemIngredient,
+	'#': ItemCookware,
+	'~': ItemTimer,
+}
+
+// parseComponent reads the component after a sigil and returns it with the number of runes it
+// used, or zero if there isn't one. Names with spaces need braces, e.g. "@olive oil{}", while
+// one-word names can leave them off, e.g. "@salt". A sigil followed by a space is just text.
+func parseComponent(itemType ItemType, runes []rune) (Item, int) {
+	item := Item{Type: itemType}
+	i := 0
+
+	if len(runes) == 0 || unicode.IsSpace(runes[0]) {
+		return Item{}, 0
+	}
+
+	// Modifiers like "@?optional" and "@&reference" don't change what we store
+	if itemType == ItemIngredient && i < len(runes) && strings.ContainsRune("?&+-", runes[i]) {
+		i++
+	}
+
+	nameStart := i
+	braces := -1
+
+	for j := i; j < len(runes); j++ {
+		if runes[j] == '{' {
+			braces = j
+			break
+		}
+
+		if _, ok := sigils[runes[j]]; ok || runes[j] == '}' {
+			break
+		}
+	}
+
+	if braces >= 0 {
+		item.Value = strings.TrimSpace(string(runes[nameStart:braces]))
+
+		end := indexRune(runes, '}', braces)
+		if end < 0 {
+			return Item{}, 0
+		}
+
+		amount := strings.TrimPrefix(strings.TrimSpace(string(runes[braces+1:end])), "=")
+		quantity, unit, _ := strings.Cut(amount, "%")
+		item.Quantity = strings.TrimSpace(quantity)
+		item.Unit = strings.TrimSpace(unit)
+		i = end + 1
+	} else {
+		for i < len(runes) && isWordRune(runes[i]) {
+			i++
+		}
+
+		item.Value = string(runes[nameStart:i])
+	}
+
+	// Timers don't need a name, but everything else does
+	if item.Value == "" && (itemType != ItemTimer || braces < 0) {
+		return Item{}, 0
+	}
+
+	if itemType == ItemIngredient && i < len(runes) && runes[i] == '(' {
+		if end := indexRune(runes, ')', i); end > 0 {
+			item.Note = strings.TrimSpace(string(runes[i+1 : end]))
+			i = end + 1
+		}
+	}
+
+	return item, i
+}
+
+// isWordRune is whether r can be part of a one-word name. Words end at spaces and punctuation,
+// but emoji like "@🧂" are words.
+func isWordRune(r rune) bool {
+	if _, ok := sigils[r]; ok {
+		return false
+	}
+
+	return r == '_' || !unicode.IsSpace(r) && !unicode.IsPunct(r)
+}
+
+func indexRune(runes []rune, r rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,250 @@
+package cooklang
+
+import (
+	"reflect"
+	"testing"
+)
+
+func text(value string) Item { return Item{Type: ItemText, Value: value} }
+
+func ingredient(name, quantity, unit string) Item {
+	return Item{Type: ItemIngredient, Value: name, Quantity: quantity, Unit: unit}
+}
+
+func cookware(name, quantity string) Item {
+	return Item{Type: ItemCookware, Value: name, Quantity: quantity}
+}
+
+func timer(name, quantity, unit string) Item {
+	return Item{Type: ItemTimer, Value: name, Quantity: quantity, Unit: unit}
+}
+
+type canonicalTest struct {
+	name     string
+	source   string
+	steps    [][]Item
+	metadata []Metadata
+}
+
+// The Cooklang spec's canonical tests (https://github.com/cooklang/spec, tests/canonical.yaml),
+// named as they are there. We keep quantities as written rather than converting them to
+// numbers, and leave an ingredient without an amount with no quantity instead of "some".
+var canonicalTests = []canonicalTest{
+	{name: "testBasicDirection", source: "Add a bit of chilli", steps: [][]Item{{text("Add a bit of chilli")}}},
+	{name: "testComments", source: "-- testing comments"},
+	{
+		name:   "testCommentsAfterIngredients",
+		source: "@thyme{2%sprigs} -- testing comments\nand some text",
+		steps:  [][]Item{{ingredient("thyme", "2", "sprigs"), text(" and some text")}},
+	},
+	{
+		name:   "testCommentsWithIngredients",
+		source: "-- testing comments\n@thyme{2%sprigs}",
+		steps:  [][]Item{{ingredient("thyme", "2", "sprigs")}},
+	},
+	{name: "testDirectionsWithDegrees", source: "Heat oven up to 200°C", steps: [][]Item{{text("Heat oven up to 200°C")}}},
+	{name: "testDirectionsWithNumbers", source: "Heat 5L of water", steps: [][]Item{{text("Heat 5L of water")}}},
+	{
+		name:   "testDirectionWithIngrident",
+		source: "Add @chilli{3%items}, @ginger{10%g} and @milk{1%l}.",
+		steps: [][]Item{{
+			text("Add "), ingredient("chilli", "3", "items"), text(", "), ingredient("ginger", "10", "g"),
+			text(" and "), ingredient("milk", "1", "l"), text("."),
+		}},
+	},
+	{name: "testEquipmentMultipleWords", source: "Fry in #frying pan{}", steps: [][]Item{{text("Fry in "), cookware("frying pan", "")}}},
+	{
+		name:   "testEquipmentMultipleWordsWithLeadingNumber",
+		source: "Fry in #7-inch nonstick frying pan{ }",
+		steps:  [][]Item{{text("Fry in "), cookware("7-inch nonstick frying pan", "")}},
+	},
+	{name: "testEquipmentMultipleWordsWithSpaces", source: "Fry in #frying pan{ }", steps: [][]Item{{text("Fry in "), cookware("frying pan", "")}}},
+	{
+		name:   "testEquipmentOneWord",
+		source: "Simmer in #pan for some time",
+		steps:  [][]Item{{text("Simmer in "), cookware("pan", ""), text(" for some time")}},
+	},
+	{name: "testEquipmentQuantity", source: "#frying pan{2}", steps: [][]Item{{cookware("frying pan", "2")}}},
+	{name: "testEquipmentQuantityOneWord", source: "#frying pan{three}", steps: [][]Item{{cookware("frying pan", "three")}}},
+	{name: "testEquipmentQuantityMultipleWords", source: "#frying pan{two small}", steps: [][]Item{{cookware("frying pan", "two small")}}},
+	{name: "testFractions", source: "@milk{1/2%cup}", steps: [][]Item{{ingredient("milk", "1/2", "cup")}}},
+	{
+		name:   "testFractionsInDirections",
+		source: "knife cut about every 1/2 inches",
+		steps:  [][]Item{{text("knife cut about every 1/2 inches")}},
+	},
+	{name: "testFractionsLike", source: "@milk{01/2%cup}", steps: [][]Item{{ingredient("milk", "01/2", "cup")}}},
+	{name: "testFractionsWithSpaces", source: "@milk{1 / 2 %cup}", steps: [][]Item{{ingredient("milk", "1 / 2", "cup")}}},
+	{
+		name:   "testIngredientMultipleWordsWithLeadingNumber",
+		source: "Top with @1000 island dressing{ }",
+		steps:  [][]Item{{text("Top with "), ingredient("1000 island dressing", "", "")}},
+	},
+	{name: "testIngredientWithEmoji", source: "Add some @🧂", steps: [][]Item{{text("Add some "), ingredient("🧂", "", "")}}},
+	{name: "testIngredientExplicitUnits", source: "@chilli{3%items}", steps: [][]Item{{ingredient("chilli", "3", "items")}}},
+	{name: "testIngredientExplicitUnitsWithSpaces", source: "@chilli{ 3 % items }", steps: [][]Item{{ingredient("chilli", "3", "items")}}},
+	{name: "testIngredientImplicitUnits", source: "@chilli{3}", steps: [][]Item{{ingredient("chilli", "3", "")}}},
+	{name: "testIngredientNoUnits", source: "@chilli", steps: [][]Item{{ingredient("chilli", "", "")}}},
+	{name: "testIngredientNoUnitsNotOnlyString", source: "@5peppers", steps: [][]Item{{ingredient("5peppers", "", "")}}},
+	{name: "testIngredientWithNumbers", source: "@tipo 00 flour{250%g}", steps: [][]Item{{ingredient("tipo 00 flour", "250", "g")}}},
+	{
+		name:   "testIngredientWithoutStopper",
+		source: "@chilli cut into pieces",
+		steps:  [][]Item{{ingredient("chilli", "", ""), text(" cut into pieces")}},
+	},
+	{name: "testInvalidMultiWordCookware", source: "Recipe # 10 {}", steps: [][]Item{{text("Recipe # 10 {}")}}},
+	{name: "testInvalidMultiWordIngredient", source: "Message me @ example {}", steps: [][]Item{{text("Message me @ example {}")}}},
+	{name: "testInvalidMultiWordTimer", source: "It is ~ {5}", steps: [][]Item{{text("It is ~ {5}")}}},
+	{name: "testInvalidSingleWordCookware", source: "Recipe # 5", steps: [][]Item{{text("Recipe # 5")}}},
+	{name: "testInvalidSingleWordIngredient", source: "Message @ example", steps: [][]Item{{text("Message @ example")}}},
+	{name: "testInvalidSingleWordTimer", source: "It is ~ 5", steps: [][]Item{{text("It is ~ 5")}}},
+	{name: "testMetadata", source: ">> sourced: babooshka", metadata: []Metadata{{"sourced", "babooshka"}}},
+	{name: "testMetadataBreak", source: "hello >> sourced: babooshka", steps: [][]Item{{text("hello >> sourced: babooshka")}}},
+	{name: "testMetadataMultiwordKey", source: ">> cooking time: 30 mins", metadata: []Metadata{{"cooking time", "30 mins"}}},
+	{name: "testMetadataMultiwordKeyWithSpaces", source: ">>cooking time    :30 mins", metadata: []Metadata{{"cooking time", "30 mins"}}},
+	{
+		name:   "testMultiLineDirections",
+		source: "Add a bit of chilli\n\nAdd a bit of hummus",
+		steps:  [][]Item{{text("Add a bit of chilli")}, {text("Add a bit of hummus")}},
+	},
+	{
+		name:     "testMultipleLines",
+		source:   ">> Prep Time: 15 minutes\n>> Cook Time: 30 minutes",
+		metadata: []Metadata{{"Prep Time", "15 minutes"}, {"Cook Time", "30 minutes"}},
+	},
+	{name: "testMultiWordIngredient", source: "@hot chilli{3}", steps: [][]Item{{ingredient("hot chilli", "3", "")}}},
+	{name: "testMultiWordIngredientNoAmount", source: "@hot chilli{}", steps: [][]Item{{ingredient("hot chilli", "", "")}}},
+	{
+		name:   "testMutipleIngredientsWithoutStopper",
+		source: "@chilli cut into pieces and @garlic",
+		steps:  [][]Item{{ingredient("chilli", "", ""), text(" cut into pieces and "), ingredient("garlic", "", "")}},
+	},
+	{name: "testQuantityAsText", source: "@thyme{few%sprigs}", steps: [][]Item{{ingredient("thyme", "few", "sprigs")}}},
+	{name: "testQuantityDigitalString", source: "@water{7 k }", steps: [][]Item{{ingredient("water", "7 k", "")}}},
+	{name: "testServings", source: ">> servings: 1|2|3", metadata: []Metadata{{"servings", "1|2|3"}}},
+	{
+		name:   "testSlashInText",
+		source: "Preheat the oven to 200℃/Fan 180°C.",
+		steps:  [][]Item{{text("Preheat the oven to 200℃/Fan 180°C.")}},
+	},
+	{name: "testTimerDecimal", source: "Fry for ~{1.5%minutes}", steps: [][]Item{{text("Fry for "), timer("", "1.5", "minutes")}}},
+	{name: "testTimerFractional", source: "Fry for ~{1/2%hour}", steps: [][]Item{{text("Fry for "), timer("", "1/2", "hour")}}},
+	{name: "testTimerInteger", source: "Fry for ~{10%minutes}", steps: [][]Item{{text("Fry for "), timer("", "10", "minutes")}}},
+	{name: "testTimerWithName", source: "Fry for ~potato{42%minutes}", steps: [][]Item{{text("Fry for "), timer("potato", "42", "minutes")}}},
+	{
+		name:   "testSingleWordTimer",
+		source: "Let it ~rest after plating",
+		steps:  [][]Item{{text("Let it "), timer("rest", "", ""), text(" after plating")}},
+	},
+	{
+		name:   "testSingleWordTimerWithPunctuation",
+		source: "Let it ~rest, then serve",
+		steps:  [][]Item{{text("Let it "), timer("rest", "", ""), text(", then serve")}},
+	},
+	{
+		name:   "testSingleWordTimerWithUnicodePunctuation",
+		source: "Let it ~rest⸫ then serve",
+		steps:  [][]Item{{text("Let it "), timer("rest", "", ""), text("⸫ then serve")}},
+	},
+	{
+		name:   "testTimerWithUnicodeWhitespace",
+		source: "Let it ~rest\u2009then serve",
+		steps:  [][]Item{{text("Let it "), timer("rest", "", ""), text("\u2009then serve")}},
+	},
+	{
+		name:   "testSingleWordIngredientWithPunctuation",
+		source: "Add some @chilli, then serve",
+		steps:  [][]Item{{text("Add some "), ingredient("chilli", "", ""), text(", then serve")}},
+	},
+	{
+		name:   "testSingleWordIngredientWithUnicodePunctuation",
+		source: "Add @chilli⸫ then bake",
+		steps:  [][]Item{{text("Add "), ingredient("chilli", "", ""), text("⸫ then bake")}},
+	},
+	{
+		name:   "testIngredientWithUnicodeWhitespace",
+		source: "Add @chilli\u2009then bake",
+		steps:  [][]Item{{text("Add "), ingredient("chilli", "", ""), text("\u2009then bake")}},
+	},
+	{
+		name:   "testSingleWordCookwareWithPunctuation",
+		source: "Place in #pot, then boil",
+		steps:  [][]Item{{text("Place in "), cookware("pot", ""), text(", then boil")}},
+	},
+	{
+		name:   "testSingleWordCookwareWithUnicodePunctuation",
+		source: "Place in #pot⸫ then boil",
+		steps:  [][]Item{{text("Place in "), cookware("pot", ""), text("⸫ then boil")}},
+	},
+	{
+		name:   "testCookwareWithUnicodeWhitespace",
+		source: "Add to #pot\u2009then boil",
+		steps:  [][]Item{{text("Add to "), cookware("pot", ""), text("\u2009then boil")}},
+	},
+}
+
+func stepItems(recipe *Recipe) [][]Item {
+	var steps [][]Item
+	for _, step := range recipe.Steps {
+		steps = append(steps, step.Items)
+	}
+
+	return steps
+}
+
+func TestParseCanonical(t *testing.T) {
+	for _, test := range canonicalTests {
+		t.Run(test.name, func(t *testing.T) {
+			recipe := Parse(test.source)
+
+			if steps := stepItems(recipe); !reflect.DeepEqual(steps, test.steps) {
+				t.Errorf("steps:\n got %#v\nwant %#v", steps, test.steps)
+			}
+
+			if !reflect.DeepEqual(recipe.Metadata, test.metadata) {
+				t.Errorf("metadata:\n got %#v\nwant %#v", recipe.Metadata, test.metadata)
+			}
+		})
+	}
+}
+
+// Writing a parsed recipe back out and parsing it again gives the same recipe, even where the
+// markup is normalized, like braces being dropped from one-word names.
+func TestSerializeCanonical(t *testing.T) {
+	for _, test := range canonicalTests {
+		t.Run(test.name, func(t *testing.T) {
+			recipe := Parse(test.source)
+			written := recipe.String()
+			reparsed := Parse(written)
+
+			if !reflect.DeepEqual(stepItems(reparsed), stepItems(recipe)) {
+				t.Errorf("steps changed after writing %q:\n got %#v\nwant %#v", written, stepItems(reparsed), stepItems(recipe))
+			}
+
+			if !reflect.DeepEqual(reparsed.Metadata, recipe.Metadata) {
+				t.Errorf("metadata changed after writing %q:\n got %#v\nwant %#v", written, reparsed.Metadata, recipe.Metadata)
+			}
+		})
+	}
+}
+
+func TestParseSectionsNotesAndFrontMatter(t *testing.T) {
+	recipe := Parse("---\ntitle: Bread\nservings: 2\n---\n> Best the next day\n\n== Dough ==\nMix @flour{500%g}.\n\n== Bake ==\nBake for ~{30%minutes}.\n")
+
+	if recipe.Get("title") != "Bread" || recipe.Get("servings") != "2" {
+		t.Errorf("front matter: got %#v", recipe.Metadata)
+	}
+
+	if !reflect.DeepEqual(recipe.Notes, []string{"Best the next day"}) {
+		t.Errorf("notes: got %#v", recipe.Notes)
+	}
+
+	if len(recipe.Steps) != 2 || recipe.Steps[0].Section != "Dough" || recipe.Steps[1].Section != "Bake" {
+		t.Fatalf("sections: got %#v", recipe.Steps)
+	}
+
+	reparsed := Parse(recipe.String())
+	if !reflect.DeepEqual(reparsed.Steps, recipe.Steps) || !reflect.DeepEqual(reparsed.Notes, recipe.Notes) {
+		t.Errorf("sections or notes changed after writing %q", recipe.String())
+	}
+}
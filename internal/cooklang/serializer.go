@@ -0,0 +1,102 @@
+package cooklang
+
+import "strings"
+
+// String writes the recipe as Cooklang, with metadata as ">>" lines.
+func (r *Recipe) String() string {
+	var cook strings.Builder
+
+	for _, metadata := range r.Metadata {
+		cook.WriteString(">> " + metadata.Key + ": " + oneLine(metadata.Value) + "\n")
+	}
+
+	if len(r.Notes) > 0 && cook.Len() > 0 {
+		cook.WriteString("\n")
+	}
+
+	for _, note := range r.Notes {
+		cook.WriteString("> " + oneLine(note) + "\n")
+	}
+
+	section := ""
+
+	for _, step := range r.Steps {
+		if step.Section != section {
+			section = step.Section
+			cook.WriteString("\n== " + oneLine(section) + " ==\n")
+		}
+
+		if cook.Len() > 0 {
+			cook.WriteString("\n")
+		}
+
+		for _, item := range step.Items {
+			cook.WriteString(item.String())
+		}
+
+		cook.WriteString("\n")
+	}
+
+	return cook.String()
+}
+
+func (i Item) String() string {
+	switch i.Type {
+	case ItemIngredient:
+		text := "@" + component(i)
+		if i.Note != "" {
+			text += "(" + strings.NewReplacer("(", "", ")", "").Replace(oneLine(i.Note)) + ")"
+		}
+		return text
+	case ItemCookware:
+		return "#" + component(i)
+	case ItemTimer:
+		return "~" + cleanName(i.Value) + "{" + amount(i) + "}"
+	default:
+		return strings.ReplaceAll(i.Value, "\n", " ")
+	}
+}
+
+// component writes a name and amount, leaving off the braces when a one-word name doesn't need them.
+func component(i Item) string {
+	name := cleanName(i.Value)
+
+	if i.Quantity == "" && i.Unit == "" && isWord(name) {
+		return name
+	}
+
+	return name + "{" + amount(i) + "}"
+}
+
+func amount(i Item) string {
+	quantity := cleanName(i.Quantity)
+
+	if i.Unit == "" {
+		return quantity
+	}
+
+	return quantity + "%" + cleanName(i.Unit)
+}
+
+// cleanName drops the characters that would end a component early.
+func cleanName(text string) string {
+	return strings.TrimSpace(strings.NewReplacer("{", "", "}", "", "%", "", "@", "", "#", "", "~", "").Replace(oneLine(text)))
+}
+
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func isWord(text string) bool {
+	if text == "" {
+		return false
+	}
+
+	for _, r := range text {
+		if !isWordRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -55,6 +55,147 @@ func (db *DB) createTables() error {
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
+
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		model TEXT NOT NULL,
+		prompt_tokens INTEGER NOT NULL,
+		completion_tokens INTEGER NOT NULL,
+		total_tokens INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_user_created ON llm_usage (user_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+		key TEXT PRIMARY KEY,
+		tokens REAL NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS llm_cache (
+		key TEXT PRIMARY KEY,
+		model TEXT NOT NULL,
+		prompt_version TEXT NOT NULL,
+		result TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS recipe_drafts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		recipe TEXT NOT NULL,
+		source_text TEXT NOT NULL DEFAULT '',
+		source_images TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS recipe_sources (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		filename TEXT NOT NULL DEFAULT '',
+		content_type TEXT NOT NULL,
+		data BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS recipe_source_pages (
+		source_id INTEGER NOT NULL,
+		number INTEGER NOT NULL,
+		text TEXT NOT NULL DEFAULT '',
+		content_type TEXT NOT NULL,
+		image BLOB NOT NULL,
+		PRIMARY KEY (source_id, number)
+	);
+
+	CREATE TABLE IF NOT EXISTS recipe_photos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recipe_id INTEGER NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		content_type TEXT NOT NULL,
+		data BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_recipe_photos_recipe ON recipe_photos (recipe_id, position);
+
+	CREATE TABLE IF NOT EXISTS recipe_embeddings (
+		recipe_id INTEGER PRIMARY KEY,
+		model TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS recipe_similarities (
+		recipe_id INTEGER NOT NULL,
+		similar_id INTEGER NOT NULL,
+		score REAL NOT NULL,
+		PRIMARY KEY (recipe_id, similar_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS recipe_similarity_fingerprints (
+		user_id INTEGER PRIMARY KEY,
+		fingerprint TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS pantry_staples (
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		PRIMARY KEY (user_id, name)
+	);
+
+	CREATE TABLE IF NOT EXISTS pantries (
+		user_id INTEGER PRIMARY KEY,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS cook_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recipe_id INTEGER NOT NULL,
+		cooked_on DATE NOT NULL,
+		rating INTEGER NOT NULL DEFAULT 0,
+		notes TEXT NOT NULL DEFAULT '',
+		tweaks TEXT NOT NULL DEFAULT '',
+		photo_content_type TEXT NOT NULL DEFAULT '',
+		photo BLOB,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_cook_log_recipe ON cook_log (recipe_id, cooked_on);
+
+	CREATE TABLE IF NOT EXISTS collections (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS collection_recipes (
+		collection_id INTEGER NOT NULL,
+		recipe_id INTEGER NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (collection_id, recipe_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_collection_recipes_recipe ON collection_recipes (recipe_id);
+
+	CREATE TABLE IF NOT EXISTS user_ingredient_foods (
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		food TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, name)
+	);
+
+	CREATE TABLE IF NOT EXISTS user_ingredient_traits (
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		traits TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, name)
+	);
 	`
 
 	db.MustExec(query)
@@ -65,6 +206,37 @@ func (db *DB) createTables() error {
 func (db *DB) updateTables() error {
 	migrations := []string{
 		`ALTER TABLE recipes ADD COLUMN notes TEXT DEFAULT '' NOT NULL`,
+		`ALTER TABLE recipes ADD COLUMN source_id INTEGER`,
+		`ALTER TABLE recipe_drafts ADD COLUMN source_id INTEGER`,
+		`ALTER TABLE recipes ADD COLUMN total_minutes INTEGER`,
+		`ALTER TABLE recipes ADD COLUMN prep_minutes INTEGER`,
+		`ALTER TABLE recipes ADD COLUMN cook_minutes INTEGER`,
+		`ALTER TABLE recipes ADD COLUMN active_minutes INTEGER`,
+		`ALTER TABLE recipes ADD COLUMN inactive_minutes INTEGER`,
+		`ALTER TABLE recipes ADD COLUMN ingredient_sections TEXT`,
+		`ALTER TABLE recipes ADD COLUMN direction_sections TEXT`,
+		`ALTER TABLE recipes ADD COLUMN times_cooked INTEGER DEFAULT 0 NOT NULL`,
+		`ALTER TABLE recipes ADD COLUMN last_cooked_on DATE`,
+		`ALTER TABLE recipes ADD COLUMN favorite BOOLEAN DEFAULT 0 NOT NULL`,
+		`ALTER TABLE recipes ADD COLUMN dietary_detected TEXT`,
+		`ALTER TABLE recipes ADD COLUMN dietary_overrides TEXT DEFAULT '{}' NOT NULL`,
+		`ALTER TABLE recipes ADD COLUMN parent_id INTEGER`,
+		`ALTER TABLE recipe_drafts ADD COLUMN parent_id INTEGER`,
+		`ALTER TABLE recipe_drafts ADD COLUMN transform TEXT DEFAULT '' NOT NULL`,
+		// Drafts used to keep a single source image. Drafts are short lived, so the old
+		// columns are dropped rather than converted, or SELECT * couldn't load them.
+		`ALTER TABLE recipe_drafts ADD COLUMN source_images TEXT`,
+		`ALTER TABLE recipe_drafts DROP COLUMN source_image`,
+		`ALTER TABLE recipe_drafts DROP COLUMN source_content_type`,
+		// What the LLM found in ingredients used to be shared by all users, so one user's
+		// ingredient names could change another's labels. It's only a cache, so it's dropped
+		// rather than attributed to anyone.
+		`DROP TABLE IF EXISTS ingredient_traits`,
+		// The foods the LLM matched to ingredient names were shared the same way, and are
+		// dropped for the same reason
+		`DROP TABLE IF EXISTS ingredient_foods`,
+		// Cache hits are counted in memory for the stats, so nothing read the per-entry count
+		`ALTER TABLE llm_cache DROP COLUMN hits`,
 	}
 
 	for _, migration := range migrations {
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMigratesSingleImageDrafts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	old, err := sqlx.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old.MustExec(`
+	CREATE TABLE recipe_drafts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		recipe TEXT NOT NULL,
+		source_text TEXT NOT NULL DEFAULT '',
+		source_image BLOB,
+		source_content_type TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO recipe_drafts (user_id, recipe, source_image, source_content_type) VALUES (1, '{}', x'ffd8', 'image/jpeg');
+	`)
+	old.Close()
+
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var columns []string
+	if err := db.Select(&columns, "SELECT name FROM pragma_table_info('recipe_drafts')"); err != nil {
+		t.Fatal(err)
+	}
+
+	has := map[string]bool{}
+	for _, column := range columns {
+		has[column] = true
+	}
+
+	if !has["source_images"] {
+		t.Error("source_images wasn't added")
+	}
+
+	if has["source_image"] || has["source_content_type"] {
+		t.Errorf("single image columns weren't dropped: %v", columns)
+	}
+
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM recipe_drafts"); err != nil || count != 1 {
+		t.Errorf("drafts were lost: count=%d err=%v", count, err)
+	}
+}
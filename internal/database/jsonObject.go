@@ -0,0 +1,38 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// JSONObject is a generic type for storing a struct as a JSON column
+type JSONObject[T any] struct {
+	Data T
+}
+
+// Value implements the driver.Valuer interface
+func (jo JSONObject[T]) Value() (driver.Value, error) {
+	return json.Marshal(jo.Data)
+}
+
+// Scan implements the sql.Scanner interface
+func (jo *JSONObject[T]) Scan(value any) error {
+	if value == nil {
+		var zero T
+		jo.Data = zero
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("cannot scan non-string value into JSONObject")
+	}
+
+	return json.Unmarshal(bytes, &jo.Data)
+}
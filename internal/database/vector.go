@@ -0,0 +1,46 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Vector is an embedding, stored as a blob of little-endian float32s
+type Vector []float32
+
+// Value implements the driver.Valuer interface
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	bytes := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(bytes[4*i:], math.Float32bits(f))
+	}
+
+	return bytes, nil
+}
+
+// Scan implements the sql.Scanner interface
+func (v *Vector) Scan(value any) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok || len(bytes)%4 != 0 {
+		return errors.New("cannot scan value into Vector")
+	}
+
+	vector := make(Vector, len(bytes)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(bytes[4*i:]))
+	}
+
+	*v = vector
+	return nil
+}
@@ -0,0 +1,221 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sourdough/internal/recipes"
+	"strings"
+	"time"
+)
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Exporter writes a user's whole library to a zip archive: each recipe as schema.org
+// JSON-LD and Markdown, alongside its photos and the original it was imported from.
+type Exporter struct {
+	repo *recipes.Repository
+}
+
+func NewExporter(repo *recipes.Repository) *Exporter {
+	return &Exporter{repo: repo}
+}
+
+// Filename is what an export taken now should be called.
+func Filename(now time.Time) string {
+	return "sourdough-export-" + now.Format("2006-01-02") + ".zip"
+}
+
+// CollectionFilename is what an export of one collection taken now should be called.
+func CollectionFilename(name string, now time.Time) string {
+	return "sourdough-" + slugify(name) + "-" + now.Format("2006-01-02") + ".zip"
+}
+
+func (e *Exporter) Export(userID int, w io.Writer) (*Manifest, error) {
+	userRecipes, err := e.repo.GetForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.ExportRecipes(userRecipes, w)
+}
+
+// ExportRecipes writes an archive of just the given recipes, e.g. the ones in a collection.
+// It can be imported the same way as a whole library.
+func (e *Exporter) ExportRecipes(userRecipes []*recipes.Recipe, w io.Writer) (*Manifest, error) {
+	archive := zip.NewWriter(w)
+	manifest := &Manifest{
+		Format:     ARCHIVE_FORMAT,
+		Version:    ARCHIVE_VERSION,
+		ExportedAt: time.Now().UTC(),
+		Recipes:    []ManifestRecipe{},
+	}
+
+	// Several recipes can come from the same source, which only needs to be written once
+	sources := map[int]*ManifestFile{}
+
+	for _, recipe := range userRecipes {
+		// Archives are read without the rest of the library, so components are written out in full
+		recipe, err := e.repo.Expand(recipe)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := e.writeRecipe(archive, recipe, sources)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't export %q: %w", recipe.Title, err)
+		}
+
+		manifest.Recipes = append(manifest.Recipes, *entry)
+	}
+
+	if err := writeJSON(archive, MANIFEST_NAME, manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, archive.Close()
+}
+
+// standaloneSections drops the IDs of the recipes that expanded components came from, since
+// they mean nothing to whatever library the archive is imported into.
+func standaloneSections(sections []recipes.Section) []recipes.Section {
+	standalone := make([]recipes.Section, 0, len(sections))
+
+	for _, section := range sections {
+		standalone = append(standalone, recipes.Section{Title: section.Title, Start: section.Start})
+	}
+
+	return standalone
+}
+
+func (e *Exporter) writeRecipe(archive *zip.Writer, recipe *recipes.Recipe, sources map[int]*ManifestFile) (*ManifestRecipe, error) {
+	dir := fmt.Sprintf("recipes/%d-%s", recipe.ID, slugify(recipe.Title))
+
+	entry := &ManifestRecipe{
+		Title:              recipe.Title,
+		JSONLD:             dir + "/recipe.jsonld",
+		Markdown:           dir + "/recipe.md",
+		Photos:             []ManifestFile{},
+		ActiveMinutes:      recipe.ActiveMinutes,
+		InactiveMinutes:    recipe.InactiveMinutes,
+		IngredientSections: standaloneSections(recipe.IngredientSections),
+	}
+
+	photoIDs, err := e.repo.GetPhotoIDs(recipe.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, photoID := range photoIDs {
+		photo, err := e.repo.GetPhoto(photoID)
+		if err != nil {
+			return nil, err
+		} else if photo == nil {
+			continue
+		}
+
+		file := ManifestFile{
+			Path:        fmt.Sprintf("%s/photo-%d%s", dir, i+1, extensionFor(photo.ContentType)),
+			ContentType: photo.ContentType,
+		}
+
+		if err := writeFile(archive, file.Path, photo.Data); err != nil {
+			return nil, err
+		}
+
+		entry.Photos = append(entry.Photos, file)
+	}
+
+	if recipe.SourceID != nil {
+		if entry.Source, err = e.writeSource(archive, *recipe.SourceID, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	// Links in the recipe's own files are relative to its directory
+	var images []string
+	for _, photo := range entry.Photos {
+		images = append(images, path.Base(photo.Path))
+	}
+
+	if err := writeJSON(archive, entry.JSONLD, recipes.NewJSONLDRecipe(recipe, images)); err != nil {
+		return nil, err
+	}
+
+	if err := writeFile(archive, entry.Markdown, []byte(Markdown(recipe, images, entry.Source))); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (e *Exporter) writeSource(archive *zip.Writer, sourceID int, sources map[int]*ManifestFile) (*ManifestFile, error) {
+	if file, ok := sources[sourceID]; ok {
+		return file, nil
+	}
+
+	source, err := e.repo.GetSource(sourceID)
+	if err != nil || source == nil {
+		return nil, err
+	}
+
+	file := &ManifestFile{
+		Path:        fmt.Sprintf("sources/%d-%s", source.ID, path.Base(source.Filename)),
+		Filename:    source.Filename,
+		ContentType: source.ContentType,
+	}
+
+	if err := writeFile(archive, file.Path, source.Data); err != nil {
+		return nil, err
+	}
+
+	sources[sourceID] = file
+	return file, nil
+}
+
+func writeJSON(archive *zip.Writer, name string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(archive, name, data)
+}
+
+func writeFile(archive *zip.Writer, name string, data []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func slugify(title string) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(title), "-"), "-")
+
+	if slug == "" {
+		return "recipe"
+	}
+
+	return slug
+}
+
+func extensionFor(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ""
+	}
+}
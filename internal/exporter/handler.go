@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"fmt"
+	"log"
+	"sourdough/internal/recipes"
+	"sourdough/internal/shared"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	exporter *Exporter
+	repo     *recipes.Repository
+}
+
+func NewHandler(exporter *Exporter, repo *recipes.Repository) *Handler {
+	return &Handler{exporter: exporter, repo: repo}
+}
+
+// Export downloads the user's whole library, or just one collection when it's given as the
+// "collection" query parameter.
+func (h *Handler) Export(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*shared.UserInfo)
+	if !ok {
+		return c.Status(401).Redirect("/login")
+	}
+
+	collection, err := h.getCollection(c, user.Id)
+	if err != nil {
+		return err
+	}
+
+	filename := Filename(time.Now())
+	var userRecipes []*recipes.Recipe
+
+	if collection == nil {
+		userRecipes, err = h.repo.GetForUser(user.Id)
+	} else {
+		filename = CollectionFilename(collection.Name, time.Now())
+		userRecipes, err = h.repo.GetCollectionRecipes(collection.ID)
+	}
+
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", ARCHIVE_MIMETYPE)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if _, err := h.exporter.ExportRecipes(userRecipes, c.Response().BodyWriter()); err != nil {
+		log.Printf("Failed to export recipes for user %d: %v", user.Id, err)
+		c.Response().ResetBody()
+		c.Response().Header.Del("Content-Disposition")
+		c.Set("Content-Type", "text/plain")
+		return c.Status(500).SendString("Failed to export recipes")
+	}
+
+	return nil
+}
+
+// getCollection loads the collection named in the "collection" query parameter, if there is
+// one, responding with the appropriate error status if it can't be found or belongs to someone else.
+func (h *Handler) getCollection(c *fiber.Ctx, userID int) (*recipes.Collection, error) {
+	if c.Query("collection") == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.Atoi(c.Query("collection"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid collection ID")
+	}
+
+	collection, err := h.repo.GetCollection(id)
+
+	if err != nil {
+		return nil, err
+	} else if collection == nil || collection.UserID != userID {
+		return nil, fiber.NewError(404, "Collection not found")
+	}
+
+	return collection, nil
+}
@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"fmt"
+	"path"
+	"sourdough/internal/recipes"
+	"strconv"
+	"strings"
+)
+
+// Markdown writes the recipe for people to read, in or out of an archive. Images and the
+// source are linked relative to the recipe's directory in the archive.
+func Markdown(recipe *recipes.Recipe, images []string, source *ManifestFile) string {
+	var md strings.Builder
+
+	fmt.Fprintf(&md, "# %s\n\n", recipe.Title)
+
+	var facts []string
+	if recipe.Servings > 0 {
+		facts = append(facts, "**Serves:** "+strconv.Itoa(recipe.Servings))
+	}
+	if recipe.PrepTime != "" {
+		facts = append(facts, "**Prep:** "+recipe.PrepTimeText())
+	}
+	if recipe.CookTime != "" {
+		facts = append(facts, "**Cook:** "+recipe.CookTimeText())
+	}
+	if recipe.ActiveMinutes != nil {
+		facts = append(facts, "**Active:** "+recipe.ActiveTimeText())
+	}
+	if recipe.InactiveMinutes != nil {
+		facts = append(facts, "**Inactive:** "+recipe.InactiveTimeText())
+	}
+	if len(facts) > 0 {
+		md.WriteString(strings.Join(facts, " · ") + "\n\n")
+	}
+
+	for i, image := range images {
+		fmt.Fprintf(&md, "![%s, photo %d](%s)\n\n", recipe.Title, i+1, image)
+	}
+
+	md.WriteString("## Ingredients\n")
+	for _, group := range recipes.Groups(recipe.Ingredients, recipe.IngredientSections) {
+		writeGroupTitle(&md, group)
+		for _, ingredient := range group.Lines {
+			fmt.Fprintf(&md, "- %s\n", ingredient)
+		}
+	}
+
+	md.WriteString("\n## Directions\n")
+	step := 1
+	for _, group := range recipes.Groups(recipe.Directions, recipe.DirectionSections) {
+		writeGroupTitle(&md, group)
+		for _, direction := range group.Lines {
+			fmt.Fprintf(&md, "%d. %s\n", step, direction)
+			step++
+		}
+	}
+
+	if notes := strings.TrimSpace(recipe.Notes); notes != "" {
+		fmt.Fprintf(&md, "\n## Notes\n\n%s\n", notes)
+	}
+
+	if source != nil {
+		fmt.Fprintf(&md, "\nOriginal: [%s](../../%s)\n", path.Base(source.Filename), source.Path)
+	}
+
+	return md.String()
+}
+
+// writeGroupTitle starts a section of ingredients or directions. Every group is preceded by a
+// blank line, which Markdown needs between a list and a heading.
+func writeGroupTitle(md *strings.Builder, group recipes.Group) {
+	md.WriteString("\n")
+
+	if group.Title != "" {
+		fmt.Fprintf(md, "### %s\n\n", group.Title)
+	}
+}
@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"sourdough/internal/recipes"
+	"time"
+)
+
+const (
+	MANIFEST_NAME    = "manifest.json"
+	ARCHIVE_FORMAT   = "sourdough-export"
+	ARCHIVE_VERSION  = 1
+	ARCHIVE_MIMETYPE = "application/zip"
+)
+
+// Manifest lists everything in an export archive, so an import doesn't have to guess
+// which files belong to which recipe. Paths are relative to the root of the archive.
+type Manifest struct {
+	Format     string           `json:"format"`
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exportedAt"`
+	Recipes    []ManifestRecipe `json:"recipes"`
+}
+
+// ManifestRecipe also carries what schema.org has no place for, like active and inactive
+// time and ingredient sections.
+type ManifestRecipe struct {
+	Title           string         `json:"title"`
+	JSONLD          string         `json:"jsonld"`
+	Markdown        string         `json:"markdown"`
+	Photos          []ManifestFile `json:"photos"`
+	Source          *ManifestFile  `json:"source,omitempty"`
+	ActiveMinutes   *int           `json:"activeMinutes,omitempty"`
+	InactiveMinutes *int           `json:"inactiveMinutes,omitempty"`
+
+	IngredientSections []recipes.Section `json:"ingredientSections,omitempty"`
+}
+
+type ManifestFile struct {
+	Path        string `json:"path"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"contentType"`
+}
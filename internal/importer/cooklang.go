@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"path"
+	"sourdough/internal/cooklang"
+	"strings"
+)
+
+// Cooklang photos sit next to the recipe with the same name, e.g. "Pancakes.cook" and "Pancakes.jpg"
+var cooklangPhotoExtensions = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+// readCooklang reads a single .cook file, or a zip of them in any layout.
+func readCooklang(filename string, data []byte) ([]entry, error) {
+	if strings.ToLower(path.Ext(filename)) == ".cook" {
+		name := strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+		recipe := cooklang.ToRecipe(cooklang.Parse(string(data)), name)
+
+		return []entry{{name: name, recipe: &ImportedRecipe{Recipe: recipe}}}, nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	return readCooklangFS(archive)
+}
+
+func readCooklangFS(fsys fs.FS) ([]entry, error) {
+	var entries []entry
+	limit := &unpackLimit{}
+
+	err := fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || strings.ToLower(path.Ext(filePath)) != ".cook" {
+			return nil
+		}
+
+		name := strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+		recipe, err := readCooklangEntry(fsys, filePath, name, limit)
+		entries = append(entries, entry{name: name, recipe: recipe, err: err})
+
+		return nil
+	})
+
+	return entries, err
+}
+
+func readCooklangEntry(fsys fs.FS, filePath, name string, limit *unpackLimit) (*ImportedRecipe, error) {
+	data, err := limit.readFile(fsys, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := &ImportedRecipe{Recipe: cooklang.ToRecipe(cooklang.Parse(string(data)), name)}
+	base := strings.TrimSuffix(filePath, path.Ext(filePath))
+
+	for _, extension := range cooklangPhotoExtensions {
+		if photo, err := limit.readFile(fsys, base+extension); err == nil {
+			imported.Photos = append(imported.Photos, newPhoto(photo))
+			break
+		}
+	}
+
+	return imported, nil
+}
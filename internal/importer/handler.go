@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"io"
+	"sourdough/internal/shared"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	importer *Importer
+}
+
+func NewHandler(importer *Importer) *Handler {
+	return &Handler{importer: importer}
+}
+
+func (h *Handler) Settings(c *fiber.Ctx) error {
+	c.Set("Content-Type", "text/html")
+	component := SettingsView()
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *Handler) Import(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(*shared.UserInfo)
+	if !ok {
+		return c.Status(401).Redirect("/login")
+	}
+
+	archiveFile, err := c.FormFile("archive")
+	if err != nil {
+		return c.Status(400).SendString("Please choose an export to import")
+	}
+
+	file, err := archiveFile.Open()
+	if err != nil {
+		return c.Status(500).SendString("Failed to open export")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return c.Status(500).SendString("Failed to read export")
+	}
+
+	report, err := h.importer.Import(user.Id, Format(c.FormValue("format")), archiveFile.Filename, data)
+	if err != nil {
+		return c.Status(422).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := ImportReportView(report)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
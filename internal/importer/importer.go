@@ -0,0 +1,213 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sourdough/internal/exporter"
+	"sourdough/internal/recipes"
+	"strings"
+)
+
+var ErrUnknownFormat = errors.New("we don't recognize that export; pick its format and try again")
+var ErrTooLarge = errors.New("the export unpacks to more than we can import at once")
+
+// Exports are unpacked into memory, so a small archive that unpacks to gigabytes fails with
+// ErrTooLarge instead.
+const (
+	MAX_UNPACKED_FILE_SIZE = 32 << 20
+	MAX_UNPACKED_SIZE      = 256 << 20
+)
+
+// unpackLimit counts what's been unpacked from one export, so no single file in it and not
+// all of them together can go over the limits.
+type unpackLimit struct {
+	unpacked int64
+}
+
+func (l *unpackLimit) read(r io.Reader) ([]byte, error) {
+	limit := min(MAX_UNPACKED_FILE_SIZE, MAX_UNPACKED_SIZE-l.unpacked)
+
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+
+	l.unpacked += int64(len(data))
+	return data, nil
+}
+
+func (l *unpackLimit) readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return l.read(reader)
+}
+
+func (l *unpackLimit) readFile(fsys fs.FS, name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return l.read(file)
+}
+
+// Importer brings recipes in from other recipe managers' exports. Exports are already
+// structured, so unlike pasted recipes they're mapped directly without calling the LLM.
+type Importer struct {
+	repo *recipes.Repository
+}
+
+func NewImporter(repo *recipes.Repository) *Importer {
+	return &Importer{repo: repo}
+}
+
+func (i *Importer) Import(userID int, format Format, filename string, data []byte) (*Report, error) {
+	if format == "" || format == FormatAuto {
+		format = DetectFormat(filename, data)
+	}
+
+	var entries []entry
+	var err error
+
+	switch format {
+	case FormatPaprika:
+		entries, err = readPaprika(data)
+	case FormatMealie:
+		entries, err = readMealie(data)
+	case FormatTandoor:
+		entries, err = readTandoor(data)
+	case FormatSourdough:
+		entries, err = readSourdough(data)
+	case FormatCooklang:
+		entries, err = readCooklang(filename, data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %s export: %w", format, err)
+	}
+
+	return i.saveAll(userID, &Report{Filename: filename, Format: format}, entries), nil
+}
+
+// ImportDirectory imports a directory of Cooklang files, the only format that isn't a single file.
+func (i *Importer) ImportDirectory(userID int, dir string) (*Report, error) {
+	entries, err := readCooklangFS(os.DirFS(dir))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %s: %w", dir, err)
+	}
+
+	return i.saveAll(userID, &Report{Filename: dir, Format: FormatCooklang}, entries), nil
+}
+
+func (i *Importer) saveAll(userID int, report *Report, entries []entry) *Report {
+	sources := map[*recipes.Source]int{}
+
+	for _, entry := range entries {
+		if entry.err != nil {
+			report.Results = append(report.Results, Result{Title: entry.name, Err: entry.err})
+			continue
+		}
+
+		recipeID, err := i.save(userID, entry.recipe, sources)
+		report.Results = append(report.Results, Result{Title: entry.recipe.Recipe.Title, RecipeID: recipeID, Err: err})
+	}
+
+	return report
+}
+
+func (i *Importer) save(userID int, imported *ImportedRecipe, sources map[*recipes.Source]int) (int, error) {
+	recipe := imported.Recipe
+	recipe.UserID = userID
+
+	if imported.Source != nil {
+		sourceID, ok := sources[imported.Source]
+
+		if !ok {
+			source := *imported.Source
+			source.UserID = userID
+
+			saved, err := i.repo.CreateSource(&source)
+			if err != nil {
+				return 0, err
+			}
+
+			sourceID = saved.ID
+			sources[imported.Source] = sourceID
+		}
+
+		recipe.SourceID = &sourceID
+	}
+
+	result, err := i.repo.Create(&recipe)
+	if err != nil {
+		return 0, err
+	}
+
+	for position, photo := range imported.Photos {
+		photo.RecipeID = result.ID
+		photo.Position = position
+
+		if _, err := i.repo.CreatePhoto(&photo); err != nil {
+			return result.ID, fmt.Errorf("saved the recipe but not its photo: %w", err)
+		}
+	}
+
+	return result.ID, nil
+}
+
+// DetectFormat guesses an export's format from its name and, for zip files, what's inside.
+func DetectFormat(filename string, data []byte) Format {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".paprikarecipes":
+		return FormatPaprika
+	case ".json":
+		return FormatMealie
+	case ".cook":
+		return FormatCooklang
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		if len(bytes.TrimSpace(data)) > 0 && strings.ContainsAny(string(bytes.TrimSpace(data)[:1]), "[{") {
+			return FormatMealie
+		}
+
+		return ""
+	}
+
+	for _, file := range archive.File {
+		if file.Name == exporter.MANIFEST_NAME {
+			return FormatSourdough
+		}
+	}
+
+	for _, file := range archive.File {
+		switch strings.ToLower(path.Ext(file.Name)) {
+		case ".paprikarecipe":
+			return FormatPaprika
+		case ".zip":
+			return FormatTandoor
+		case ".cook":
+			return FormatCooklang
+		}
+	}
+
+	return ""
+}
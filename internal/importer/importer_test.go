@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func paprikaArchive(t *testing.T, entries map[string][]byte) []byte {
+	t.Helper()
+
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+
+	for name, content := range entries {
+		file, err := writer.Create(name + ".paprikarecipe")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compressed := gzip.NewWriter(file)
+		compressed.Write(content)
+		compressed.Close()
+	}
+
+	writer.Close()
+	return archive.Bytes()
+}
+
+func TestReadPaprikaRejectsOversizedEntries(t *testing.T) {
+	data := paprikaArchive(t, map[string][]byte{
+		"bomb":    bytes.Repeat([]byte(" "), MAX_UNPACKED_FILE_SIZE+1),
+		"pancake": []byte(`{"name": "Pancakes", "ingredients": "flour\neggs", "directions": "mix\nfry"}`),
+	})
+
+	// The whole archive is a tiny fraction of what it unpacks to
+	if len(data) > MAX_UNPACKED_FILE_SIZE/100 {
+		t.Fatalf("test archive is %d bytes", len(data))
+	}
+
+	entries, err := readPaprika(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		switch entry.name {
+		case "bomb":
+			if !errors.Is(entry.err, ErrTooLarge) {
+				t.Errorf("bomb: got error %v, want ErrTooLarge", entry.err)
+			}
+		case "pancake":
+			if entry.err != nil || entry.recipe.Recipe.Title != "Pancakes" {
+				t.Errorf("pancake: got %+v, %v", entry.recipe, entry.err)
+			}
+		}
+	}
+}
+
+func TestUnpackLimitCapsTheWholeExport(t *testing.T) {
+	limit := &unpackLimit{}
+	file := bytes.Repeat([]byte(" "), MAX_UNPACKED_FILE_SIZE)
+
+	for range MAX_UNPACKED_SIZE / MAX_UNPACKED_FILE_SIZE {
+		if _, err := limit.read(bytes.NewReader(file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := limit.read(strings.NewReader("one more")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got error %v, want ErrTooLarge", err)
+	}
+}
+
+func TestMealieNotesWithoutTitles(t *testing.T) {
+	entries, err := readMealie([]byte(`{
+		"name": "Soup",
+		"recipeIngredient": [],
+		"recipeInstructions": [],
+		"notes": [{"title": "", "text": "Freezes well"}, {"title": "Tip", "text": "Add lemon"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notes := entries[0].recipe.Recipe.Notes
+	if !strings.Contains(notes, "Freezes well") || strings.Contains(notes, ": Freezes well") {
+		t.Errorf("untitled note: got %q", notes)
+	}
+
+	if !strings.Contains(notes, "Tip: Add lemon") {
+		t.Errorf("titled note: got %q", notes)
+	}
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"net/http"
+	"regexp"
+	"sourdough/internal/database"
+	"sourdough/internal/recipes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var firstNumberPattern = regexp.MustCompile(`\d+`)
+
+// newRecipe fills in the parts of a recipe every format shares.
+func newRecipe(title string, ingredients, directions []string, notes, prepTime, cookTime string, servings int) recipes.Recipe {
+	return recipes.Recipe{
+		Title:               strings.TrimSpace(title),
+		Ingredients:         database.JSONArray[string](ingredients),
+		NumberOfIngredients: len(ingredients),
+		Directions:          database.JSONArray[string](directions),
+		Notes:               strings.TrimSpace(notes),
+		PrepTime:            prepTime,
+		CookTime:            cookTime,
+		Servings:            servings,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+}
+
+// splitLines turns a block of text into its non-empty lines. It never returns nil,
+// since ingredients and directions can't be NULL.
+func splitLines(text string) []string {
+	lines := []string{}
+
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// parseServings takes the first number out of yields like "4 servings" or "Makes 12".
+func parseServings(text string) int {
+	servings, _ := strconv.Atoi(firstNumberPattern.FindString(text))
+	return servings
+}
+
+// normalizeTime accepts ISO-8601 durations and plain text, and writes them like the LLM does.
+func normalizeTime(text string) string {
+	text = strings.TrimSpace(text)
+
+	if d, err := recipes.ParseISODuration(text); err == nil {
+		return recipes.FormatDuration(d)
+	}
+
+	return text
+}
+
+func minutesToTime(minutes int) string {
+	if minutes <= 0 {
+		return ""
+	}
+
+	return recipes.FormatDuration(time.Duration(minutes) * time.Minute)
+}
+
+func formatAmount(amount float64) string {
+	if amount == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+func joinNonEmpty(parts ...string) string {
+	var nonEmpty []string
+
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+
+	return strings.Join(nonEmpty, " ")
+}
+
+func newPhoto(data []byte) recipes.Photo {
+	return recipes.Photo{ContentType: http.DetectContentType(data), Data: data}
+}
@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Mealie exports recipes as JSON. Depending on the version and how it was exported, that's a
+// single recipe, a list of them, or an object with a "recipes" list.
+type mealieRecipe struct {
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	RecipeYield        string            `json:"recipeYield"`
+	RecipeServings     float64           `json:"recipeServings"`
+	PrepTime           string            `json:"prepTime"`
+	CookTime           string            `json:"cookTime"`
+	PerformTime        string            `json:"performTime"`
+	RecipeIngredient   []json.RawMessage `json:"recipeIngredient"`
+	RecipeInstructions []json.RawMessage `json:"recipeInstructions"`
+	Notes              []struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	} `json:"notes"`
+	OrgURL string `json:"orgURL"`
+}
+
+type mealieIngredient struct {
+	Title        string  `json:"title"`
+	Display      string  `json:"display"`
+	OriginalText string  `json:"originalText"`
+	Note         string  `json:"note"`
+	Quantity     float64 `json:"quantity"`
+	Unit         *struct {
+		Name string `json:"name"`
+	} `json:"unit"`
+	Food *struct {
+		Name string `json:"name"`
+	} `json:"food"`
+}
+
+type mealieInstruction struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+func readMealie(data []byte) ([]entry, error) {
+	var raw []json.RawMessage
+
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		var wrapper struct {
+			Recipes []json.RawMessage `json:"recipes"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, err
+		}
+
+		raw = wrapper.Recipes
+		if raw == nil {
+			raw = []json.RawMessage{data}
+		}
+	}
+
+	var entries []entry
+
+	for index, message := range raw {
+		var mealie mealieRecipe
+		if err := json.Unmarshal(message, &mealie); err != nil {
+			entries = append(entries, entry{name: fmt.Sprintf("Recipe #%d", index+1), err: err})
+			continue
+		}
+
+		recipe, err := mapMealie(mealie)
+		entries = append(entries, entry{name: mealie.Name, recipe: recipe, err: err})
+	}
+
+	return entries, nil
+}
+
+func mapMealie(mealie mealieRecipe) (*ImportedRecipe, error) {
+	if strings.TrimSpace(mealie.Name) == "" {
+		return nil, errors.New("recipe has no name")
+	}
+
+	ingredients := []string{}
+	for _, message := range mealie.RecipeIngredient {
+		if text := mealieIngredientText(message); text != "" {
+			ingredients = append(ingredients, text)
+		}
+	}
+
+	directions := []string{}
+	for _, message := range mealie.RecipeInstructions {
+		var text string
+		if err := json.Unmarshal(message, &text); err != nil {
+			var instruction mealieInstruction
+			if err := json.Unmarshal(message, &instruction); err != nil {
+				return nil, err
+			}
+			text = instruction.Text
+		}
+
+		directions = append(directions, splitLines(text)...)
+	}
+
+	var notes []string
+	for _, note := range mealie.Notes {
+		if strings.TrimSpace(note.Title) == "" {
+			notes = append(notes, note.Text)
+		} else {
+			notes = append(notes, joinNonEmpty(note.Title+":", note.Text))
+		}
+	}
+
+	servings := int(mealie.RecipeServings)
+	if servings == 0 {
+		servings = parseServings(mealie.RecipeYield)
+	}
+
+	// Older versions call the cook time performTime
+	cookTime := mealie.CookTime
+	if cookTime == "" {
+		cookTime = mealie.PerformTime
+	}
+
+	return &ImportedRecipe{
+		Recipe: newRecipe(
+			mealie.Name,
+			ingredients,
+			directions,
+			strings.Join(splitLines(mealie.Description+"\n"+strings.Join(notes, "\n")+"\n"+mealie.OrgURL), "\n\n"),
+			normalizeTime(mealie.PrepTime),
+			normalizeTime(cookTime),
+			servings,
+		),
+	}, nil
+}
+
+// Ingredients are plain strings in older exports and parsed objects in newer ones.
+func mealieIngredientText(message json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(message, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+
+	var ingredient mealieIngredient
+	if err := json.Unmarshal(message, &ingredient); err != nil {
+		return ""
+	}
+
+	if ingredient.Display != "" {
+		return strings.TrimSpace(ingredient.Display)
+	}
+
+	if ingredient.OriginalText != "" {
+		return strings.TrimSpace(ingredient.OriginalText)
+	}
+
+	var unit, food string
+	if ingredient.Unit != nil {
+		unit = ingredient.Unit.Name
+	}
+	if ingredient.Food != nil {
+		food = ingredient.Food.Name
+	}
+
+	return joinNonEmpty(formatAmount(ingredient.Quantity), unit, food, ingredient.Note)
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"fmt"
+	"sourdough/internal/recipes"
+)
+
+type Format string
+
+const (
+	FormatAuto      Format = "auto"
+	FormatPaprika   Format = "paprika"
+	FormatMealie    Format = "mealie"
+	FormatTandoor   Format = "tandoor"
+	FormatSourdough Format = "sourdough"
+	FormatCooklang  Format = "cooklang"
+)
+
+var Formats = []Format{FormatSourdough, FormatPaprika, FormatMealie, FormatTandoor, FormatCooklang}
+
+func (f Format) Label() string {
+	switch f {
+	case FormatPaprika:
+		return "Paprika"
+	case FormatMealie:
+		return "Mealie"
+	case FormatTandoor:
+		return "Tandoor"
+	case FormatSourdough:
+		return "Sourdough"
+	case FormatCooklang:
+		return "Cooklang"
+	default:
+		return "Figure out the format"
+	}
+}
+
+// ImportedRecipe is a recipe mapped from another manager's export, ready to be saved.
+// Recipes that were imported from the same original share a Source.
+type ImportedRecipe struct {
+	Recipe recipes.Recipe
+	Photos []recipes.Photo
+	Source *recipes.Source
+}
+
+// entry is one recipe read from an export. Entries that couldn't be read carry an error
+// instead, so one bad recipe doesn't stop the rest from importing.
+type entry struct {
+	name   string
+	recipe *ImportedRecipe
+	err    error
+}
+
+type Result struct {
+	Title    string
+	RecipeID int
+	Err      error
+}
+
+type Report struct {
+	Filename string
+	Format   Format
+	Results  []Result
+}
+
+func (r *Report) Succeeded() int {
+	count := 0
+
+	for _, result := range r.Results {
+		if result.Err == nil {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (r *Report) Failed() int {
+	return len(r.Results) - r.Succeeded()
+}
+
+func (r *Report) Summary() string {
+	if r.Failed() == 0 {
+		return fmt.Sprintf("%d recipes imported.", r.Succeeded())
+	}
+
+	return fmt.Sprintf("%d recipes imported, %d failed.", r.Succeeded(), r.Failed())
+}
@@ -0,0 +1,112 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+)
+
+// A .paprikarecipes export is a zip archive of .paprikarecipe entries, each a gzipped JSON recipe.
+type paprikaRecipe struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Ingredients string `json:"ingredients"`
+	Directions  string `json:"directions"`
+	Notes       string `json:"notes"`
+	Servings    string `json:"servings"`
+	PrepTime    string `json:"prep_time"`
+	CookTime    string `json:"cook_time"`
+	SourceURL   string `json:"source_url"`
+	PhotoData   string `json:"photo_data"`
+	Photos      []struct {
+		Data string `json:"data"`
+	} `json:"photos"`
+}
+
+func readPaprika(data []byte) ([]entry, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	limit := &unpackLimit{}
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		name := strings.TrimSuffix(path.Base(file.Name), path.Ext(file.Name))
+		recipe, err := readPaprikaEntry(file, limit)
+		entries = append(entries, entry{name: name, recipe: recipe, err: err})
+	}
+
+	return entries, nil
+}
+
+func readPaprikaEntry(file *zip.File, limit *unpackLimit) (*ImportedRecipe, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	content, err := limit.read(gzipReader)
+	if err != nil {
+		return nil, err
+	}
+
+	var paprika paprikaRecipe
+	if err := json.Unmarshal(content, &paprika); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(paprika.Name) == "" {
+		return nil, errors.New("recipe has no name")
+	}
+
+	imported := &ImportedRecipe{
+		Recipe: newRecipe(
+			paprika.Name,
+			splitLines(paprika.Ingredients),
+			splitLines(paprika.Directions),
+			strings.Join(splitLines(paprika.Description+"\n"+paprika.Notes+"\n"+paprika.SourceURL), "\n\n"),
+			normalizeTime(paprika.PrepTime),
+			normalizeTime(paprika.CookTime),
+			parseServings(paprika.Servings),
+		),
+	}
+
+	// The main photo is in photo_data, any others are in photos
+	encodedPhotos := []string{paprika.PhotoData}
+	for _, photo := range paprika.Photos {
+		encodedPhotos = append(encodedPhotos, photo.Data)
+	}
+
+	for _, encoded := range encodedPhotos {
+		if encoded == "" {
+			continue
+		}
+
+		photoData, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("recipe has an unreadable photo")
+		}
+
+		imported.Photos = append(imported.Photos, newPhoto(photoData))
+	}
+
+	return imported, nil
+}
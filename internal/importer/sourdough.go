@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sourdough/internal/exporter"
+	"sourdough/internal/recipes"
+	"strings"
+)
+
+// readSourdough restores an archive written by exporter.Exporter, using its manifest to find
+// each recipe's JSON-LD, photos and source. The Markdown files are for people and are ignored.
+func readSourdough(data []byte) ([]entry, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	manifestFile, ok := files[exporter.MANIFEST_NAME]
+	if !ok {
+		return nil, errors.New("the manifest is missing")
+	}
+
+	limit := &unpackLimit{}
+
+	manifestData, err := limit.readZipFile(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest exporter.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, err
+	}
+
+	if manifest.Format != exporter.ARCHIVE_FORMAT {
+		return nil, errors.New("the manifest isn't from a sourdough export")
+	} else if manifest.Version > exporter.ARCHIVE_VERSION {
+		return nil, fmt.Errorf("the export is version %d, but this version of sourdough only reads up to version %d", manifest.Version, exporter.ARCHIVE_VERSION)
+	}
+
+	sources := map[string]*recipes.Source{}
+	var entries []entry
+
+	for _, manifestRecipe := range manifest.Recipes {
+		recipe, err := readSourdoughEntry(files, manifestRecipe, sources, limit)
+		entries = append(entries, entry{name: manifestRecipe.Title, recipe: recipe, err: err})
+	}
+
+	return entries, nil
+}
+
+func readSourdoughEntry(files map[string]*zip.File, manifestRecipe exporter.ManifestRecipe, sources map[string]*recipes.Source, limit *unpackLimit) (*ImportedRecipe, error) {
+	jsonLDData, err := readArchiveFile(files, manifestRecipe.JSONLD, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonLD recipes.JSONLDRecipe
+	if err := json.Unmarshal(jsonLDData, &jsonLD); err != nil {
+		return nil, err
+	}
+
+	imported := &ImportedRecipe{Recipe: jsonLD.ToRecipe(0)}
+
+	if imported.Recipe.Title == "" {
+		return nil, errors.New("recipe has no name")
+	}
+
+	imported.Recipe.ActiveMinutes = manifestRecipe.ActiveMinutes
+	imported.Recipe.InactiveMinutes = manifestRecipe.InactiveMinutes
+
+	// Components were written out in full, but older archives kept the IDs of the recipes
+	// they came from, which belong to the library they were exported from
+	sections := []recipes.Section{}
+	for _, section := range manifestRecipe.IngredientSections {
+		sections = append(sections, recipes.Section{Title: section.Title, Start: section.Start})
+	}
+	imported.Recipe.IngredientSections = sections
+
+	for _, manifestPhoto := range manifestRecipe.Photos {
+		photoData, err := readArchiveFile(files, manifestPhoto.Path, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		// The manifest's content types are whatever whoever wrote the archive said, and are
+		// served back to the browser, so only the bytes are trusted
+		photo := newPhoto(photoData)
+		if !strings.HasPrefix(photo.ContentType, "image/") {
+			return nil, fmt.Errorf("%s isn't an image", manifestPhoto.Path)
+		}
+
+		imported.Photos = append(imported.Photos, photo)
+	}
+
+	if manifestRecipe.Source != nil {
+		source, ok := sources[manifestRecipe.Source.Path]
+
+		if !ok {
+			sourceData, err := readArchiveFile(files, manifestRecipe.Source.Path, limit)
+			if err != nil {
+				return nil, err
+			}
+
+			contentType := http.DetectContentType(sourceData)
+			if !strings.HasPrefix(contentType, "image/") && contentType != "application/pdf" {
+				return nil, fmt.Errorf("%s isn't an image or a PDF", manifestRecipe.Source.Path)
+			}
+
+			source = &recipes.Source{
+				Filename:    manifestRecipe.Source.Filename,
+				ContentType: contentType,
+				Data:        sourceData,
+			}
+			sources[manifestRecipe.Source.Path] = source
+		}
+
+		imported.Source = source
+	}
+
+	return imported, nil
+}
+
+func readArchiveFile(files map[string]*zip.File, name string, limit *unpackLimit) ([]byte, error) {
+	file, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing", name)
+	}
+
+	return limit.readZipFile(file)
+}
@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"sourdough/internal/database"
+	"sourdough/internal/exporter"
+	"sourdough/internal/recipes"
+	"testing"
+)
+
+func TestSourdoughArchivesDontCarryComponentIDs(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo := recipes.NewRepository(db)
+
+	crust, err := repo.Create(&recipes.Recipe{UserID: 1, Title: "Pie crust", Ingredients: []string{"2 cups flour", "1 cup butter"}, Directions: []string{"Rub in the butter."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.Create(&recipes.Recipe{
+		UserID:             1,
+		Title:              "Apple pie",
+		Ingredients:        []string{"6 apples"},
+		IngredientSections: []recipes.Section{{Title: "Pie crust", Start: 1, Component: true}},
+		Directions:         []string{"Fill the crust with the apples and bake."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := exporter.NewExporter(repo).Export(1, &archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range manifest.Recipes {
+		for _, section := range entry.IngredientSections {
+			if section.RecipeID != 0 || section.Component {
+				t.Errorf("%q exported section %+v", entry.Title, section)
+			}
+		}
+	}
+
+	report, err := NewImporter(repo).Import(2, FormatSourdough, "export.zip", archive.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range report.Results {
+		if result.Err != nil {
+			t.Fatalf("%q: %v", result.Title, result.Err)
+		}
+
+		recipe, err := repo.Get(result.RecipeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, section := range recipe.IngredientSections {
+			if section.RecipeID == crust.ID || section.Component {
+				t.Errorf("%q imported section %+v", recipe.Title, section)
+			}
+		}
+
+		if recipe.Title == "Apple pie" && !slices.Contains(recipe.Ingredients, "2 cups flour") {
+			t.Errorf("the crust wasn't written out: %q", recipe.Ingredients)
+		}
+	}
+}
+
+func TestSourdoughArchivesOnlyCarryImagesAndPDFs(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	html := []byte("<html><script>alert(document.cookie)</script></html>")
+
+	tests := []struct {
+		name        string
+		photo       []byte
+		source      []byte
+		contentType string
+		wantErr     bool
+	}{
+		{"photo and PDF", png, []byte("%PDF-1.4\n"), "application/pdf", false},
+		{"photo and image", png, png, "image/png", false},
+		{"HTML photo", html, nil, "", true},
+		{"HTML source", png, html, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data bytes.Buffer
+			archive := zip.NewWriter(&data)
+
+			// The manifest claims every file is harmless
+			manifestRecipe := exporter.ManifestRecipe{
+				Title:  "Toast",
+				JSONLD: "toast.json",
+				Photos: []exporter.ManifestFile{{Path: "photo", ContentType: "image/png"}},
+			}
+
+			files := map[string][]byte{
+				"toast.json": []byte(`{"@type": "Recipe", "name": "Toast"}`),
+				"photo":      tt.photo,
+			}
+
+			if tt.source != nil {
+				manifestRecipe.Source = &exporter.ManifestFile{Path: "source", Filename: "toast.pdf", ContentType: "application/pdf"}
+				files["source"] = tt.source
+			}
+
+			manifest, _ := json.Marshal(exporter.Manifest{
+				Format:  exporter.ARCHIVE_FORMAT,
+				Version: exporter.ARCHIVE_VERSION,
+				Recipes: []exporter.ManifestRecipe{manifestRecipe},
+			})
+			files[exporter.MANIFEST_NAME] = manifest
+
+			for name, content := range files {
+				writer, _ := archive.Create(name)
+				writer.Write(content)
+			}
+			archive.Close()
+
+			entries, err := readSourdough(data.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := entries[0].err != nil; got != tt.wantErr {
+				t.Fatalf("got error %v, want an error: %v", entries[0].err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if photo := entries[0].recipe.Photos[0]; photo.ContentType != "image/png" {
+				t.Errorf("photo is %q", photo.ContentType)
+			}
+
+			if source := entries[0].recipe.Source; source.ContentType != tt.contentType {
+				t.Errorf("source is %q, want %q", source.ContentType, tt.contentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// A Tandoor export is a zip archive holding one zip per recipe, each with a recipe.json and
+// optionally an image.
+type tandoorRecipe struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Servings     int    `json:"servings"`
+	ServingsText string `json:"servings_text"`
+	WorkingTime  int    `json:"working_time"`
+	WaitingTime  int    `json:"waiting_time"`
+	SourceURL    string `json:"source_url"`
+	Steps        []struct {
+		Name        string `json:"name"`
+		Instruction string `json:"instruction"`
+		Order       int    `json:"order"`
+		Ingredients []struct {
+			Food *struct {
+				Name string `json:"name"`
+			} `json:"food"`
+			Unit *struct {
+				Name string `json:"name"`
+			} `json:"unit"`
+			Amount   float64 `json:"amount"`
+			Note     string  `json:"note"`
+			IsHeader bool    `json:"is_header"`
+			NoAmount bool    `json:"no_amount"`
+		} `json:"ingredients"`
+	} `json:"steps"`
+}
+
+func readTandoor(data []byte) ([]entry, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	limit := &unpackLimit{}
+
+	for _, file := range archive.File {
+		if strings.ToLower(path.Ext(file.Name)) != ".zip" {
+			continue
+		}
+
+		name := strings.TrimSuffix(path.Base(file.Name), path.Ext(file.Name))
+		recipe, err := readTandoorEntry(file, limit)
+
+		if recipe != nil {
+			name = recipe.Recipe.Title
+		}
+
+		entries = append(entries, entry{name: name, recipe: recipe, err: err})
+	}
+
+	return entries, nil
+}
+
+func readTandoorEntry(file *zip.File, limit *unpackLimit) (*ImportedRecipe, error) {
+	content, err := limit.readZipFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	var tandoor *tandoorRecipe
+	var photos [][]byte
+
+	for _, innerFile := range inner.File {
+		innerContent, err := limit.readZipFile(innerFile)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case path.Base(innerFile.Name) == "recipe.json":
+			tandoor = &tandoorRecipe{}
+			if err := json.Unmarshal(innerContent, tandoor); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(path.Base(innerFile.Name), "image"):
+			photos = append(photos, innerContent)
+		}
+	}
+
+	if tandoor == nil {
+		return nil, errors.New("recipe.json is missing")
+	}
+
+	if strings.TrimSpace(tandoor.Name) == "" {
+		return nil, errors.New("recipe has no name")
+	}
+
+	sort.SliceStable(tandoor.Steps, func(i, j int) bool {
+		return tandoor.Steps[i].Order < tandoor.Steps[j].Order
+	})
+
+	ingredients := []string{}
+	directions := []string{}
+
+	for _, step := range tandoor.Steps {
+		for _, ingredient := range step.Ingredients {
+			var unit, food string
+			if ingredient.Unit != nil {
+				unit = ingredient.Unit.Name
+			}
+			if ingredient.Food != nil {
+				food = ingredient.Food.Name
+			}
+
+			amount := formatAmount(ingredient.Amount)
+			if ingredient.NoAmount || ingredient.IsHeader {
+				amount, unit = "", ""
+			}
+
+			if text := joinNonEmpty(amount, unit, food, ingredient.Note); text != "" {
+				ingredients = append(ingredients, text)
+			}
+		}
+
+		directions = append(directions, splitLines(step.Instruction)...)
+	}
+
+	imported := &ImportedRecipe{
+		Recipe: newRecipe(
+			tandoor.Name,
+			ingredients,
+			directions,
+			strings.Join(splitLines(tandoor.Description+"\n"+tandoor.SourceURL), "\n\n"),
+			minutesToTime(tandoor.WorkingTime),
+			minutesToTime(tandoor.WaitingTime),
+			tandoor.Servings,
+		),
+	}
+
+	for _, photo := range photos {
+		imported.Photos = append(imported.Photos, newPhoto(photo))
+	}
+
+	return imported, nil
+}
@@ -0,0 +1,160 @@
+package nutrition
+
+import "strings"
+
+// Nutrients are amounts in kcal for calories and grams for the rest.
+type Nutrients struct {
+	Calories float64
+	Protein  float64
+	Fat      float64
+	Carbs    float64
+	Fiber    float64
+}
+
+func (n Nutrients) add(other Nutrients) Nutrients {
+	return Nutrients{
+		Calories: n.Calories + other.Calories,
+		Protein:  n.Protein + other.Protein,
+		Fat:      n.Fat + other.Fat,
+		Carbs:    n.Carbs + other.Carbs,
+		Fiber:    n.Fiber + other.Fiber,
+	}
+}
+
+func (n Nutrients) scale(factor float64) Nutrients {
+	return Nutrients{
+		Calories: n.Calories * factor,
+		Protein:  n.Protein * factor,
+		Fat:      n.Fat * factor,
+		Carbs:    n.Carbs * factor,
+		Fiber:    n.Fiber * factor,
+	}
+}
+
+// Item is one ingredient line to estimate. Amount is only meaningful when HasAmount is set,
+// and Food is nil when the ingredient couldn't be matched to the data.
+type Item struct {
+	Line      string
+	Amount    float64
+	HasAmount bool
+	Unit      string
+	Food      *Food
+}
+
+// Estimate is what a recipe's ingredients add up to. Ingredients that weren't matched to a
+// food, or whose amount couldn't be turned into a weight, are left out of the totals and
+// listed so the reader knows how far to trust them.
+type Estimate struct {
+	Servings   int
+	Total      Nutrients
+	PerServing Nutrients
+	Counted    []Counted
+	Unmatched  []string
+	Unmeasured []string
+}
+
+// Counted is an ingredient that went into the totals.
+type Counted struct {
+	Line  string
+	Food  *Food
+	Grams float64
+}
+
+// Estimated says whether any ingredients could be counted at all.
+func (e *Estimate) Estimated() bool {
+	return len(e.Counted) > 0
+}
+
+// Ingredients is how many ingredients were considered, counted or not.
+func (e *Estimate) Ingredients() int {
+	return len(e.Counted) + len(e.Unmatched) + len(e.Unmeasured)
+}
+
+// EstimateItems adds up the items and divides them between the servings. Recipes without a
+// number of servings are treated as one.
+func EstimateItems(items []Item, servings int) *Estimate {
+	estimate := &Estimate{
+		Servings:   max(servings, 1),
+		Counted:    []Counted{},
+		Unmatched:  []string{},
+		Unmeasured: []string{},
+	}
+
+	for _, item := range items {
+		if item.Food == nil {
+			estimate.Unmatched = append(estimate.Unmatched, item.Line)
+			continue
+		}
+
+		grams, ok := Grams(item.Amount, item.Unit, item.Food)
+		if !item.HasAmount || !ok {
+			estimate.Unmeasured = append(estimate.Unmeasured, item.Line)
+			continue
+		}
+
+		estimate.Counted = append(estimate.Counted, Counted{Line: item.Line, Food: item.Food, Grams: grams})
+		estimate.Total = estimate.Total.add(item.Food.Per100g.scale(grams / 100))
+	}
+
+	estimate.PerServing = estimate.Total.scale(1 / float64(estimate.Servings))
+
+	return estimate
+}
+
+// Weights of the units that are the same whatever the ingredient
+var unitGrams = map[string]float64{
+	"g": 1, "gram": 1, "grams": 1,
+	"kg": 1000, "kilogram": 1000, "kilograms": 1000,
+	"oz": 28.35, "ounce": 28.35, "ounces": 28.35,
+	"lb": 453.6, "lbs": 453.6, "pound": 453.6, "pounds": 453.6,
+	"pinch": 0.36, "pinches": 0.36, "dash": 0.6, "dashes": 0.6,
+	"handful": 30, "handfuls": 30, "sprig": 1, "sprigs": 1, "bunch": 100, "bunches": 100,
+
+	// Most canned goods come in 14 to 15 oz cans
+	"can": 400, "cans": 400,
+}
+
+// Volumes in millilitres, converted to weights with the food's grams per cup
+var unitMillilitres = map[string]float64{
+	"ml": 1, "milliliter": 1, "milliliters": 1,
+	"l": 1000, "liter": 1000, "liters": 1000, "litre": 1000, "litres": 1000,
+	"tsp": 4.93, "teaspoon": 4.93, "teaspoons": 4.93,
+	"tbsp": 14.79, "tbs": 14.79, "tablespoon": 14.79, "tablespoons": 14.79,
+	"c": 236.6, "cup": 236.6, "cups": 236.6,
+	"pt": 473.2, "pint": 473.2, "pints": 473.2,
+	"qt": 946.4, "quart": 946.4, "quarts": 946.4,
+	"gallon": 3785, "gallons": 3785,
+}
+
+// Units that count whole things, weighed with the food's grams each
+var unitCounts = map[string]bool{
+	"": true, "clove": true, "cloves": true, "slice": true, "slices": true, "stick": true, "sticks": true,
+}
+
+// Grams converts an amount of the food to a weight. It fails for units that depend on the
+// food when the data doesn't have the food's density or size.
+func Grams(amount float64, unit string, food *Food) (float64, bool) {
+	// "T" and "t" are the usual shorthands for tablespoon and teaspoon
+	switch unit = strings.TrimSuffix(unit, "."); unit {
+	case "T":
+		unit = "tbsp"
+	case "t":
+		unit = "tsp"
+	default:
+		unit = strings.ToLower(unit)
+	}
+
+	if grams, ok := unitGrams[unit]; ok {
+		return amount * grams, true
+	}
+
+	if millilitres, ok := unitMillilitres[unit]; ok && food.GramsPerCup > 0 {
+		return amount * millilitres / unitMillilitres["cup"] * food.GramsPerCup, true
+	}
+
+	if unitCounts[unit] && food.GramsEach > 0 {
+		return amount * food.GramsEach, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,94 @@
+package nutrition
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var (
+	testFlour = &Food{Name: "flour", Per100g: Nutrients{Calories: 400, Protein: 10, Carbs: 80}, GramsPerCup: 125}
+	testEgg   = &Food{Name: "egg", Per100g: Nutrients{Calories: 150, Protein: 12, Fat: 10}, GramsEach: 50}
+	testSalt  = &Food{Name: "salt"}
+)
+
+func TestGrams(t *testing.T) {
+	tests := []struct {
+		amount float64
+		unit   string
+		food   *Food
+		grams  float64
+		ok     bool
+	}{
+		{250, "g", testFlour, 250, true},
+		{1.5, "kg", testFlour, 1500, true},
+		{2, "oz", testSalt, 56.7, true},
+		{1, "lb.", testFlour, 453.6, true},
+		{2, "cups", testFlour, 250, true},
+		{1, "Cup", testFlour, 125, true},
+		{4, "tbsp", testFlour, 31.25, true},
+		{4, "T", testFlour, 31.25, true},
+		{3, "t", testFlour, 7.82, true},
+		{250, "ml", testFlour, 132.1, true},
+		{3, "", testEgg, 150, true},
+		{1, "pinch", testSalt, 0.36, true},
+		{2, "cans", testSalt, 800, true},
+		// Volumes and counts need to know the food
+		{1, "tsp", testSalt, 0, false},
+		{2, "", testFlour, 0, false},
+		{1, "handfull", testFlour, 0, false},
+	}
+
+	for _, tt := range tests {
+		grams, ok := Grams(tt.amount, tt.unit, tt.food)
+		if ok != tt.ok || math.Abs(grams-tt.grams) > 0.1 {
+			t.Errorf("%v %q %s: got %.2f, %v, want %.2f, %v", tt.amount, tt.unit, tt.food.Name, grams, ok, tt.grams, tt.ok)
+		}
+	}
+}
+
+func TestEstimateItems(t *testing.T) {
+	items := []Item{
+		{Line: "250 g flour", Amount: 250, HasAmount: true, Unit: "g", Food: testFlour},
+		{Line: "2 eggs", Amount: 2, HasAmount: true, Food: testEgg},
+		{Line: "1 cup mystery relish", Amount: 1, HasAmount: true, Unit: "cup"},
+		{Line: "salt, to taste", Food: testSalt},
+		{Line: "1 tsp salt", Amount: 1, HasAmount: true, Unit: "tsp", Food: testSalt},
+	}
+
+	estimate := EstimateItems(items, 4)
+
+	if !estimate.Estimated() || estimate.Ingredients() != 5 {
+		t.Fatalf("got %+v", estimate)
+	}
+
+	if want := []string{"1 cup mystery relish"}; !reflect.DeepEqual(estimate.Unmatched, want) {
+		t.Errorf("unmatched: got %q, want %q", estimate.Unmatched, want)
+	}
+
+	if want := []string{"salt, to taste", "1 tsp salt"}; !reflect.DeepEqual(estimate.Unmeasured, want) {
+		t.Errorf("unmeasured: got %q, want %q", estimate.Unmeasured, want)
+	}
+
+	// 250 g of flour and 100 g of egg
+	want := Nutrients{Calories: 1150, Protein: 37, Fat: 10, Carbs: 200}
+	if estimate.Total != want {
+		t.Errorf("total: got %+v, want %+v", estimate.Total, want)
+	}
+
+	if want := want.scale(0.25); estimate.PerServing != want {
+		t.Errorf("per serving: got %+v, want %+v", estimate.PerServing, want)
+	}
+}
+
+func TestEstimateItemsWithoutServings(t *testing.T) {
+	estimate := EstimateItems([]Item{{Line: "2 eggs", Amount: 2, HasAmount: true, Food: testEgg}}, 0)
+
+	if estimate.Servings != 1 || estimate.PerServing != estimate.Total {
+		t.Errorf("got %+v", estimate)
+	}
+
+	if estimate := EstimateItems(nil, 2); estimate.Estimated() {
+		t.Errorf("nothing to count: got %+v", estimate)
+	}
+}
@@ -0,0 +1,183 @@
+package nutrition
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// foods.csv is a subset of USDA FoodData Central (SR Legacy) covering everyday cooking
+// ingredients, rounded. Nutrients are per 100 g. grams_per_cup converts volumes to weights
+// and grams_each is the weight of one, e.g. an egg or a clove of garlic; 0 means unknown.
+// Aliases are separated by "|". When two foods match an ingredient equally well the one
+// listed first wins, so more specific foods come before general ones.
+//
+//go:embed foods.csv
+var foodsCSV string
+
+// Food is one row of the nutrient data.
+type Food struct {
+	Name        string
+	Aliases     []string
+	Per100g     Nutrients
+	GramsPerCup float64
+	GramsEach   float64
+}
+
+var foods = mustLoadFoods(foodsCSV)
+
+// The foods' names and aliases as Key would make them, for matching ingredient names
+var foodKeys = foodKeyList(foods)
+
+type foodKey struct {
+	words []string
+	food  *Food
+}
+
+// Foods lists every food in the data, in the order they're listed.
+func Foods() []*Food {
+	return foods
+}
+
+// FindFood looks up a food by its exact name, ignoring case.
+func FindFood(name string) *Food {
+	for _, food := range foods {
+		if strings.EqualFold(food.Name, name) {
+			return food
+		}
+	}
+
+	return nil
+}
+
+// MatchFood finds the food an ingredient name refers to. The longest name or alias found in
+// the ingredient wins, so "peanut butter" isn't mistaken for butter.
+func MatchFood(ingredient string) *Food {
+	words := strings.Fields(Key(ingredient))
+
+	var best *foodKey
+	for i := range foodKeys {
+		candidate := &foodKeys[i]
+
+		if (best == nil || len(candidate.words) > len(best.words)) && containsPhrase(words, candidate.words) {
+			best = candidate
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	return best.food
+}
+
+// Key normalizes an ingredient name for matching: lower case words without punctuation,
+// accents or plurals.
+func Key(name string) string {
+	name = accentReplacer.Replace(strings.ToLower(name))
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	})
+
+	for i, word := range words {
+		words[i] = singular(word)
+	}
+
+	return strings.Join(words, " ")
+}
+
+var accentReplacer = strings.NewReplacer("é", "e", "è", "e", "ê", "e", "ñ", "n", "ï", "i", "î", "i", "ô", "o", "ü", "u", "ç", "c", "'", "", "’", "")
+
+// singular roughly undoes English plurals. It only has to treat ingredient names and the
+// food data the same way, not be right.
+func singular(word string) string {
+	switch {
+	case len(word) <= 3 || strings.HasSuffix(word, "ss"):
+		return word
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "oes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ves"):
+		return strings.TrimSuffix(word, "ves") + "f"
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+
+	return word
+}
+
+func containsPhrase(words, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+
+		for j, word := range phrase {
+			if words[i+j] != word {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
+
+func foodKeyList(foods []*Food) []foodKey {
+	var keys []foodKey
+
+	for _, food := range foods {
+		for _, name := range append([]string{food.Name}, food.Aliases...) {
+			keys = append(keys, foodKey{words: strings.Fields(Key(name)), food: food})
+		}
+	}
+
+	return keys
+}
+
+// The data ships with the binary, so a malformed row is a bug to catch at startup.
+func mustLoadFoods(data string) []*Food {
+	rows, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("nutrition: can't read foods.csv: %v", err))
+	}
+
+	var foods []*Food
+
+	for i, row := range rows[1:] {
+		numbers := make([]float64, len(row)-2)
+
+		for j, value := range row[2:] {
+			if numbers[j], err = strconv.ParseFloat(value, 64); err != nil {
+				panic(fmt.Sprintf("nutrition: foods.csv line %d: %v", i+2, err))
+			}
+		}
+
+		food := &Food{
+			Name: row[0],
+			Per100g: Nutrients{
+				Calories: numbers[0],
+				Protein:  numbers[1],
+				Fat:      numbers[2],
+				Carbs:    numbers[3],
+				Fiber:    numbers[4],
+			},
+			GramsPerCup: numbers[5],
+			GramsEach:   numbers[6],
+		}
+
+		if row[1] != "" {
+			food.Aliases = strings.Split(row[1], "|")
+		}
+
+		foods = append(foods, food)
+	}
+
+	return foods
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sourdough/internal/shared"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Limiter struct {
+	repo *Repository
+}
+
+func NewLimiter(repo *Repository) *Limiter {
+	return &Limiter{repo: repo}
+}
+
+// Handler enforces the policy's per-IP bucket and, for signed in users, its per-user bucket.
+// A request has to be allowed by every bucket, and is only charged when it is.
+// Routes that use a per-user limit should put this after auth.RequireAuth.
+func (l *Limiter) Handler(policy Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var limits []bucketLimit
+
+		if policy.PerIP.enabled() {
+			limits = append(limits, bucketLimit{fmt.Sprintf("%s:ip:%s", policy.Name, c.IP()), policy.PerIP})
+		}
+
+		if user, ok := c.Locals("user").(*shared.UserInfo); ok && policy.PerUser.enabled() {
+			limits = append(limits, bucketLimit{fmt.Sprintf("%s:user:%d", policy.Name, user.Id), policy.PerUser})
+		}
+
+		allowed, wait, err := l.repo.Take(limits, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if !allowed {
+			return tooManyRequests(c, wait)
+		}
+
+		return c.Next()
+	}
+}
+
+// Start deletes idle buckets in the background until the process exits.
+func (l *Limiter) Start() {
+	go func() {
+		for {
+			if _, err := l.repo.DeleteIdle(time.Now().Add(-idleBucketAge)); err != nil {
+				log.Printf("Failed to delete idle rate limit buckets: %v", err)
+			}
+
+			time.Sleep(idleBucketAge)
+		}
+	}()
+}
+
+func tooManyRequests(c *fiber.Ctx, wait time.Duration) error {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	c.Status(fiber.StatusTooManyRequests)
+
+	if c.Get("HX-Request") != "true" {
+		return c.SendString(fmt.Sprintf("Too many requests. Please try again in %d seconds.", retryAfter))
+	}
+
+	// Swap the message into the layout's flash area instead of whatever the request targeted
+	c.Set("HX-Retarget", "#flash")
+	c.Set("HX-Reswap", "innerHTML")
+	c.Set("Content-Type", "text/html")
+	component := RateLimitedView(retryAfter)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSignInChargesEachBucketOnce(t *testing.T) {
+	limiter := NewLimiter(newTestRepository(t))
+
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+
+	app := fiber.New()
+	app.Get("/auth/:provider", limiter.Handler(AuthPolicy), ok)
+	app.Get("/auth/:provider/callback", limiter.Handler(AuthCallbackPolicy), ok)
+
+	get := func(path string) int {
+		t.Helper()
+
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return resp.StatusCode
+	}
+
+	// Every full sign in that fits in the start bucket also fits in the callback's
+	for i := range int(AuthPolicy.PerIP.Capacity) {
+		if status := get("/auth/google"); status != 200 {
+			t.Fatalf("start %d: got %d", i, status)
+		}
+
+		if status := get("/auth/google/callback"); status != 200 {
+			t.Fatalf("callback %d: got %d", i, status)
+		}
+	}
+
+	if status := get("/auth/google/callback"); status != 429 {
+		t.Errorf("callback over the limit: got %d, want 429", status)
+	}
+
+	if status := get("/auth/google"); status != 429 {
+		t.Errorf("start over the limit: got %d, want 429", status)
+	}
+}
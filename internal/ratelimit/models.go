@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+type Bucket struct {
+	Key       string  `db:"key"`
+	Tokens    float64 `db:"tokens"`
+	UpdatedAt int64   `db:"updated_at"` // unix nanoseconds
+}
+
+// Limit is a token bucket: up to Capacity requests in a burst, with one request
+// earned back every Refill. A zero Limit is not enforced.
+type Limit struct {
+	Capacity float64
+	Refill   time.Duration
+}
+
+func (l Limit) enabled() bool {
+	return l.Capacity > 0 && l.Refill > 0
+}
+
+// bucketLimit is the limit to enforce on one bucket, like a policy's per-IP limit on one IP.
+type bucketLimit struct {
+	key   string
+	limit Limit
+}
+
+type Policy struct {
+	Name    string
+	PerIP   Limit
+	PerUser Limit
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import "time"
+
+// Every rate limited route group gets its policy from here, so limits can be tuned in one place.
+var (
+	// Signing in involves a round trip to the OAuth provider, so a person never needs many of these.
+	// Starting a sign in spends from this bucket and the provider's callback from its own, so a
+	// whole sign in costs one token from each.
+	AuthPolicy = Policy{
+		Name:  "auth",
+		PerIP: Limit{Capacity: 10, Refill: 30 * time.Second},
+	}
+
+	// The callback exchanges its code with the provider, and can be requested without ever
+	// starting a sign in
+	AuthCallbackPolicy = Policy{
+		Name:  "auth-callback",
+		PerIP: Limit{Capacity: 10, Refill: 30 * time.Second},
+	}
+
+	// Importing a recipe calls the (paid) LLM provider
+	ImportPolicy = Policy{
+		Name:    "import",
+		PerIP:   Limit{Capacity: 20, Refill: time.Minute},
+		PerUser: Limit{Capacity: 5, Refill: 2 * time.Minute},
+	}
+
+	// Importing another app's export doesn't call the LLM, but unpacks and saves a whole library
+	BulkImportPolicy = Policy{
+		Name:    "bulk-import",
+		PerIP:   Limit{Capacity: 10, Refill: 5 * time.Minute},
+		PerUser: Limit{Capacity: 5, Refill: 10 * time.Minute},
+	}
+
+	// Asking the LLM for help with a recipe that's already saved, which costs less than an import
+	AssistPolicy = Policy{
+		Name:    "assist",
+		PerIP:   Limit{Capacity: 30, Refill: 30 * time.Second},
+		PerUser: Limit{Capacity: 10, Refill: time.Minute},
+	}
+)
+
+// A bucket left alone this long has refilled under every policy, which is the same as not
+// having one, so it can be deleted.
+const idleBucketAge = time.Hour
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sourdough/internal/database"
+	"sync"
+	"time"
+)
+
+type Repository struct {
+	db *database.DB
+
+	// Taking a token is a read-modify-write, so serialize it within the process
+	mu sync.Mutex
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Take removes a token from each of the buckets, but only if none of them is empty. Otherwise
+// it takes nothing and returns false along with how long the caller has to wait until every
+// bucket has a token again.
+func (repo *Repository) Take(limits []bucketLimit, now time.Time) (bool, time.Duration, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return false, 0, err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	buckets := make([]Bucket, len(limits))
+	var wait time.Duration
+
+	for i, limit := range limits {
+		bucket := Bucket{Key: limit.key, Tokens: limit.limit.Capacity, UpdatedAt: now.UnixNano()}
+
+		err = tx.Get(&bucket, "SELECT * FROM rate_limit_buckets WHERE key = ?", limit.key)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return false, 0, err
+		}
+
+		elapsed := now.Sub(time.Unix(0, bucket.UpdatedAt))
+		bucket.Tokens = math.Min(limit.limit.Capacity, bucket.Tokens+elapsed.Seconds()/limit.limit.Refill.Seconds())
+		bucket.UpdatedAt = now.UnixNano()
+
+		if bucket.Tokens < 1 {
+			wait = max(wait, time.Duration((1-bucket.Tokens)*float64(limit.limit.Refill)))
+		}
+
+		buckets[i] = bucket
+	}
+
+	if wait > 0 {
+		return false, wait, nil
+	}
+
+	for _, bucket := range buckets {
+		bucket.Tokens--
+
+		_, err = tx.NamedExec(
+			"INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES (:key, :tokens, :updated_at) ON CONFLICT (key) DO UPDATE SET tokens = excluded.tokens, updated_at = excluded.updated_at",
+			&bucket,
+		)
+		if err != nil {
+			return false, 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, err
+	}
+
+	return true, 0, nil
+}
+
+// DeleteIdle deletes the buckets that haven't been used since before.
+func (repo *Repository) DeleteIdle(before time.Time) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	result, err := repo.db.Exec("DELETE FROM rate_limit_buckets WHERE updated_at < ?", before.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
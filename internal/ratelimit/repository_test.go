@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"path/filepath"
+	"sourdough/internal/database"
+	"testing"
+	"time"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepository(db)
+}
+
+func TestTakeChargesNoBucketWhenOneIsEmpty(t *testing.T) {
+	repo := newTestRepository(t)
+	now := time.Now()
+
+	ip := bucketLimit{"test:ip:1.2.3.4", Limit{Capacity: 5, Refill: time.Minute}}
+	user := bucketLimit{"test:user:1", Limit{Capacity: 1, Refill: time.Minute}}
+
+	if allowed, _, err := repo.Take([]bucketLimit{ip, user}, now); err != nil || !allowed {
+		t.Fatalf("first request: allowed=%v err=%v", allowed, err)
+	}
+
+	// The user's bucket is empty now, so the IP's shouldn't be charged for the rejected requests
+	for range 5 {
+		allowed, wait, err := repo.Take([]bucketLimit{ip, user}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if allowed {
+			t.Fatal("request allowed with an empty user bucket")
+		}
+
+		if wait != time.Minute {
+			t.Errorf("got wait %v, want %v", wait, time.Minute)
+		}
+	}
+
+	for i := range 4 {
+		if allowed, _, err := repo.Take([]bucketLimit{ip}, now); err != nil || !allowed {
+			t.Fatalf("IP request %d: allowed=%v err=%v", i, allowed, err)
+		}
+	}
+
+	if allowed, _, _ := repo.Take([]bucketLimit{ip}, now); allowed {
+		t.Error("IP bucket allowed more requests than its capacity")
+	}
+}
+
+func TestDeleteIdle(t *testing.T) {
+	repo := newTestRepository(t)
+	now := time.Now()
+	limit := Limit{Capacity: 1, Refill: time.Minute}
+
+	repo.Take([]bucketLimit{{"test:old", limit}}, now.Add(-2*idleBucketAge))
+	repo.Take([]bucketLimit{{"test:new", limit}}, now)
+
+	deleted, err := repo.DeleteIdle(now.Add(-idleBucketAge))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted != 1 {
+		t.Errorf("deleted %d buckets, want 1", deleted)
+	}
+
+	if allowed, _, _ := repo.Take([]bucketLimit{{"test:new", limit}}, now); allowed {
+		t.Error("DeleteIdle deleted a bucket that was in use")
+	}
+}
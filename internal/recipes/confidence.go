@@ -0,0 +1,61 @@
+package recipes
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var quantityPattern = regexp.MustCompile(`\d|[¼½¾⅓⅔⅛]|\b(a|an|one|two|three|four|pinch|dash|handful|to taste)\b`)
+
+// Matches how recipes state their servings, like "Serves 4", "Yield: 6-8" or "12 portions"
+var servingsPattern = regexp.MustCompile(`\b(?:serves|servings|serving size|yields?|makes|feeds)\W{0,3}(\d+)(?:\s*(?:-|–|to)\s*(\d+))?|\b(\d+)(?:\s*(?:-|–|to)\s*(\d+))?\s+(?:servings|portions|people|persons)\b`)
+
+// LowConfidenceFields flags the parts of an extracted recipe that are worth a second look,
+// keyed by form field name. Checks against the source only apply to text sources, since we
+// can't see what the model read from an image.
+func LowConfidenceFields(llmRecipe LLMRecipe, sourceText string) map[string]string {
+	fields := map[string]string{}
+	source := strings.ToLower(sourceText)
+
+	if sourceText != "" && !strings.Contains(source, strings.ToLower(strings.TrimSpace(llmRecipe.Title))) {
+		fields["title"] = "This title doesn't appear in the original."
+	}
+
+	if llmRecipe.PrepTime == "" {
+		fields["prep_time"] = "We couldn't find a prep time."
+	}
+
+	if llmRecipe.CookTime == "" {
+		fields["cook_time"] = "We couldn't find a cook time."
+	}
+
+	if sourceText != "" && !statesServings(source, llmRecipe.Servings) {
+		fields["servings"] = "The number of servings was estimated."
+	}
+
+	ingredients, _ := ParseSections(llmRecipe.Ingredients, false)
+
+	for _, ingredient := range ingredients {
+		if !quantityPattern.MatchString(strings.ToLower(ingredient)) {
+			fields["ingredients"] = "Some ingredients don't have a quantity."
+			break
+		}
+	}
+
+	return fields
+}
+
+// statesServings reports whether the source says the recipe makes this many servings, either
+// exactly or as one end of a range. Any other number in the text, like "4 cups", doesn't count.
+func statesServings(source string, servings int) bool {
+	for _, match := range servingsPattern.FindAllStringSubmatch(source, -1) {
+		for _, number := range match[1:] {
+			if n, err := strconv.Atoi(number); err == nil && n == servings {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,27 @@
+package recipes
+
+import "testing"
+
+func TestLowConfidenceServings(t *testing.T) {
+	tests := []struct {
+		source   string
+		servings int
+		flagged  bool
+	}{
+		{"Pancakes\nServes 4\n2 cups flour", 4, false},
+		{"Pancakes\nYield: 6-8\n2 cups flour", 8, false},
+		{"Pancakes\nMakes 12 portions", 12, false},
+		{"Pancakes\nFeeds 3 to 4 people", 3, false},
+		{"Pancakes\n4 cups flour\n2 eggs", 4, true},
+		{"Pancakes\nServes 14", 4, true},
+		{"Pancakes\nServes 6", 4, true},
+	}
+
+	for _, test := range tests {
+		fields := LowConfidenceFields(LLMRecipe{Title: "Pancakes", Servings: test.servings}, test.source)
+
+		if _, flagged := fields["servings"]; flagged != test.flagged {
+			t.Errorf("%q with %d servings: flagged=%v, want %v", test.source, test.servings, flagged, test.flagged)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package recipes
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Matches the times in a step that deserve a timer, like "25 minutes", "20-25 mins" or
+// "1 hour and 15 minutes". Single letter units are left out, since "5 m" is rarely a time.
+var stepTimePattern = regexp.MustCompile(
+	`(?i)\b\d+(?:\.\d+|\s+\d+/\d+|/\d+)?(?:\s*(?:-|–|to)\s*\d+(?:\.\d+)?)?\s*(?:hours?|hrs?|minutes?|mins?|seconds?|secs?)\b(?:,?\s*(?:and\s+)?\d+(?:\.\d+)?\s*(?:minutes?|mins?|seconds?|secs?)\b)?`,
+)
+
+// CookStep is one direction as cook mode shows it, with the ingredients it uses and the
+// times in it that can be started as timers. Ingredients are positions in the recipe's
+// ingredient list, not counting blank lines.
+type CookStep struct {
+	Number      int
+	Section     string
+	Text        string
+	Ingredients []int
+	Timers      []StepTimer
+}
+
+type StepTimer struct {
+	Label   string
+	Seconds int
+}
+
+// CookSteps breaks the recipe's directions into steps for cook mode. Pass it an expanded
+// recipe so components' steps are included.
+func CookSteps(recipe *Recipe) []CookStep {
+	steps := []CookStep{}
+	ingredients := ingredientNames(nonBlank(recipe.Ingredients))
+
+	for _, group := range Groups(recipe.Directions, recipe.DirectionSections) {
+		for _, text := range group.Lines {
+			steps = append(steps, CookStep{
+				Number:      len(steps) + 1,
+				Section:     group.Title,
+				Text:        text,
+				Ingredients: stepIngredients(text, ingredients),
+				Timers:      stepTimers(text),
+			})
+		}
+	}
+
+	return steps
+}
+
+func stepTimers(text string) []StepTimer {
+	timers := []StepTimer{}
+
+	for _, match := range stepTimePattern.FindAllString(text, -1) {
+		d, err := ParseDuration(match)
+		if err != nil || d <= 0 {
+			continue
+		}
+
+		timers = append(timers, StepTimer{Label: strings.TrimSpace(match), Seconds: int(d.Seconds())})
+	}
+
+	return timers
+}
+
+// ingredientNames are the words of each ingredient's name, without its quantity and unit.
+func ingredientNames(lines []string) [][]string {
+	names := make([][]string, len(lines))
+
+	for i, line := range lines {
+		names[i] = ingredientWords(ParseIngredient(line).Name)
+	}
+
+	return names
+}
+
+// stepIngredients finds the ingredients a step mentions. Steps often shorten "all-purpose
+// flour" to "flour", so the last word of a name is enough.
+func stepIngredients(text string, names [][]string) []int {
+	used := []int{}
+	words := ingredientWords(text)
+
+	for i, name := range names {
+		if len(name) == 0 {
+			continue
+		}
+
+		if slices.Contains(words, name[len(name)-1]) {
+			used = append(used, i)
+		}
+	}
+
+	return used
+}
@@ -0,0 +1,91 @@
+package recipes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStepTimePattern(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Bake for 25 minutes.", []string{"25 minutes"}},
+		{"Bake for 20-25 mins, until golden.", []string{"20-25 mins"}},
+		{"Simmer 20 – 30 minutes", []string{"20 – 30 minutes"}},
+		{"Roast 1 to 2 hours", []string{"1 to 2 hours"}},
+		{"Let it rise for 1 1/2 hours", []string{"1 1/2 hours"}},
+		{"Chill 1/2 hour", []string{"1/2 hour"}},
+		{"Proof 2.5 hrs", []string{"2.5 hrs"}},
+		{"Braise for 1 hour and 15 minutes", []string{"1 hour and 15 minutes"}},
+		{"Braise for 1 hour, 15 minutes", []string{"1 hour, 15 minutes"}},
+		{"Whisk 30 secs, then rest 5 minutes", []string{"30 secs", "5 minutes"}},
+		{"Cook 5 m, then 10 s more", nil},
+		{"Use 5 minced cloves", nil},
+		{"Preheat the oven to 350°F", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := stepTimePattern.FindAllString(tt.text, -1); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepTimers(t *testing.T) {
+	tests := []struct {
+		text string
+		want []StepTimer
+	}{
+		{"Bake for 25 minutes.", []StepTimer{{"25 minutes", 25 * 60}}},
+		// Ranges time the longest, so the cook can check on it early
+		{"Bake for 20-25 mins, until golden.", []StepTimer{{"20-25 mins", 25 * 60}}},
+		{"Let it rise for 1 1/2 hours", []StepTimer{{"1 1/2 hours", 90 * 60}}},
+		{"Braise for 1 hour and 15 minutes", []StepTimer{{"1 hour and 15 minutes", 75 * 60}}},
+		{"Whisk 30 secs, then rest 5 minutes", []StepTimer{{"30 secs", 30}, {"5 minutes", 5 * 60}}},
+		{"Cook 5 m", []StepTimer{}},
+		{"Serve right away.", []StepTimer{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := stepTimers(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepIngredients(t *testing.T) {
+	names := ingredientNames([]string{
+		"2 cups all-purpose flour",
+		"1 tsp salt",
+		"3 large eggs",
+		"1 cup whole milk",
+		"2 tbsp unsalted butter, melted",
+	})
+
+	tests := []struct {
+		text string
+		want []int
+	}{
+		// Steps shorten names to their last word
+		{"Whisk the flour and salt together.", []int{0, 1}},
+		{"Beat in the eggs one at a time.", []int{2}},
+		{"Add the egg and milk.", []int{2, 3}},
+		{"Brush with the melted butter.", []int{4}},
+		// Words that are only the start of a name don't count
+		{"Use whole wheat if you like.", []int{}},
+		{"Let the batter rest.", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := stepIngredients(tt.text, names); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
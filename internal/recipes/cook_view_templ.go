@@ -0,0 +1,339 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.960
+package recipes
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+import (
+	"sourdough/internal/shared"
+	"strconv"
+)
+
+func CookView(recipe *Recipe, steps []CookStep) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Var2 := templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+			templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+			templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+			if !templ_7745c5c3_IsBuffer {
+				defer func() {
+					templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+					if templ_7745c5c3_Err == nil {
+						templ_7745c5c3_Err = templ_7745c5c3_BufErr
+					}
+				}()
+			}
+			ctx = templ.InitializeContext(ctx)
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<main class=\"cook-mode\" x-data=\"cookMode()\" @keydown.window.arrow-right=\"next()\" @keydown.window.arrow-left=\"previous()\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templ.JSONScript("cook-step-ingredients", stepIngredientLists(steps)).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<div class=\"toolbar\"><div class=\"toolbar--left\"><a href=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 templ.SafeURL
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL("/recipes/" + strconv.Itoa(recipe.ID) + "#cook-log"))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 14, Col: 81}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" class=\"button\"><i class=\"fa-solid fa-check\"></i>Done</a></div><div class=\"toolbar--right\"><span class=\"wake-lock\" x-show=\"awake === 'on'\"><i class=\"fa-solid fa-sun\"></i>Screen stays on</span> <span class=\"wake-lock\" x-show=\"awake === 'unsupported'\"><i class=\"fa-solid fa-moon\"></i>Your screen may turn off</span></div></div><h2>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 string
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(recipe.Title)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 21, Col: 21}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</h2>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if len(steps) == 0 {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<p class=\"cook-empty\">This recipe doesn't have any directions yet.</p>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			} else {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<div class=\"cook-layout\"><aside class=\"cook-ingredients\"><h3>Ingredients</h3>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				ingredients := Groups(recipe.Ingredients, recipe.IngredientSections)
+				for i, group := range ingredients {
+					if group.Title != "" {
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<h4>")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						var templ_7745c5c3_Var5 string
+						templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(group.Title)
+						if templ_7745c5c3_Err != nil {
+							return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 31, Col: 25}
+						}
+						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</h4>")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, " <ul>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					for j, ingredient := range group.Lines {
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<li x-bind:class=\"")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						var templ_7745c5c3_Var6 string
+						templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs("{ 'in-step': uses(" + strconv.Itoa(lineOffset(ingredients, i)+j) + ") }")
+						if templ_7745c5c3_Err != nil {
+							return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 35, Col: 101}
+						}
+						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "\">")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						var templ_7745c5c3_Var7 string
+						templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(ingredient)
+						if templ_7745c5c3_Err != nil {
+							return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 35, Col: 116}
+						}
+						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</li>")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "</ul>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "</aside><section class=\"cook-steps\" @touchstart=\"swipeStart($event)\" @touchend=\"swipeEnd($event)\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				for i, step := range steps {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "<article class=\"cook-step\" x-show=\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var8 string
+					templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs("current === " + strconv.Itoa(i))
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 42, Col: 75}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					if i > 0 {
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, " x-cloak")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "><small class=\"cook-step-number\">Step ")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var9 string
+					templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.Itoa(step.Number))
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 44, Col: 41}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, " of ")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var10 string
+					templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.Itoa(len(steps)))
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 44, Col: 73}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, " ")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					if step.Section != "" {
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "&middot; ")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						var templ_7745c5c3_Var11 string
+						templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(step.Section)
+						if templ_7745c5c3_Err != nil {
+							return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 46, Col: 33}
+						}
+						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "</small><p class=\"cook-step-text\">")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var12 string
+					templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(step.Text)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 49, Col: 45}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "</p>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					if len(step.Timers) > 0 {
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "<div class=\"cook-step-timers\">")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						for _, timer := range step.Timers {
+							templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, "<button type=\"button\" class=\"button button--action\" data-label=\"")
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+							var templ_7745c5c3_Var13 string
+							templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(timer.Label)
+							if templ_7745c5c3_Err != nil {
+								return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 53, Col: 87}
+							}
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+							templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "\" data-seconds=\"")
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+							var templ_7745c5c3_Var14 string
+							templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.Itoa(timer.Seconds))
+							if templ_7745c5c3_Err != nil {
+								return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 53, Col: 132}
+							}
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+							templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "\" @click=\"startTimer($el.dataset.label, Number($el.dataset.seconds))\"><i class=\"fa-solid fa-stopwatch\"></i>")
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+							var templ_7745c5c3_Var15 string
+							templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinStringErrs(timer.Label)
+							if templ_7745c5c3_Err != nil {
+								return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/cook_view.templ`, Line: 54, Col: 62}
+							}
+							_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+							templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "</button>")
+							if templ_7745c5c3_Err != nil {
+								return templ_7745c5c3_Err
+							}
+						}
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "</div>")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "</article>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "<nav class=\"cook-nav\"><button type=\"button\" class=\"button\" @click=\"previous()\" x-bind:disabled=\"current === 0\"><i class=\"fa-solid fa-chevron-left\"></i>Back</button> <button type=\"button\" class=\"button button--action\" @click=\"next()\" x-bind:disabled=\"current === steps.length - 1\">Next<i class=\"fa-solid fa-chevron-right\"></i></button></nav></section></div>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "<div class=\"cook-timers\" x-show=\"timers.length > 0\" x-cloak><template x-for=\"timer in timers\" :key=\"timer.id\"><div class=\"cook-timer\" x-bind:class=\"{ 'cook-timer--done': timer.done }\"><span class=\"cook-timer-label\" x-text=\"'Step ' + timer.step + ': ' + timer.label\"></span> <span class=\"cook-timer-time\" x-text=\"timer.done ? 'Done!' : format(timer.remaining)\"></span> <a class=\"button\" x-show=\"!timer.done\" @click=\"togglePause(timer)\"><i class=\"fa-solid\" x-bind:class=\"timer.paused ? 'fa-play' : 'fa-pause'\"></i></a> <a class=\"button\" @click=\"dismiss(timer)\"><i class=\"fa-solid fa-xmark\"></i></a></div></template></div></main><script>\n\t\t\tfunction cookMode() {\n\t\t\t\treturn {\n\t\t\t\t\tcurrent: 0,\n\t\t\t\t\tsteps: [],\n\t\t\t\t\ttimers: [],\n\t\t\t\t\tawake: '',\n\t\t\t\t\tlock: null,\n\t\t\t\t\taudio: null,\n\t\t\t\t\ttouchX: null,\n\n\t\t\t\t\tinit() {\n\t\t\t\t\t\tthis.steps = JSON.parse(document.getElementById('cook-step-ingredients').textContent);\n\t\t\t\t\t\tsetInterval(() => this.tick(), 250);\n\n\t\t\t\t\t\t// Browsers drop the wake lock whenever the page is hidden, so take it again on return\n\t\t\t\t\t\tthis.keepAwake();\n\t\t\t\t\t\tdocument.addEventListener('visibilitychange', () => {\n\t\t\t\t\t\t\tif (document.visibilityState === 'visible') this.keepAwake();\n\t\t\t\t\t\t});\n\t\t\t\t\t},\n\n\t\t\t\t\tasync keepAwake() {\n\t\t\t\t\t\tif (!('wakeLock' in navigator)) {\n\t\t\t\t\t\t\tthis.awake = 'unsupported';\n\t\t\t\t\t\t\treturn;\n\t\t\t\t\t\t}\n\n\t\t\t\t\t\ttry {\n\t\t\t\t\t\t\tthis.lock = await navigator.wakeLock.request('screen');\n\t\t\t\t\t\t\tthis.awake = 'on';\n\t\t\t\t\t\t\tthis.lock.addEventListener('release', () => { this.awake = ''; });\n\t\t\t\t\t\t} catch (e) {\n\t\t\t\t\t\t\tthis.awake = 'unsupported';\n\t\t\t\t\t\t}\n\t\t\t\t\t},\n\n\t\t\t\t\tuses(index) {\n\t\t\t\t\t\treturn (this.steps[this.current] || []).includes(index);\n\t\t\t\t\t},\n\n\t\t\t\t\tnext() {\n\t\t\t\t\t\tthis.current = Math.min(this.current + 1, this.steps.length - 1);\n\t\t\t\t\t},\n\n\t\t\t\t\tprevious() {\n\t\t\t\t\t\tthis.current = Math.max(this.current - 1, 0);\n\t\t\t\t\t},\n\n\t\t\t\t\tswipeStart(event) {\n\t\t\t\t\t\tthis.touchX = event.changedTouches[0].clientX;\n\t\t\t\t\t},\n\n\t\t\t\t\tswipeEnd(event) {\n\t\t\t\t\t\tif (this.touchX === null) return;\n\n\t\t\t\t\t\tconst distance = event.changedTouches[0].clientX - this.touchX;\n\t\t\t\t\t\tthis.touchX = null;\n\n\t\t\t\t\t\tif (distance < -60) this.next();\n\t\t\t\t\t\tif (distance > 60) this.previous();\n\t\t\t\t\t},\n\n\t\t\t\t\tstartTimer(label, seconds) {\n\t\t\t\t\t\t// Sound can only be started from a tap, so get it ready now for when the timer ends\n\t\t\t\t\t\tif (!this.audio && window.AudioContext) {\n\t\t\t\t\t\t\tthis.audio = new AudioContext();\n\t\t\t\t\t\t}\n\n\t\t\t\t\t\tthis.timers.push({\n\t\t\t\t\t\t\tid: Date.now() + Math.random(),\n\t\t\t\t\t\t\tlabel,\n\t\t\t\t\t\t\tstep: this.current + 1,\n\t\t\t\t\t\t\tendsAt: Date.now() + seconds * 1000,\n\t\t\t\t\t\t\tremaining: seconds,\n\t\t\t\t\t\t\tpaused: false,\n\t\t\t\t\t\t\tdone: false,\n\t\t\t\t\t\t});\n\t\t\t\t\t},\n\n\t\t\t\t\t// Timers count down from their end time, since intervals slow down in background tabs\n\t\t\t\t\ttick() {\n\t\t\t\t\t\tfor (const timer of this.timers) {\n\t\t\t\t\t\t\tif (timer.paused || timer.done) continue;\n\n\t\t\t\t\t\t\ttimer.remaining = Math.max(0, Math.ceil((timer.endsAt - Date.now()) / 1000));\n\t\t\t\t\t\t\tif (timer.remaining === 0) {\n\t\t\t\t\t\t\t\ttimer.done = true;\n\t\t\t\t\t\t\t\tthis.alarm();\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t}\n\t\t\t\t\t},\n\n\t\t\t\t\ttogglePause(timer) {\n\t\t\t\t\t\tif (timer.paused) {\n\t\t\t\t\t\t\ttimer.endsAt = Date.now() + timer.remaining * 1000;\n\t\t\t\t\t\t}\n\t\t\t\t\t\ttimer.paused = !timer.paused;\n\t\t\t\t\t},\n\n\t\t\t\t\tdismiss(timer) {\n\t\t\t\t\t\tthis.timers = this.timers.filter(t => t.id !== timer.id);\n\t\t\t\t\t},\n\n\t\t\t\t\talarm() {\n\t\t\t\t\t\tnavigator.vibrate?.([400, 200, 400, 200, 400]);\n\n\t\t\t\t\t\tif (!this.audio) return;\n\n\t\t\t\t\t\tfor (let i = 0; i < 3; i++) {\n\t\t\t\t\t\t\tconst beep = this.audio.createOscillator();\n\t\t\t\t\t\t\tconst start = this.audio.currentTime + i * 0.6;\n\t\t\t\t\t\t\tbeep.frequency.value = 880;\n\t\t\t\t\t\t\tbeep.connect(this.audio.destination);\n\t\t\t\t\t\t\tbeep.start(start);\n\t\t\t\t\t\t\tbeep.stop(start + 0.3);\n\t\t\t\t\t\t}\n\t\t\t\t\t},\n\n\t\t\t\t\tformat(seconds) {\n\t\t\t\t\t\tconst h = Math.floor(seconds / 3600);\n\t\t\t\t\t\tconst m = Math.floor((seconds % 3600) / 60);\n\t\t\t\t\t\tconst s = String(seconds % 60).padStart(2, '0');\n\n\t\t\t\t\t\treturn h > 0 ? `${h}:${String(m).padStart(2, '0')}:${s}` : `${m}:${s}`;\n\t\t\t\t\t},\n\t\t\t\t}\n\t\t\t}\n\t\t</script>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			return nil
+		})
+		templ_7745c5c3_Err = shared.Layout(recipe.Title).Render(templ.WithChildren(ctx, templ_7745c5c3_Var2), templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// stepIngredientLists is which ingredients each step uses, for highlighting them as the cook moves through the steps
+func stepIngredientLists(steps []CookStep) [][]int {
+	lists := make([][]int, len(steps))
+
+	for i, step := range steps {
+		lists[i] = step.Ingredients
+	}
+
+	return lists
+}
+
+var _ = templruntime.GeneratedTemplate
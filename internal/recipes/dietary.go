@@ -0,0 +1,524 @@
+package recipes
+
+import (
+	"net/url"
+	"slices"
+	"sourdough/internal/nutrition"
+	"strings"
+)
+
+// Labels a recipe can have. Allergen labels mean the recipe contains the allergen, and diet
+// labels mean it's suitable for the diet.
+const (
+	LabelNuts       = "nuts"
+	LabelDairy      = "dairy"
+	LabelGluten     = "gluten"
+	LabelEggs       = "eggs"
+	LabelShellfish  = "shellfish"
+	LabelSoy        = "soy"
+	LabelVegetarian = "vegetarian"
+	LabelVegan      = "vegan"
+)
+
+// DietaryLabel is how a label is shown. FilterParam is the URL parameter that leaves out
+// recipes containing an allergen.
+type DietaryLabel struct {
+	Name        string
+	Title       string
+	FilterParam string
+}
+
+var Allergens = []DietaryLabel{
+	{Name: LabelNuts, Title: "Nuts", FilterParam: "nutFree"},
+	{Name: LabelDairy, Title: "Dairy", FilterParam: "dairyFree"},
+	{Name: LabelGluten, Title: "Gluten", FilterParam: "glutenFree"},
+	{Name: LabelEggs, Title: "Eggs", FilterParam: "eggFree"},
+	{Name: LabelShellfish, Title: "Shellfish", FilterParam: "shellfishFree"},
+	{Name: LabelSoy, Title: "Soy", FilterParam: "soyFree"},
+}
+
+var Diets = []DietaryLabel{
+	{Name: LabelVegetarian, Title: "Vegetarian"},
+	{Name: LabelVegan, Title: "Vegan"},
+}
+
+// Traits of single ingredients that a recipe's labels are worked out from. Besides the
+// allergens, meat covers meat, poultry and fish, and animal other animal products like honey.
+const (
+	traitMeat   = "meat"
+	traitAnimal = "animal"
+)
+
+// dietaryRule gives an ingredient a trait when its name contains one of the phrases, unless
+// the phrase is only there as part of an exception, like "butter" in "peanut butter", or the
+// name says it doesn't apply, like "vegan sausage".
+type dietaryRule struct {
+	trait      string
+	phrases    []string
+	exceptions []string
+	unless     []string
+}
+
+var dietaryRules = keyDietaryRules([]dietaryRule{
+	{
+		trait: LabelNuts,
+		phrases: []string{
+			"nut", "almond", "walnut", "pecan", "cashew", "pistachio", "hazelnut", "filbert", "macadamia",
+			"chestnut", "brazil nut", "pine nut", "pignoli", "peanut", "groundnut", "praline", "marzipan",
+			"frangipane", "nutella", "gianduja", "pesto", "satay",
+		},
+		exceptions: []string{"water chestnut"},
+		unless:     []string{"nut free"},
+	},
+	{
+		trait: LabelDairy,
+		phrases: []string{
+			"milk", "butter", "buttermilk", "cream", "cheese", "yogurt", "yoghurt", "ghee", "whey", "casein",
+			"kefir", "parmesan", "parmigiano", "pecorino", "mozzarella", "burrata", "cheddar", "ricotta",
+			"feta", "brie", "camembert", "gruyere", "gouda", "emmental", "provolone", "manchego",
+			"mascarpone", "halloumi", "paneer", "gorgonzola", "roquefort", "stilton", "queso", "cotija",
+			"creme fraiche", "half and half", "custard", "alfredo", "bechamel", "pesto",
+		},
+		exceptions: []string{
+			"coconut milk", "coconut cream", "cream of coconut", "coconut yogurt", "almond milk", "oat milk",
+			"soy milk", "soya milk", "rice milk", "cashew milk", "nut milk", "plant milk",
+			"peanut butter", "almond butter", "cashew butter", "nut butter", "seed butter", "apple butter",
+			"cocoa butter", "shea butter", "butter bean", "butter lettuce", "cream of tartar",
+		},
+		unless: []string{"vegan", "dairy free", "non dairy", "plant based"},
+	},
+	{
+		trait: LabelGluten,
+		phrases: []string{
+			"flour", "wheat", "bread", "breadcrumb", "panko", "crouton", "pasta", "spaghetti", "linguine",
+			"fettuccine", "tagliatelle", "pappardelle", "penne", "rigatoni", "fusilli", "farfalle",
+			"macaroni", "lasagna", "lasagne", "orzo", "ravioli", "tortellini", "gnocchi", "noodle", "ramen",
+			"udon", "couscous", "bulgur", "barley", "rye", "spelt", "farro", "semolina", "durum", "seitan",
+			"malt", "beer", "soy sauce", "teriyaki", "tortilla", "pita", "naan", "baguette", "bun",
+			"bagel", "brioche", "croissant", "cracker", "biscuit", "cookie", "cookies", "brownie",
+			"brownies", "cake", "pastry", "phyllo", "filo", "pie crust", "dough", "sourdough",
+			"wonton wrapper", "dumpling wrapper", "matzo",
+		},
+		exceptions: []string{
+			"almond flour", "coconut flour", "rice flour", "corn flour", "chickpea flour", "gram flour",
+			"buckwheat flour", "tapioca flour", "potato flour", "cassava flour", "sorghum flour",
+			"teff flour", "rice noodle", "glass noodle", "cellophane noodle", "zucchini noodle",
+			"shirataki noodle", "rice pasta", "chickpea pasta", "lentil pasta", "corn tortilla",
+			"rice cake", "root beer", "ginger beer",
+		},
+		unless: []string{"gluten free"},
+	},
+	{
+		trait:      LabelEggs,
+		phrases:    []string{"egg", "yolk", "mayonnaise", "mayo", "aioli", "meringue", "hollandaise", "bearnaise", "custard", "eggnog"},
+		exceptions: []string{"flax egg", "chia egg", "egg replacer"},
+		unless:     []string{"vegan", "egg free"},
+	},
+	{
+		trait: LabelShellfish,
+		phrases: []string{
+			"shellfish", "shrimp", "prawn", "crab", "lobster", "crawfish", "crayfish", "langoustine",
+			"scallop", "clam", "mussel", "oyster", "cockle", "squid", "calamari", "octopus", "krill",
+		},
+		exceptions: []string{"oyster mushroom", "crab apple"},
+	},
+	{
+		trait: LabelSoy,
+		phrases: []string{
+			"soy", "soya", "soybean", "tofu", "tempeh", "edamame", "miso", "tamari", "shoyu", "natto",
+			"yuba", "bean curd", "teriyaki", "hoisin", "textured vegetable protein", "tvp",
+		},
+	},
+	{
+		trait: traitMeat,
+		phrases: []string{
+			"meat", "chicken", "beef", "steak", "pork", "bacon", "ham", "sausage", "lamb", "mutton", "turkey",
+			"veal", "duck", "goose", "venison", "rabbit", "goat", "bison", "prosciutto", "pancetta",
+			"guanciale", "salami", "pepperoni", "chorizo", "pastrami", "mortadella", "kielbasa", "andouille",
+			"bratwurst", "hot dog", "hamburger", "meatball", "mincemeat", "brisket", "sirloin", "tenderloin",
+			"short rib", "oxtail", "liver", "giblet", "lard", "suet", "tallow", "schmaltz", "dripping",
+			"gelatin", "gelatine", "bone broth", "fish", "anchovy", "salmon", "tuna", "cod", "haddock",
+			"halibut", "tilapia", "trout", "sardine", "mackerel", "herring", "snapper", "bass", "catfish",
+			"swordfish", "mahi mahi", "pollock", "caviar", "roe", "bonito", "dashi", "worcestershire",
+		},
+		exceptions: []string{"goat cheese", "goat milk", "duck egg", "hamburger bun", "hot dog bun"},
+		unless:     []string{"vegan", "vegetarian", "veggie", "meatless", "meat free", "plant based"},
+	},
+	{
+		trait:   traitAnimal,
+		phrases: []string{"honey", "bee pollen", "royal jelly"},
+		unless:  []string{"vegan"},
+	},
+})
+
+// keyDietaryRules puts the rules' phrases in the same form as the ingredient names they're
+// matched against. Plurals that Key doesn't undo, like "cookies", have to be listed as well.
+func keyDietaryRules(rules []dietaryRule) []dietaryRule {
+	keyAll := func(phrases []string) []string {
+		keys := make([]string, len(phrases))
+		for i, phrase := range phrases {
+			keys[i] = " " + nutrition.Key(phrase) + " "
+		}
+		return keys
+	}
+
+	for i := range rules {
+		rules[i].phrases = keyAll(rules[i].phrases)
+		rules[i].exceptions = keyAll(rules[i].exceptions)
+		rules[i].unless = keyAll(rules[i].unless)
+	}
+
+	return rules
+}
+
+// ingredientTraits are the traits the rules find in an ingredient's name.
+func ingredientTraits(name string) []string {
+	key := " " + nutrition.Key(name) + " "
+	traits := []string{}
+
+	for _, rule := range dietaryRules {
+		if containsAny(key, rule.unless) {
+			continue
+		}
+
+		text := key
+		for _, exception := range rule.exceptions {
+			for strings.Contains(text, exception) {
+				text = strings.Replace(text, exception, " ", 1)
+			}
+		}
+
+		if containsAny(text, rule.phrases) {
+			traits = append(traits, rule.trait)
+		}
+	}
+
+	return traits
+}
+
+func containsAny(text string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectDietary works out the recipe's labels from its ingredients. Pass it an expanded recipe
+// so components count too. traits holds what the LLM found in names the rules don't know,
+// keyed by nutrition.Key, which adds to what the rules find.
+func DetectDietary(recipe *Recipe, traits map[string][]string) []string {
+	found := map[string]bool{}
+	ingredients := nonBlank(recipe.Ingredients)
+
+	for _, line := range ingredients {
+		name := ParseIngredient(line).Name
+
+		for _, trait := range ingredientTraits(name) {
+			found[trait] = true
+		}
+		for _, trait := range traits[nutrition.Key(name)] {
+			found[trait] = true
+		}
+	}
+
+	labels := []string{}
+
+	for _, allergen := range Allergens {
+		if found[allergen.Name] {
+			labels = append(labels, allergen.Name)
+		}
+	}
+
+	// A recipe without ingredients isn't vegan, it's unknown
+	if len(ingredients) > 0 && !found[traitMeat] && !found[LabelShellfish] {
+		labels = append(labels, LabelVegetarian)
+
+		if !found[LabelDairy] && !found[LabelEggs] && !found[traitAnimal] {
+			labels = append(labels, LabelVegan)
+		}
+	}
+
+	return labels
+}
+
+// uncheckedIngredientNames are the names of the ingredients the rules found nothing in, and
+// that haven't been asked about yet, to ask the LLM about.
+func uncheckedIngredientNames(recipe *Recipe, traits map[string][]string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	for _, line := range nonBlank(recipe.Ingredients) {
+		name := ParseIngredient(line).Name
+		key := nutrition.Key(name)
+
+		if key == "" || seen[key] || len(ingredientTraits(name)) > 0 {
+			continue
+		}
+
+		if _, asked := traits[key]; !asked {
+			names = append(names, name)
+		}
+		seen[key] = true
+	}
+
+	return names
+}
+
+// ingredientTraitKeys are the keys of all of the recipe's ingredient names, for looking up
+// what the LLM found in them before.
+func ingredientTraitKeys(recipe *Recipe) []string {
+	keys := []string{}
+
+	for _, line := range nonBlank(recipe.Ingredients) {
+		keys = append(keys, nutrition.Key(ParseIngredient(line).Name))
+	}
+
+	return keys
+}
+
+// detectDietary sets the labels detected in the recipe's ingredients, including those of its
+// components, going by the rules and what the LLM found before.
+func (repo *Repository) detectDietary(recipe *Recipe) error {
+	expanded, err := repo.Expand(recipe)
+	if err != nil {
+		return err
+	}
+
+	traits, err := repo.GetIngredientTraits(recipe.UserID, ingredientTraitKeys(expanded))
+	if err != nil {
+		return err
+	}
+
+	recipe.DietaryDetected = DetectDietary(expanded, traits)
+
+	return nil
+}
+
+// redetectDietary detects the recipe's labels again and saves them if they changed.
+func (repo *Repository) redetectDietary(recipe *Recipe) (bool, error) {
+	detected := slices.Clone(recipe.DietaryDetected)
+
+	if err := repo.detectDietary(recipe); err != nil {
+		return false, err
+	}
+
+	if detected != nil && slices.Equal(detected, recipe.DietaryDetected) {
+		return false, nil
+	}
+
+	return true, repo.SetDietaryDetected(recipe.ID, recipe.DietaryDetected)
+}
+
+// redetectDependents detects the labels again of the recipes that use the recipe as a
+// component, directly or through other components, after it's changed or deleted.
+func (repo *Repository) redetectDependents(recipe *Recipe) error {
+	seen := map[int]bool{recipe.ID: true}
+	queue := []int{recipe.ID}
+
+	for len(queue) > 0 {
+		var dependents []*Recipe
+
+		err := repo.db.Select(&dependents, "SELECT * FROM recipes WHERE user_id = ? AND EXISTS (SELECT 1 FROM json_each(ingredient_sections) WHERE json_extract(value, '$.recipeId') = ?)", recipe.UserID, queue[0])
+		if err != nil {
+			return err
+		}
+
+		queue = queue[1:]
+
+		for _, dependent := range dependents {
+			if seen[dependent.ID] {
+				continue
+			}
+			seen[dependent.ID] = true
+
+			if _, err := repo.redetectDietary(dependent); err != nil {
+				return err
+			}
+
+			queue = append(queue, dependent.ID)
+		}
+	}
+
+	return nil
+}
+
+// RedetectDietary detects the labels again of the user's recipes that use any of the
+// ingredients, keyed by nutrition.Key, e.g. after the LLM found traits in them. It returns
+// how many recipes' labels changed.
+func (repo *Repository) RedetectDietary(userID int, keys []string) (int, error) {
+	recipes, err := repo.GetForUser(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+
+	for _, recipe := range recipes {
+		expanded, err := repo.Expand(recipe)
+		if err != nil {
+			return changed, err
+		}
+
+		if !slices.ContainsFunc(ingredientTraitKeys(expanded), func(key string) bool { return slices.Contains(keys, key) }) {
+			continue
+		}
+
+		updated, err := repo.redetectDietary(recipe)
+		if err != nil {
+			return changed, err
+		}
+
+		if updated {
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// HasDietaryLabel says whether the recipe has the label, as the user set it or else as detected.
+func (r *Recipe) HasDietaryLabel(label string) bool {
+	if value, ok := r.DietaryOverrides.Data[label]; ok {
+		return value
+	}
+
+	return r.DetectedDietaryLabel(label)
+}
+
+// DetectedDietaryLabel says whether the label was detected in the recipe's ingredients.
+func (r *Recipe) DetectedDietaryLabel(label string) bool {
+	return slices.Contains(r.DietaryDetected, label)
+}
+
+// DietaryOverride is how the user set the label, "yes" or "no", or "" to go by what was detected.
+func (r *Recipe) DietaryOverride(label string) string {
+	value, ok := r.DietaryOverrides.Data[label]
+
+	switch {
+	case !ok:
+		return ""
+	case value:
+		return "yes"
+	default:
+		return "no"
+	}
+}
+
+// SuitableDiets are the diets to show the recipe as suitable for. Vegan recipes are
+// vegetarian too, so that goes without saying.
+func (r *Recipe) SuitableDiets() []DietaryLabel {
+	diets := []DietaryLabel{}
+
+	for _, diet := range Diets {
+		if r.HasDietaryLabel(diet.Name) && !(diet.Name == LabelVegetarian && r.HasDietaryLabel(LabelVegan)) {
+			diets = append(diets, diet)
+		}
+	}
+
+	return diets
+}
+
+// ContainedAllergens are the allergens to show the recipe as containing.
+func (r *Recipe) ContainedAllergens() []DietaryLabel {
+	allergens := []DietaryLabel{}
+
+	for _, allergen := range Allergens {
+		if r.HasDietaryLabel(allergen.Name) {
+			allergens = append(allergens, allergen)
+		}
+	}
+
+	return allergens
+}
+
+// DietaryFilter keeps the recipes that are free of some allergens, and suitable for a diet.
+// Zero values mean no filter.
+type DietaryFilter struct {
+	FreeFrom []string
+	Diet     string
+}
+
+// ParseDietaryFilter reads a filter from URL parameters. Unknown diets are ignored.
+func ParseDietaryFilter(params map[string]string) DietaryFilter {
+	filter := DietaryFilter{}
+
+	for _, allergen := range Allergens {
+		if params[allergen.FilterParam] != "" {
+			filter.FreeFrom = append(filter.FreeFrom, allergen.Name)
+		}
+	}
+
+	for _, diet := range Diets {
+		if params["diet"] == diet.Name {
+			filter.Diet = diet.Name
+		}
+	}
+
+	return filter
+}
+
+// IsFreeFrom says whether the filter leaves out recipes with the allergen.
+func (f DietaryFilter) IsFreeFrom(allergen string) bool {
+	return slices.Contains(f.FreeFrom, allergen)
+}
+
+func (f DietaryFilter) setValues(values url.Values) {
+	for _, allergen := range Allergens {
+		if f.IsFreeFrom(allergen.Name) {
+			values.Set(allergen.FilterParam, "on")
+		}
+	}
+
+	if f.Diet != "" {
+		values.Set("diet", f.Diet)
+	}
+}
+
+// Matches says whether the recipe passes the filter.
+func (f DietaryFilter) Matches(recipe *Recipe) bool {
+	for _, allergen := range f.FreeFrom {
+		if recipe.HasDietaryLabel(allergen) {
+			return false
+		}
+	}
+
+	return f.Diet == "" || recipe.HasDietaryLabel(f.Diet)
+}
+
+// Filter keeps the recipes that pass the filter.
+func (f DietaryFilter) Filter(recipes []*Recipe) []*Recipe {
+	kept := []*Recipe{}
+
+	for _, recipe := range recipes {
+		if f.Matches(recipe) {
+			kept = append(kept, recipe)
+		}
+	}
+
+	return kept
+}
+
+// The SQL for whether a recipe has a label, as HasDietaryLabel works it out. It takes the
+// label twice.
+const dietaryLabelSQL = "COALESCE(json_extract(dietary_overrides, '$.' || ?), EXISTS (SELECT 1 FROM json_each(dietary_detected) WHERE value = ?))"
+
+// conditions are the filter as SQL conditions on the recipes table, with their arguments.
+func (f DietaryFilter) conditions() ([]string, []any) {
+	var where []string
+	var args []any
+
+	for _, allergen := range f.FreeFrom {
+		where = append(where, "NOT "+dietaryLabelSQL)
+		args = append(args, allergen, allergen)
+	}
+
+	if f.Diet != "" {
+		where = append(where, dietaryLabelSQL)
+		args = append(args, f.Diet, f.Diet)
+	}
+
+	return where, args
+}
@@ -0,0 +1,193 @@
+package recipes
+
+import (
+	"slices"
+	"sourdough/internal/nutrition"
+	"testing"
+)
+
+func TestIngredientTraits(t *testing.T) {
+	tests := []struct {
+		name   string
+		traits []string
+	}{
+		{"butter", []string{LabelDairy}},
+		{"peanut butter", []string{LabelNuts}},
+		{"coconut milk", []string{}},
+		{"water chestnuts", []string{}},
+		{"pesto", []string{LabelNuts, LabelDairy}},
+		{"almond flour", []string{LabelNuts}},
+		{"gluten free flour", []string{}},
+		{"large eggs", []string{LabelEggs}},
+		{"vegan sausage", []string{}},
+		{"chicken thighs", []string{traitMeat}},
+		{"worcestershire sauce", []string{traitMeat}},
+		{"oyster mushrooms", []string{}},
+		{"soy sauce", []string{LabelGluten, LabelSoy}},
+		{"honey", []string{traitAnimal}},
+		{"carrots", []string{}},
+	}
+
+	for _, test := range tests {
+		if traits := ingredientTraits(test.name); !slices.Equal(traits, test.traits) {
+			t.Errorf("%q: got %q, want %q", test.name, traits, test.traits)
+		}
+	}
+}
+
+func TestDetectDietary(t *testing.T) {
+	tests := []struct {
+		ingredients []string
+		traits      map[string][]string
+		labels      []string
+	}{
+		{[]string{"2 cups flour", "1 cup water", "1 tsp salt"}, nil, []string{LabelGluten, LabelVegetarian, LabelVegan}},
+		{[]string{"2 eggs", "1 cup milk"}, nil, []string{LabelDairy, LabelEggs, LabelVegetarian}},
+		{[]string{"1 lb shrimp", "2 tbsp butter"}, nil, []string{LabelDairy, LabelShellfish}},
+		{[]string{"1 tbsp honey", "1 cup oats"}, nil, []string{LabelVegetarian}},
+		{[]string{}, nil, []string{}},
+		// What the LLM found adds to what the rules find
+		{[]string{"1 tbsp lemon juice", "2 tbsp mystery relish"}, map[string][]string{nutrition.Key("mystery relish"): {LabelNuts}}, []string{LabelNuts, LabelVegetarian, LabelVegan}},
+	}
+
+	for _, test := range tests {
+		if labels := DetectDietary(&Recipe{Ingredients: test.ingredients}, test.traits); !slices.Equal(labels, test.labels) {
+			t.Errorf("%q: got %q, want %q", test.ingredients, labels, test.labels)
+		}
+	}
+}
+
+func createDietaryRecipe(t *testing.T, repo *Repository, userID int, title string, ingredients []string, sections ...Section) *Recipe {
+	t.Helper()
+
+	recipe, err := repo.Create(&Recipe{UserID: userID, Title: title, Ingredients: ingredients, IngredientSections: sections, Directions: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return recipe
+}
+
+func TestDietaryFilterSQLMatchesFilter(t *testing.T) {
+	repo := newTestRepository(t)
+
+	createDietaryRecipe(t, repo, 1, "Bread", []string{"flour", "water"})
+	createDietaryRecipe(t, repo, 1, "Omelette", []string{"eggs", "butter"})
+	createDietaryRecipe(t, repo, 1, "Salad", []string{"lettuce", "walnuts"})
+	tofu := createDietaryRecipe(t, repo, 1, "Tofu stir fry", []string{"tofu", "broccoli"})
+	steak := createDietaryRecipe(t, repo, 1, "Steak", []string{"steak"})
+
+	// The user knows better: this tofu is made without soy, and the steak is a plant based one
+	if err := repo.SetDietaryOverrides(tofu.ID, map[string]bool{LabelSoy: false}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetDietaryOverrides(steak.ID, map[string]bool{LabelVegan: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := repo.GetForUser(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters := []DietaryFilter{
+		{FreeFrom: []string{LabelGluten}},
+		{FreeFrom: []string{LabelDairy, LabelNuts}},
+		{FreeFrom: []string{LabelSoy}},
+		{Diet: LabelVegan},
+		{Diet: LabelVegetarian, FreeFrom: []string{LabelEggs}},
+	}
+
+	for _, filter := range filters {
+		page, err := repo.GetLibrary(1, LibraryQuery{Sort: SortTitle, Dietary: filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want []string
+		for _, recipe := range filter.Filter(all) {
+			want = append(want, recipe.Title)
+		}
+		slices.Sort(want)
+
+		var got []string
+		for _, recipe := range page.Recipes {
+			got = append(got, recipe.Title)
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("%+v: SQL found %q, Filter found %q", filter, got, want)
+		}
+	}
+}
+
+func TestComponentChangesRedetectDependents(t *testing.T) {
+	repo := newTestRepository(t)
+
+	crust := createDietaryRecipe(t, repo, 1, "Pie crust", []string{"flour", "butter"})
+	filling := createDietaryRecipe(t, repo, 1, "Apple filling", []string{"apples"}, Section{Title: "Pie crust", Component: true})
+	pie := createDietaryRecipe(t, repo, 1, "Apple pie", []string{"cream"}, Section{Title: "Apple filling", Component: true})
+
+	labels := func(id int) []string {
+		recipe, err := repo.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return recipe.DietaryDetected
+	}
+
+	if !slices.Contains(labels(pie.ID), LabelGluten) {
+		t.Fatalf("the pie's labels don't include its crust's: %q", labels(pie.ID))
+	}
+
+	crust.Ingredients = []string{"gluten free flour", "butter"}
+	if _, err := repo.Update(crust); err != nil {
+		t.Fatal(err)
+	}
+
+	if slices.Contains(labels(filling.ID), LabelGluten) || slices.Contains(labels(pie.ID), LabelGluten) {
+		t.Errorf("changing the crust didn't change the recipes using it: %q and %q", labels(filling.ID), labels(pie.ID))
+	}
+
+	if _, err := repo.Delete(crust.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if slices.Contains(labels(filling.ID), LabelDairy) || !slices.Contains(labels(filling.ID), LabelVegan) {
+		t.Errorf("deleting the crust didn't change the filling: %q", labels(filling.ID))
+	}
+}
+
+func TestIngredientTraitsArePerUser(t *testing.T) {
+	repo := newTestRepository(t)
+
+	mine := createDietaryRecipe(t, repo, 1, "Toast", []string{"bread", "mystery spread"})
+	theirs := createDietaryRecipe(t, repo, 2, "Crackers", []string{"crackers", "mystery spread"})
+
+	key := nutrition.Key("mystery spread")
+	if err := repo.SaveIngredientTraits(1, map[string][]string{key: {LabelNuts}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if traits, _ := repo.GetIngredientTraits(2, []string{key}); len(traits) != 0 {
+		t.Errorf("another user's traits were found: %q", traits)
+	}
+
+	changed, err := repo.RedetectDietary(1, []string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed != 1 {
+		t.Errorf("%d recipes changed, want 1", changed)
+	}
+
+	if recipe, _ := repo.Get(mine.ID); !slices.Contains(recipe.DietaryDetected, LabelNuts) {
+		t.Errorf("the user's recipe wasn't relabeled: %q", recipe.DietaryDetected)
+	}
+
+	if recipe, _ := repo.Get(theirs.ID); slices.Contains(recipe.DietaryDetected, LabelNuts) {
+		t.Errorf("another user's recipe was relabeled: %q", recipe.DietaryDetected)
+	}
+}
@@ -0,0 +1,202 @@
+package recipes
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDuration = errors.New("not a recognizable duration")
+
+// Matches an amount, an optional upper bound for ranges like "20-25", and a unit, e.g. "1 1/2 hours" or "20 to 25 mins"
+var durationPartPattern = regexp.MustCompile(
+	`(?i)(\d+\s+\d+/\d+|\d+/\d+|\d+(?:\.\d+)?)\s*(?:(?:-|–|to)\s*(\d+\s+\d+/\d+|\d+/\d+|\d+(?:\.\d+)?)\s*)?(days?|hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\.?`,
+)
+
+// Matches clock-style durations like "1:15"
+var clockDurationPattern = regexp.MustCompile(`^(\d+):([0-5]\d)$`)
+
+// Words that can sit between the parts of a duration without changing its meaning
+var durationFillerPattern = regexp.MustCompile(`(?i)\b(and|about|approx(imately)?|around|plus|total)\b|[\s,+~.]`)
+
+var durationUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"h": time.Hour,
+	"m": time.Minute,
+	"s": time.Second,
+}
+
+// ParseDuration turns the free-form times people (and LLMs) write, like "1 hr 15 mins",
+// into a duration. Ranges resolve to their upper bound.
+func ParseDuration(text string) (time.Duration, error) {
+	text = strings.TrimSpace(text)
+
+	if match := clockDurationPattern.FindStringSubmatch(text); match != nil {
+		hours, _ := strconv.Atoi(match[1])
+		minutes, _ := strconv.Atoi(match[2])
+		return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+	}
+
+	matches := durationPartPattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return 0, ErrInvalidDuration
+	}
+
+	if leftover := durationFillerPattern.ReplaceAllString(durationPartPattern.ReplaceAllString(text, ""), ""); leftover != "" {
+		return 0, ErrInvalidDuration
+	}
+
+	var total time.Duration
+
+	for _, match := range matches {
+		amount := match[1]
+		if match[2] != "" {
+			amount = match[2]
+		}
+
+		value, err := parseQuantity(amount)
+		if err != nil {
+			return 0, err
+		}
+
+		unit := durationUnits[strings.ToLower(match[3][:1])]
+		total += time.Duration(value * float64(unit))
+	}
+
+	return total.Round(time.Second), nil
+}
+
+// isDurationRange reports whether the text gives a range, like "20-25 mins".
+func isDurationRange(text string) bool {
+	for _, match := range durationPartPattern.FindAllStringSubmatch(text, -1) {
+		if match[2] != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseQuantity understands whole numbers, decimals, fractions and mixed numbers like "1 1/2".
+func parseQuantity(text string) (float64, error) {
+	var total float64
+
+	for _, field := range strings.Fields(text) {
+		if numerator, denominator, ok := strings.Cut(field, "/"); ok {
+			n, err := strconv.ParseFloat(numerator, 64)
+			if err != nil {
+				return 0, err
+			}
+
+			d, err := strconv.ParseFloat(denominator, 64)
+			if err != nil || d == 0 {
+				return 0, ErrInvalidDuration
+			}
+
+			total += n / d
+			continue
+		}
+
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		total += value
+	}
+
+	return total, nil
+}
+
+// Matches ISO-8601 durations like "PT1H15M", as used by schema.org and other recipe managers
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+func ParseISODuration(text string) (time.Duration, error) {
+	match := isoDurationPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(text)))
+	if match == nil || text == "P" || text == "PT" {
+		return 0, ErrInvalidDuration
+	}
+
+	var total time.Duration
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(match[i+1], 64)
+		if err != nil {
+			return 0, err
+		}
+
+		total += time.Duration(value * float64(unit))
+	}
+
+	return total, nil
+}
+
+// FormatDuration writes a duration the way recipes show times, e.g. "1 h 15 min".
+func FormatDuration(d time.Duration) string {
+	hours := int(d / time.Hour)
+	minutes := int((d % time.Hour) / time.Minute)
+
+	var parts []string
+
+	if hours > 0 {
+		parts = append(parts, strconv.Itoa(hours)+" h")
+	}
+
+	if minutes > 0 || hours == 0 {
+		parts = append(parts, strconv.Itoa(minutes)+" min")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func FormatMinutes(minutes int) string {
+	return FormatDuration(time.Duration(minutes) * time.Minute)
+}
+
+// Placeholders the LLM and other recipe managers write when a recipe doesn't give a time
+var missingTimePattern = regexp.MustCompile(`(?i)^(n/?a|none|unknown|not (given|listed|specified)|-+|–|—)?$`)
+
+// ParseMinutes reads a time as written by people, the LLM or other recipe managers (which
+// often use ISO-8601) into whole minutes. It returns nil if the time is missing or unreadable.
+func ParseMinutes(text string) *int {
+	text = strings.TrimSpace(text)
+
+	if missingTimePattern.MatchString(text) {
+		return nil
+	}
+
+	d, err := ParseISODuration(text)
+	if err != nil {
+		if d, err = ParseDuration(text); err != nil {
+			return nil
+		}
+	}
+
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	return &minutes
+}
+
+// FormatISODuration writes a duration as ISO-8601, e.g. "PT1H15M", for schema.org and other recipe managers.
+func FormatISODuration(d time.Duration) string {
+	hours := int(d / time.Hour)
+	minutes := int((d % time.Hour) / time.Minute)
+
+	iso := "PT"
+
+	if hours > 0 {
+		iso += strconv.Itoa(hours) + "H"
+	}
+
+	if minutes > 0 || hours == 0 {
+		iso += strconv.Itoa(minutes) + "M"
+	}
+
+	return iso
+}
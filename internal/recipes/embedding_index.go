@@ -0,0 +1,310 @@
+package recipes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sort"
+	"strings"
+)
+
+const (
+	// How much of a search result's score comes from meaning rather than matching words
+	SEMANTIC_WEIGHT = 0.7
+	// Results that don't share a word with the search need to be at least this similar
+	SEMANTIC_MIN_SIMILARITY = 0.3
+	// How much a term that only starts a word counts, compared to one matching a whole word
+	PREFIX_MATCH_WEIGHT  = 0.5
+	SEARCH_RESULT_LIMIT  = 50
+	EMBEDDING_BATCH_SIZE = 32
+	EMBEDDING_QUEUE_SIZE = 256
+	// Embedding text is truncated so long recipes don't blow through the model's context
+	EMBEDDING_MAX_CHARACTERS = 8000
+)
+
+// EmbeddingIndex keeps an embedding of every recipe and answers hybrid searches, which blend
+// semantic similarity with keyword matches. Saved recipes are embedded in the background.
+// Anything that was missed, like imports made by the CLI or recipes embedded by an earlier
+// model, is noticed at search time and caught up in the background too.
+type EmbeddingIndex struct {
+	repo     *Repository
+	embedder Embedder
+	queue    chan *Recipe
+	catchUps chan int
+}
+
+func NewEmbeddingIndex(repo *Repository, embedder Embedder) *EmbeddingIndex {
+	return &EmbeddingIndex{
+		repo:     repo,
+		embedder: embedder,
+		queue:    make(chan *Recipe, EMBEDDING_QUEUE_SIZE),
+		catchUps: make(chan int, EMBEDDING_QUEUE_SIZE),
+	}
+}
+
+// Start embeds queued recipes, and catches up users whose searches found stale embeddings, in
+// the background until the process exits.
+func (idx *EmbeddingIndex) Start() {
+	go func() {
+		for {
+			select {
+			case recipe := <-idx.queue:
+				if err := idx.refresh(recipe.UserID, []*Recipe{recipe}); err != nil {
+					log.Printf("Failed to embed recipe %d: %v", recipe.ID, err)
+				}
+			case userID := <-idx.catchUps:
+				if err := idx.catchUp(userID); err != nil {
+					log.Printf("Failed to catch up on embeddings for user %d: %v", userID, err)
+				}
+			}
+		}
+	}()
+}
+
+// Enqueue schedules a recipe to be embedded. It never blocks: if the queue is full, the recipe
+// is left for the next search to catch up on.
+func (idx *EmbeddingIndex) Enqueue(recipe *Recipe) {
+	select {
+	case idx.queue <- recipe:
+	default:
+	}
+}
+
+// Search ranks the user's recipes that pass the filter by how well they match the term, in
+// meaning or in words. It never waits on embedding recipes: those without a current
+// embedding are ranked by their words alone, and caught up on in the background.
+func (idx *EmbeddingIndex) Search(userID int, term string, filter DietaryFilter) ([]*SearchResult, error) {
+	recipes, err := idx.repo.GetForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings, stale, err := idx.current(userID, recipes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stale) > 0 {
+		idx.enqueueCatchUp(userID)
+	}
+
+	// Semantic search is an improvement on keyword search, so if the provider is unavailable
+	// (or the user is out of quota) it falls back to keyword matches alone
+	var query []float32
+	if len(embeddings) > 0 {
+		if vectors, err := idx.embedder.Embed(userID, []string{term}); err != nil {
+			log.Printf("Failed to embed search for user %d: %v", userID, err)
+		} else {
+			query = vectors[0]
+		}
+	}
+
+	terms := keywordTerms(term)
+	var results []*scoredResult
+
+	for _, recipe := range filter.Filter(recipes) {
+		keyword := keywordScore(recipe, terms)
+
+		// Without an embedding to compare, the words are all there is to go on
+		similarity := keyword
+		if embedding, ok := embeddings[recipe.ID]; ok && query != nil {
+			similarity = dot(query, embedding.Vector)
+		}
+
+		if keyword == 0 && similarity < SEMANTIC_MIN_SIMILARITY {
+			continue
+		}
+
+		results = append(results, &scoredResult{
+			SearchResult: &SearchResult{Recipe: recipe},
+			score:        SEMANTIC_WEIGHT*similarity + (1-SEMANTIC_WEIGHT)*keyword,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+
+	ranked := make([]*SearchResult, 0, min(len(results), SEARCH_RESULT_LIMIT))
+	for _, result := range results[:min(len(results), SEARCH_RESULT_LIMIT)] {
+		ranked = append(ranked, result.SearchResult)
+	}
+
+	return ranked, nil
+}
+
+type scoredResult struct {
+	*SearchResult
+	score float64
+}
+
+// enqueueCatchUp schedules the user's stale embeddings to be refreshed. Like Enqueue it never
+// blocks, since the next search will ask again.
+func (idx *EmbeddingIndex) enqueueCatchUp(userID int) {
+	select {
+	case idx.catchUps <- userID:
+	default:
+	}
+}
+
+// current returns the embeddings of the recipes that are up to date, and the recipes whose
+// embedding is missing, out of date or from another model.
+func (idx *EmbeddingIndex) current(userID int, recipes []*Recipe) (map[int]*Embedding, []*Recipe, error) {
+	embeddings, err := idx.repo.GetEmbeddings(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stale []*Recipe
+
+	for _, recipe := range recipes {
+		embedding, ok := embeddings[recipe.ID]
+
+		if !ok || embedding.Model != idx.embedder.Model() || embedding.ContentHash != contentHash(recipe) {
+			delete(embeddings, recipe.ID)
+			stale = append(stale, recipe)
+		}
+	}
+
+	return embeddings, stale, nil
+}
+
+// catchUp embeds all of the user's recipes that don't have a current embedding.
+func (idx *EmbeddingIndex) catchUp(userID int) error {
+	recipes, err := idx.repo.GetForUser(userID)
+	if err != nil {
+		return err
+	}
+
+	_, stale, err := idx.current(userID, recipes)
+	if err != nil {
+		return err
+	}
+
+	return idx.refresh(userID, stale)
+}
+
+func (idx *EmbeddingIndex) refresh(userID int, recipes []*Recipe) error {
+	for start := 0; start < len(recipes); start += EMBEDDING_BATCH_SIZE {
+		batch := recipes[start:min(start+EMBEDDING_BATCH_SIZE, len(recipes))]
+
+		texts := make([]string, len(batch))
+		for i, recipe := range batch {
+			texts[i] = embeddingText(recipe)
+		}
+
+		vectors, err := idx.embedder.Embed(userID, texts)
+		if err != nil {
+			return err
+		}
+
+		for i, recipe := range batch {
+			err := idx.repo.SaveEmbedding(&Embedding{
+				RecipeID:    recipe.ID,
+				Model:       idx.embedder.Model(),
+				ContentHash: contentHash(recipe),
+				Vector:      normalize(vectors[i]),
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// embeddingText is what a recipe's embedding is computed from, most important parts first.
+func embeddingText(recipe *Recipe) string {
+	text := strings.Join([]string{
+		recipe.Title,
+		strings.Join(recipe.Ingredients, "\n"),
+		recipe.Notes,
+		strings.Join(recipe.Directions, "\n"),
+	}, "\n\n")
+
+	if len(text) > EMBEDDING_MAX_CHARACTERS {
+		text = strings.ToValidUTF8(text[:EMBEDDING_MAX_CHARACTERS], "")
+	}
+
+	return text
+}
+
+func contentHash(recipe *Recipe) string {
+	sum := sha256.Sum256([]byte(embeddingText(recipe)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Words that say nothing about which recipe someone wants, like "something easy with squash"
+var searchStopWords = map[string]bool{
+	"and": true, "dish": true, "easy": true, "for": true, "from": true, "good": true, "make": true,
+	"meal": true, "recipe": true, "some": true, "something": true, "that": true, "the": true,
+	"thing": true, "this": true, "with": true, "without": true,
+}
+
+func keywordTerms(term string) []string {
+	var terms []string
+
+	for _, word := range ingredientWords(term) {
+		if len(word) > 2 && !searchStopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+// keywordScore rates how well a recipe matches the words of a search, from 0 to 1. Words in
+// the title count for more than words in the ingredients, which count for more than the rest.
+// A term can be the start of a word, so "choc" finds chocolate, but that counts for less.
+func keywordScore(recipe *Recipe, terms []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+
+	fields := []struct {
+		words  map[string]bool
+		weight float64
+	}{
+		{wordSet(recipe.Title), 3},
+		{wordSet(strings.Join(recipe.Ingredients, " ")), 2},
+		{wordSet(recipe.Notes + " " + strings.Join(recipe.Directions, " ")), 1},
+	}
+
+	var score float64
+
+	for _, term := range terms {
+		var best float64
+
+		for _, field := range fields {
+			if field.words[term] {
+				best = max(best, field.weight)
+			} else if hasPrefix(field.words, term) {
+				best = max(best, field.weight*PREFIX_MATCH_WEIGHT)
+			}
+		}
+
+		score += best
+	}
+
+	return score / (3 * float64(len(terms)))
+}
+
+func hasPrefix(words map[string]bool, prefix string) bool {
+	for word := range words {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func wordSet(text string) map[string]bool {
+	words := map[string]bool{}
+
+	for _, word := range ingredientWords(text) {
+		words[word] = true
+	}
+
+	return words
+}
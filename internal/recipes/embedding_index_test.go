@@ -0,0 +1,168 @@
+package recipes
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fakeEmbedder puts each text on the axis of the first of its words it knows, so tests can
+// decide what's similar: words on the same axis mean the same thing. It can be made to fail
+// like an unavailable provider.
+type fakeEmbedder struct {
+	model string
+	axes  [][]string
+	fail  bool
+	calls [][]string
+}
+
+func (e *fakeEmbedder) Model() string {
+	return e.model
+}
+
+func (e *fakeEmbedder) Embed(userID int, texts []string) ([][]float32, error) {
+	e.calls = append(e.calls, texts)
+
+	if e.fail {
+		return nil, errors.New("provider unavailable")
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e.axes))
+
+		for _, word := range ingredientWords(text) {
+			if axis := slices.IndexFunc(e.axes, func(words []string) bool { return slices.Contains(words, word) }); axis >= 0 {
+				vectors[i][axis] = 1
+				break
+			}
+		}
+	}
+
+	return vectors, nil
+}
+
+func newTestIndex(t *testing.T, embedder *fakeEmbedder, titles ...string) (*EmbeddingIndex, []*Recipe) {
+	t.Helper()
+
+	repo := newTestRepository(t)
+
+	var recipes []*Recipe
+	for _, title := range titles {
+		recipe, err := repo.Create(&Recipe{UserID: 1, Title: title, Ingredients: []string{}, Directions: []string{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recipes = append(recipes, recipe)
+	}
+
+	return NewEmbeddingIndex(repo, embedder), recipes
+}
+
+func resultTitles(results []*SearchResult) []string {
+	var titles []string
+	for _, result := range results {
+		titles = append(titles, result.Recipe.Title)
+	}
+
+	return titles
+}
+
+func TestSearchCatchesUpInTheBackground(t *testing.T) {
+	embedder := &fakeEmbedder{model: "fake", axes: [][]string{{"dessert", "cake"}, {"soup"}}}
+	idx, _ := newTestIndex(t, embedder, "Chocolate cake", "Pumpkin soup")
+
+	results, err := idx.Search(1, "cake", DietaryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(embedder.calls) != 0 {
+		t.Errorf("search embedded %q", embedder.calls)
+	}
+
+	if titles := resultTitles(results); !slices.Equal(titles, []string{"Chocolate cake"}) {
+		t.Errorf("before catching up: got %q", titles)
+	}
+
+	if got := len(idx.catchUps); got != 1 {
+		t.Fatalf("%d catch ups were queued, want 1", got)
+	}
+
+	if err := idx.catchUp(<-idx.catchUps); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dessert is like a cake in meaning, without sharing a word with it
+	results, err = idx.Search(1, "dessert", DietaryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if titles := resultTitles(results); !slices.Equal(titles, []string{"Chocolate cake"}) {
+		t.Errorf("after catching up: got %q", titles)
+	}
+
+	if len(idx.catchUps) != 0 {
+		t.Error("a catch up was queued with every embedding current")
+	}
+}
+
+func TestSearchFallsBackToKeywordsWhenTheProviderFails(t *testing.T) {
+	embedder := &fakeEmbedder{model: "fake", axes: [][]string{{"cake"}, {"soup"}}}
+	idx, _ := newTestIndex(t, embedder, "Chocolate cake", "Pumpkin soup", "Carrot cake")
+
+	if err := idx.catchUp(1); err != nil {
+		t.Fatal(err)
+	}
+
+	embedder.fail = true
+
+	results, err := idx.Search(1, "carrot cake", DietaryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if titles := resultTitles(results); !slices.Equal(titles, []string{"Carrot cake", "Chocolate cake"}) {
+		t.Errorf("got %q", titles)
+	}
+}
+
+func TestSearchIgnoresStaleEmbeddings(t *testing.T) {
+	embedder := &fakeEmbedder{model: "fake", axes: [][]string{{"dessert", "cake"}, {"soup"}}}
+	idx, recipes := newTestIndex(t, embedder, "Chocolate cake", "Pumpkin soup")
+
+	// Embeddings from another model, and of what the soup used to be, both look like a dessert
+	for _, embedding := range []*Embedding{
+		{RecipeID: recipes[0].ID, Model: "old", ContentHash: contentHash(recipes[0]), Vector: []float32{1, 0}},
+		{RecipeID: recipes[1].ID, Model: "fake", ContentHash: "old", Vector: []float32{1, 0}},
+	} {
+		if err := idx.repo.SaveEmbedding(embedding); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := idx.Search(1, "dessert", DietaryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 0 {
+		t.Errorf("got %q", resultTitles(results))
+	}
+}
+
+func TestSearchMatchesTheStartOfWords(t *testing.T) {
+	embedder := &fakeEmbedder{model: "fake", fail: true}
+	idx, _ := newTestIndex(t, embedder, "Chocolate cake", "Pumpkin soup", "Choc chip cookies")
+
+	results, err := idx.Search(1, "choc", DietaryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if titles := resultTitles(results); !slices.Equal(titles, []string{"Choc chip cookies", "Chocolate cake"}) {
+		t.Errorf("got %q", titles)
+	}
+}
@@ -0,0 +1,151 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"math"
+	"sourdough/internal/usage"
+	"strconv"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const HASHING_EMBEDDING_DIMENSIONS = 512
+
+var ErrEmbeddingMismatch = errors.New("the embeddings provider returned the wrong number of embeddings")
+
+// Embedder turns text into vectors whose closeness reflects how similar the texts are.
+type Embedder interface {
+	// Model identifies the embeddings, since vectors from different models can't be compared
+	Model() string
+	Embed(userID int, texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbedder uses the configured provider's embeddings endpoint. Tokens count against the
+// user's quota like any other LLM call.
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  string
+	meter  *usage.Meter
+}
+
+func NewOpenAIEmbedder(client *openai.Client, model string, meter *usage.Meter) *OpenAIEmbedder {
+	return &OpenAIEmbedder{client: client, model: model, meter: meter}
+}
+
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
+func (e *OpenAIEmbedder) Embed(userID int, texts []string) ([][]float32, error) {
+	if err := e.meter.Check(userID); err != nil {
+		return nil, err
+	}
+
+	resp, err := e.client.CreateEmbeddings(context.Background(), openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.EmbeddingModel(e.model),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.meter.Record(userID, e.model, resp.Usage); err != nil {
+		log.Printf("Failed to record embeddings usage: %v", err)
+	}
+
+	if len(resp.Data) != len(texts) {
+		return nil, ErrEmbeddingMismatch
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, embedding := range resp.Data {
+		if embedding.Index < 0 || embedding.Index >= len(texts) {
+			return nil, ErrEmbeddingMismatch
+		}
+
+		vectors[embedding.Index] = embedding.Embedding
+	}
+
+	return vectors, nil
+}
+
+// HashingEmbedder is a local embedder that needs no provider: it hashes words into a fixed
+// number of dimensions. It only captures shared vocabulary, not meaning, but it's free, works
+// offline and is deterministic, which makes it the fallback and the one to use in development.
+type HashingEmbedder struct {
+	dimensions int
+}
+
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	return &HashingEmbedder{dimensions: dimensions}
+}
+
+func (e *HashingEmbedder) Model() string {
+	return "local-hashing-" + strconv.Itoa(e.dimensions)
+}
+
+func (e *HashingEmbedder) Embed(userID int, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+
+	for i, text := range texts {
+		vector := make([]float32, e.dimensions)
+
+		for _, word := range ingredientWords(text) {
+			if len(word) < 3 {
+				continue
+			}
+
+			hash := fnv.New32a()
+			hash.Write([]byte(word))
+			sum := hash.Sum32()
+
+			// The sign bit spreads collisions out instead of letting them pile up
+			sign := float32(1)
+			if sum&(1<<31) != 0 {
+				sign = -1
+			}
+
+			vector[int(sum%uint32(e.dimensions))] += sign
+		}
+
+		vectors[i] = normalize(vector)
+	}
+
+	return vectors, nil
+}
+
+// normalize scales a vector to unit length, so cosine similarity is just a dot product.
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+
+	if sum == 0 {
+		return vector
+	}
+
+	length := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= length
+	}
+
+	return vector
+}
+
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+
+	return sum
+}
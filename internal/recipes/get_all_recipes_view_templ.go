@@ -8,7 +8,14 @@ package recipes
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-func GetAllRecipesView(recipes []*Recipe) templ.Component {
+import (
+	"sourdough/internal/security"
+	"sourdough/internal/shared"
+	"strconv"
+	"strings"
+)
+
+func GetAllRecipesView(page *LibraryPage, query LibraryQuery, drafts []*Draft) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -41,23 +48,250 @@ func GetAllRecipesView(recipes []*Recipe) templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<main class=\"my-recipes\" x-data=\"{ showInputs: false }\"><header><input type=\"text\" name=\"term\" placeholder=\"search your recipes\" hx-get=\"/search\" hx-trigger=\"keyup changed delay:250ms\" hx-target=\"#recipe-list\"> <span class=\"button button--action\" @click=\"showInputs = true\" x-show=\"!showInputs\"><i class=\"fa-solid fa-plus\"></i> new recipe</span></header><div class=\"add-recipe\" x-data=\"newRecipeComponent()\" x-show=\"showInputs\" @paste=\"handlePaste($event)\"><form action=\"/recipes\" method=\"POST\" enctype=\"multipart/form-data\" hx-boost=\"false\"><div class=\"recipe-placeholder\" x-show=\"!inputType\"><i class=\"fa-solid fa-paste\"></i>Paste in your recipe &mdash; you can use images or text!</div><div class=\"recipe-image\" x-show=\"inputType === 'image'\"><img x-bind:src=\"imagePreview\"></div><div class=\"recipe-text\" x-show=\"inputType === 'text'\" x-text=\"textPreview\"></div><div class=\"toolbar\"><div class=\"toolbar--left\"><button type=\"submit\" class=\"button button--action\" x-show=\"inputType\"><i class=\"fa-solid fa-floppy-disk\"></i>Save</button> <a class=\"button\" @click=\"cancel(); showInputs=false;\"><i class=\"fa-solid fa-xmark\"></i>Maybe next time?</a></div></div><input type=\"file\" name=\"recipeImage\" x-ref=\"recipeImage\" style=\"display: none;\" accept=\"image/*\"> <input type=\"text\" name=\"recipeText\" x-ref=\"recipeText\" style=\"display: none;\"></form></div><div id=\"recipe-list\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<main class=\"my-recipes\" x-data=\"{ showInputs: false, searchMode: 'text' }\"><header><div class=\"search\"><input type=\"text\" name=\"term\" x-bind:placeholder=\"searchMode === 'ingredients' ? 'what do you have? e.g. eggs, spinach, feta' : 'search your recipes, e.g. something cozy with squash'\" hx-get=\"/search\" hx-trigger=\"keyup changed delay:250ms\" hx-target=\"#recipe-list\" hx-include=\".search, .dietary-filters\"> <select name=\"mode\" x-model=\"searchMode\" hx-get=\"/search\" hx-trigger=\"change\" hx-target=\"#recipe-list\" hx-include=\".search, .dietary-filters\"><option value=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(SearchByText)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 17, Col: 34}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\">by description</option> <option value=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 string
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(SearchByIngredients)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 18, Col: 41}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\">by ingredients</option></select> <label x-show=\"searchMode === 'ingredients'\"><input type=\"checkbox\" name=\"staples\" value=\"on\" checked hx-get=\"/search\" hx-trigger=\"change\" hx-target=\"#recipe-list\" hx-include=\".search, .dietary-filters\"> ignore <a href=\"/pantry\">pantry staples</a></label></div><span class=\"button button--action\" @click=\"showInputs = true\" x-show=\"!showInputs\"><i class=\"fa-solid fa-plus\"></i> new recipe</span><nav class=\"header-links\"><a href=\"/collections\" class=\"button button--subdued\" title=\"Collections\"><i class=\"fa-solid fa-layer-group\"></i></a> <a href=\"/cookbook\" class=\"button button--subdued\" title=\"Make a cookbook\"><i class=\"fa-solid fa-book\"></i></a> <a href=\"/settings\" class=\"button button--subdued\" title=\"Settings\"><i class=\"fa-solid fa-gear\"></i></a></nav></header><div class=\"add-recipe\" x-data=\"newRecipeComponent()\" x-show=\"showInputs\" @paste=\"handlePaste($event)\" @dragover.prevent @drop.prevent=\"handleDrop($event)\"><form action=\"/recipes\" method=\"POST\" enctype=\"multipart/form-data\" hx-boost=\"false\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = security.CSRFField().Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<div class=\"recipe-placeholder\" x-show=\"!inputType\"><i class=\"fa-solid fa-paste\"></i>Paste or drop in your recipe &mdash; you can use images, PDFs or text! <a class=\"button button--subdued\" @click=\"$refs.imagePicker.click()\"><i class=\"fa-solid fa-images\"></i>Choose files</a></div><div class=\"recipe-image\" x-show=\"inputType === 'image'\"><template x-for=\"(image, index) in images\" :key=\"image.preview\"><figure><img x-bind:src=\"image.preview\"><figcaption>Page <span x-text=\"index + 1\"></span> <a class=\"button\" @click=\"removeImage(index)\"><i class=\"fa-solid fa-xmark\"></i></a></figcaption></figure></template></div><div class=\"recipe-text\" x-show=\"inputType === 'text'\" x-text=\"textPreview\"></div><div class=\"recipe-text\" x-show=\"inputType === 'pdf'\"><i class=\"fa-solid fa-file-pdf\"></i>&nbsp;<span x-text=\"pdfName\"></span></div><div class=\"toolbar\"><div class=\"toolbar--left\"><button type=\"submit\" class=\"button button--action\" x-show=\"inputType\"><i class=\"fa-solid fa-floppy-disk\"></i>Save</button> <a class=\"button\" x-show=\"inputType === 'image'\" @click=\"$refs.imagePicker.click()\"><i class=\"fa-solid fa-plus\"></i>Add a page</a> <a class=\"button\" @click=\"cancel(); showInputs=false;\"><i class=\"fa-solid fa-xmark\"></i>Maybe next time?</a></div><div class=\"toolbar--right\"><label class=\"button button--subdued\"><input type=\"checkbox\" name=\"review\" checked>Review before saving</label></div></div><input type=\"file\" name=\"recipeImage\" x-ref=\"recipeImage\" style=\"display: none;\" accept=\"image/*\" multiple> <input type=\"file\" name=\"recipePDF\" x-ref=\"recipePDF\" style=\"display: none;\" accept=\"application/pdf\"> <input type=\"file\" x-ref=\"imagePicker\" style=\"display: none;\" accept=\"image/*,application/pdf\" multiple @change=\"addFiles($event.target.files); $event.target.value = ''\"> <input type=\"text\" name=\"recipeText\" x-ref=\"recipeText\" style=\"display: none;\"></form></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = DraftListComponent(drafts).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<form class=\"library-filters\" hx-get=\"/\" hx-trigger=\"change, submit\" hx-sync=\"this:replace\" hx-target=\"#recipe-list\" hx-push-url=\"true\"><label>Sort by <select name=\"sort\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, option := range SortLabels {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<option value=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var5 string
+				templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(option.Value)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 73, Col: 35}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				if option.Value == query.Sort {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, " selected")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, ">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var6 string
+				templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(option.Label)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 73, Col: 93}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</option>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</select></label> <label>Ready in <input type=\"number\" name=\"maxMinutes\" min=\"1\" placeholder=\"any\" value=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var7 string
+			templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(filterValue(query.MaxMinutes))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 79, Col: 107}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "\"> mins</label> <label>At most <input type=\"number\" name=\"maxIngredients\" min=\"1\" placeholder=\"any\" value=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			for _, recipe := range recipes {
-				templ_7745c5c3_Err = RecipeComponent(recipe).Render(ctx, templ_7745c5c3_Buffer)
+			var templ_7745c5c3_Var8 string
+			templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(filterValue(query.MaxIngredients))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 84, Col: 115}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "\"> ingredients</label> <label>Serves at least <input type=\"number\" name=\"minServings\" min=\"1\" placeholder=\"any\" value=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var9 string
+			templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(filterValue(query.MinServings))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 89, Col: 109}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "\"></label> <label><input type=\"checkbox\" name=\"favorites\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if query.Favorites {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, " checked")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</div></main><script>\n\t\t\tfunction newRecipeComponent() {\n\t\t\t\treturn {\n\t\t\t\t\tinputType: '',\n\t\t\t\t\timagePreview: '',\n\t\t\t\t\ttextPreview: '',\n\t\t\t\t\t\n\t\t\t\t\thandlePaste(event) {\n\t\t\t\t\t\tconst items = event.clipboardData?.items;\n\t\t\t\t\t\tif (!items) return;\n\t\t\t\t\t\t\n\t\t\t\t\t\tfor (let item of items) {\n\t\t\t\t\t\t\tif (item.type.indexOf('image') !== -1) {\n\t\t\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\t\t\tconst file = item.getAsFile();\n\t\t\t\t\t\t\t\tif (file) {\n\t\t\t\t\t\t\t\t\tthis.setImageFile(file);\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t\tbreak;\n\t\t\t\t\t\t\t} else if (item.kind === 'string' && item.type === 'text/plain') {\n\t\t\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\t\t\titem.getAsString(s => this.setText(s));\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t}\n\t\t\t\t\t},\n\n\t\t\t\t\tsetText(text) {\n\t\t\t\t\t\tthis.inputType=\"text\";\n\t\t\t\t\t\tthis.textPreview = text;\n\t\t\t\t\t\tthis.$refs.recipeText.value=text;\n\t\t\t\t\t},\n\t\t\t\t\t\n\t\t\t\t\tsetImageFile(file) {\n\t\t\t\t\t\tthis.inputType = \"image\";\n\t\t\t\t\t\tconst reader = new FileReader();\n\t\t\t\t\t\treader.onload = (e) => {\n\t\t\t\t\t\t\tthis.imagePreview = e.target.result;\n\t\t\t\t\t\t};\n\t\t\t\t\t\treader.readAsDataURL(file);\n\t\t\t\t\t\t\n\t\t\t\t\t\t// Set the file input\n\t\t\t\t\t\tconst dt = new DataTransfer();\n\t\t\t\t\t\tdt.items.add(file);\n\t\t\t\t\t\tthis.$refs.recipeImage.files = dt.files;\n\t\t\t\t\t},\n\n\t\t\t\t\tcancel() {\n\t\t\t\t\t\tthis.inputType='';\n\t\t\t\t\t\tthis.imagePreview = '';\n\t\t\t\t\t\tthis.textPreview = '';\n\t\t\t\t\t\tthis.$refs.recipeImage.value = '';\n\t\t\t\t\t\tthis.$refs.recipeText.value = '';\n\t\t\t\t\t}\n\t\t\t\t}\n\t\t\t}\n\t\t</script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "> Favorites only</label><fieldset class=\"dietary-filters\"><label>Diet <select name=\"diet\"><option value=\"\">any</option> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, diet := range Diets {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "<option value=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var10 string
+				templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(diet.Name)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 101, Col: 33}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				if diet.Name == query.Dietary.Diet {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, " selected")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, ">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var11 string
+				templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(strings.ToLower(diet.Title))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 101, Col: 111}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "</option>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "</select></label> Without ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, allergen := range Allergens {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "<label><input type=\"checkbox\" name=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var12 string
+				templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(allergen.FilterParam)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 108, Col: 57}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				if query.Dietary.IsFreeFrom(allergen.Name) {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, " checked")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "> ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var13 string
+				templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(strings.ToLower(allergen.Title))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 109, Col: 40}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "</label>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "</fieldset></form><div id=\"recipe-list\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = RecipePage(page, query).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "</div></main><script>\n\t\t\tfunction newRecipeComponent() {\n\t\t\t\treturn {\n\t\t\t\t\tinputType: '',\n\t\t\t\t\timages: [],\n\t\t\t\t\ttextPreview: '',\n\t\t\t\t\tpdfName: '',\n\t\t\t\t\t\n\t\t\t\t\thandlePaste(event) {\n\t\t\t\t\t\tconst items = event.clipboardData?.items;\n\t\t\t\t\t\tif (!items) return;\n\n\t\t\t\t\t\tconst files = [];\n\t\t\t\t\t\tlet textItem = null;\n\n\t\t\t\t\t\tfor (let item of items) {\n\t\t\t\t\t\t\tif (item.type.indexOf('image') !== -1) {\n\t\t\t\t\t\t\t\tconst file = item.getAsFile();\n\t\t\t\t\t\t\t\tif (file) {\n\t\t\t\t\t\t\t\t\tfiles.push(file);\n\t\t\t\t\t\t\t\t}\n\t\t\t\t\t\t\t} else if (item.kind === 'string' && item.type === 'text/plain') {\n\t\t\t\t\t\t\t\ttextItem = item;\n\t\t\t\t\t\t\t}\n\t\t\t\t\t\t}\n\n\t\t\t\t\t\t// Images win over text, since copying an image often brings its alt text along\n\t\t\t\t\t\tif (files.length > 0) {\n\t\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\t\tthis.addImageFiles(files);\n\t\t\t\t\t\t} else if (textItem) {\n\t\t\t\t\t\t\tevent.preventDefault();\n\t\t\t\t\t\t\ttextItem.getAsString(s => this.setText(s));\n\t\t\t\t\t\t}\n\t\t\t\t\t},\n\n\t\t\t\t\thandleDrop(event) {\n\t\t\t\t\t\tconst files = [...(event.dataTransfer?.files || [])];\n\t\t\t\t\t\tif (files.length > 0) {\n\t\t\t\t\t\t\tthis.addFiles(files);\n\t\t\t\t\t\t}\n\t\t\t\t\t},\n\n\t\t\t\t\t// PDFs are imported on their own, anything else is treated as another page image\n\t\t\t\t\taddFiles(files) {\n\t\t\t\t\t\tconst pdf = [...files].find(f => f.type === 'application/pdf');\n\t\t\t\t\t\tif (pdf) {\n\t\t\t\t\t\t\tthis.setPDFFile(pdf);\n\t\t\t\t\t\t\treturn;\n\t\t\t\t\t\t}\n\n\t\t\t\t\t\tthis.addImageFiles([...files].filter(f => f.type.indexOf('image') !== -1));\n\t\t\t\t\t},\n\n\t\t\t\t\tsetPDFFile(file) {\n\t\t\t\t\t\tthis.clearImages();\n\t\t\t\t\t\tthis.textPreview = '';\n\t\t\t\t\t\tthis.$refs.recipeText.value = '';\n\t\t\t\t\t\tthis.inputType = \"pdf\";\n\t\t\t\t\t\tthis.pdfName = file.name;\n\n\t\t\t\t\t\tconst dt = new DataTransfer();\n\t\t\t\t\t\tdt.items.add(file);\n\t\t\t\t\t\tthis.$refs.recipePDF.files = dt.files;\n\t\t\t\t\t},\n\n\t\t\t\t\tclearPDF() {\n\t\t\t\t\t\tthis.pdfName = '';\n\t\t\t\t\t\tthis.$refs.recipePDF.value = '';\n\t\t\t\t\t},\n\n\t\t\t\t\tsetText(text) {\n\t\t\t\t\t\tthis.clearImages();\n\t\t\t\t\t\tthis.clearPDF();\n\t\t\t\t\t\tthis.inputType=\"text\";\n\t\t\t\t\t\tthis.textPreview = text;\n\t\t\t\t\t\tthis.$refs.recipeText.value=text;\n\t\t\t\t\t},\n\t\t\t\t\t\n\t\t\t\t\taddImageFiles(files) {\n\t\t\t\t\t\tif (files.length === 0) return;\n\n\t\t\t\t\t\tthis.clearPDF();\n\t\t\t\t\t\tthis.inputType = \"image\";\n\t\t\t\t\t\tthis.textPreview = '';\n\t\t\t\t\t\tthis.$refs.recipeText.value = '';\n\n\t\t\t\t\t\tfor (let file of files) {\n\t\t\t\t\t\t\tthis.images.push({ file, preview: URL.createObjectURL(file) });\n\t\t\t\t\t\t}\n\n\t\t\t\t\t\tthis.syncImageInput();\n\t\t\t\t\t},\n\n\t\t\t\t\tremoveImage(index) {\n\t\t\t\t\t\tURL.revokeObjectURL(this.images[index].preview);\n\t\t\t\t\t\tthis.images.splice(index, 1);\n\t\t\t\t\t\tthis.syncImageInput();\n\n\t\t\t\t\t\tif (this.images.length === 0) {\n\t\t\t\t\t\t\tthis.inputType = '';\n\t\t\t\t\t\t}\n\t\t\t\t\t},\n\n\t\t\t\t\t// The images are sent as the file input's files, in page order\n\t\t\t\t\tsyncImageInput() {\n\t\t\t\t\t\tconst dt = new DataTransfer();\n\t\t\t\t\t\tfor (let image of this.images) {\n\t\t\t\t\t\t\tdt.items.add(image.file);\n\t\t\t\t\t\t}\n\t\t\t\t\t\tthis.$refs.recipeImage.files = dt.files;\n\t\t\t\t\t},\n\n\t\t\t\t\tclearImages() {\n\t\t\t\t\t\tfor (let image of this.images) {\n\t\t\t\t\t\t\tURL.revokeObjectURL(image.preview);\n\t\t\t\t\t\t}\n\t\t\t\t\t\tthis.images = [];\n\t\t\t\t\t\tthis.$refs.recipeImage.value = '';\n\t\t\t\t\t},\n\n\t\t\t\t\tcancel() {\n\t\t\t\t\t\tthis.inputType='';\n\t\t\t\t\t\tthis.clearImages();\n\t\t\t\t\t\tthis.clearPDF();\n\t\t\t\t\t\tthis.textPreview = '';\n\t\t\t\t\t\tthis.$refs.recipeText.value = '';\n\t\t\t\t\t}\n\t\t\t\t}\n\t\t\t}\n\t\t</script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 			return nil
 		})
-		templ_7745c5c3_Err = Layout("My Recipes").Render(templ.WithChildren(ctx, templ_7745c5c3_Var2), templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = shared.Layout("My Recipes").Render(templ.WithChildren(ctx, templ_7745c5c3_Var2), templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -65,4 +299,70 @@ func GetAllRecipesView(recipes []*Recipe) templ.Component {
 	})
 }
 
+// RecipePage is one page of the library, ending in a placeholder that loads the next page
+// when it scrolls into view.
+func RecipePage(page *LibraryPage, query LibraryQuery) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var14 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var14 == nil {
+			templ_7745c5c3_Var14 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		for _, recipe := range page.Recipes {
+			templ_7745c5c3_Err = RecipeComponent(recipe).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if len(page.Recipes) == 0 && query.Cursor == "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "<p class=\"no-results\">No recipes match those filters.</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if page.NextCursor != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "<div class=\"load-more\" hx-get=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var15 string
+			templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinStringErrs(query.NextPageURL(page.NextCursor))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/recipes/get_all_recipes_view.templ`, Line: 262, Col: 68}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "\" hx-trigger=\"revealed\" hx-swap=\"outerHTML\"><i class=\"fa-solid fa-spinner fa-spin\"></i></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		return nil
+	})
+}
+
+func filterValue(value int) string {
+	if value == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(value)
+}
+
 var _ = templruntime.GeneratedTemplate
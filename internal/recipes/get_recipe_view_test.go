@@ -0,0 +1,86 @@
+package recipes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"sourdough/internal/database"
+	"strings"
+	"testing"
+)
+
+var jsonLDScriptPattern = regexp.MustCompile(`(?s)<script id="recipe-jsonld" type="application/ld\+json">(.*?)</script>`)
+
+func TestGetRecipeViewDescribesTheRecipe(t *testing.T) {
+	recipe := &Recipe{
+		ID:                 1,
+		Title:              "Shortbread",
+		Ingredients:        database.JSONArray[string]{"3/4 cup sugar", "1 cup butter"},
+		IngredientSections: database.JSONArray[Section]{},
+		Directions:         database.JSONArray[string]{"Cream the butter and sugar.", "Bake until golden."},
+		DirectionSections:  database.JSONArray[Section]{{Title: "Bake", Start: 1}},
+		PrepTime:           "15 minutes",
+		CookTime:           "1 hour",
+		Servings:           12,
+	}
+	recipe.normalizeTimes()
+
+	var page bytes.Buffer
+	jsonLD := NewJSONLDRecipe(recipe, []string{"https://example.com/recipes/1/photos/2"})
+	view := GetRecipeView(recipe, []int{2}, jsonLD, nil, nil, nil, EstimateNutrition(recipe, nil), false, nil, nil)
+	if err := view.Render(context.Background(), &page); err != nil {
+		t.Fatal(err)
+	}
+	html := page.String()
+
+	script := jsonLDScriptPattern.FindStringSubmatch(html)
+	if script == nil {
+		t.Fatal("the page has no JSON-LD")
+	}
+
+	var described JSONLDRecipe
+	if err := json.Unmarshal([]byte(script[1]), &described); err != nil {
+		t.Fatal(err)
+	}
+
+	if described.Type != "Recipe" || described.Name != "Shortbread" || described.RecipeYield != "12" {
+		t.Errorf("got %+v", described)
+	}
+
+	if described.PrepTime != "PT15M" || described.CookTime != "PT1H" || described.TotalTime != "PT1H15M" {
+		t.Errorf("got times %q, %q and %q", described.PrepTime, described.CookTime, described.TotalTime)
+	}
+
+	if len(described.RecipeIngredient) != 2 || described.RecipeIngredient[0] != "3/4 cup sugar" {
+		t.Errorf("got ingredients %q", described.RecipeIngredient)
+	}
+
+	if len(described.RecipeInstructions) != 2 || described.RecipeInstructions[1].Type != "HowToSection" {
+		t.Errorf("got instructions %+v", described.RecipeInstructions)
+	}
+
+	for _, markup := range []string{
+		`<main class="recipe h-recipe">`,
+		`<h2 class="p-name">Shortbread</h2>`,
+		`<img class="u-photo"`,
+		`<li class="p-ingredient">3/4 cup sugar</li>`,
+		`<li class="p-ingredient">1 cup butter</li>`,
+		`<div class="e-instructions">`,
+		`<span class="p-yield">12</span>`,
+		`<time class="dt-duration" datetime="PT1H15M">`,
+		`class="nutrition-facts p-nutrition"`,
+	} {
+		if !strings.Contains(html, markup) {
+			t.Errorf("the page is missing %s", markup)
+		}
+	}
+
+	if strings.Count(html, "dt-duration") != 1 {
+		t.Errorf("the page has %d durations, want 1", strings.Count(html, "dt-duration"))
+	}
+
+	if strings.Contains(html, `class=""`) {
+		t.Error("the page has empty class attributes")
+	}
+}
@@ -1,12 +1,18 @@
 package recipes
 
 import (
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"sourdough/internal/database"
 	"sourdough/internal/shared"
+	"sourdough/internal/usage"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -14,12 +20,14 @@ import (
 type Handler struct {
 	repo       *Repository
 	llmService *LLMService
+	index      *EmbeddingIndex
 }
 
-func NewHandler(repo *Repository, llmService *LLMService) *Handler {
+func NewHandler(repo *Repository, llmService *LLMService, index *EmbeddingIndex) *Handler {
 	return &Handler{
 		repo:       repo,
 		llmService: llmService,
+		index:      index,
 	}
 }
 
@@ -52,8 +60,100 @@ func (h *Handler) GetRecipe(c *fiber.Ctx) error {
 		return c.Status(403).SendString("Forbidden")
 	}
 
+	photoIDs, err := h.repo.GetPhotoIDs(recipe.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	recipe, err = h.repo.Expand(recipe)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	// Structured data is read by other sites and tools, so its links need to be absolute
+	recipeURL := c.BaseURL() + "/recipes/" + strconv.Itoa(recipe.ID)
+
+	var photoURLs []string
+	for _, photoID := range photoIDs {
+		photoURLs = append(photoURLs, recipeURL+"/photos/"+strconv.Itoa(photoID))
+	}
+
+	jsonLD := NewJSONLDRecipe(recipe, photoURLs)
+	jsonLD.URL = recipeURL
+
+	similar, err := h.repo.GetSimilarRecipes(recipe.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	cookLog, err := h.repo.GetCookLog(recipe.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	collections, err := h.repo.GetRecipeCollections(user.Id, recipe.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	foods, err := h.repo.GetIngredientFoods(recipe.UserID, ingredientNameKeys(recipe))
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	traits, err := h.repo.GetIngredientTraits(recipe.UserID, ingredientTraitKeys(recipe))
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	var parent *Recipe
+	if recipe.ParentID != nil {
+		if parent, err = h.repo.Get(*recipe.ParentID); err != nil {
+			return c.Status(500).SendString(err.Error())
+		}
+	}
+
+	variations, err := h.repo.GetVariations(recipe.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := GetRecipeView(recipe, photoIDs, jsonLD, similar, cookLog, collections, EstimateNutrition(recipe, foods), len(uncheckedIngredientNames(recipe, traits)) > 0, parent, variations)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+// CookRecipe shows the recipe one step at a time, for following along in the kitchen.
+func (h *Handler) CookRecipe(c *fiber.Ctx) error {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).SendString("Invalid recipe ID")
+	}
+
+	recipe, err := h.repo.Get(id)
+
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	} else if recipe == nil {
+		return c.Status(404).SendString("Recipe not found")
+	}
+
+	if user.Id != recipe.UserID {
+		return c.Status(403).SendString("Forbidden")
+	}
+
+	recipe, err = h.repo.Expand(recipe)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
 	c.Set("Content-Type", "text/html")
-	component := GetRecipeView(recipe)
+	component := CookView(recipe, CookSteps(recipe))
 	return component.Render(c.Context(), c.Response().BodyWriter())
 }
 
@@ -86,24 +186,86 @@ func (h *Handler) EditRecipe(c *fiber.Ctx) error {
 		return c.Status(403).SendString("Forbidden")
 	}
 
+	if err := h.repo.refreshComponentTitles(recipe); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
 	c.Set("Content-Type", "text/html")
 	component := EditRecipeView(recipe)
 	return component.Render(c.Context(), c.Response().BodyWriter())
 }
 
-func (h *Handler) GetAllRecipes(c *fiber.Ctx) error {
+func (h *Handler) GetPhoto(c *fiber.Ctx) error {
 	user, err := h.getCurrentUserFromSession(c)
 	if err != nil {
 		return err
 	}
 
-	recipes, err := h.repo.GetForUser(user.Id)
+	recipeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).SendString("Invalid recipe ID")
+	}
+
+	photoID, err := strconv.Atoi(c.Params("photoId"))
+	if err != nil {
+		return c.Status(400).SendString("Invalid photo ID")
+	}
+
+	recipe, err := h.repo.Get(recipeID)
+
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	} else if recipe == nil {
+		return c.Status(404).SendString("Recipe not found")
+	}
+
+	if user.Id != recipe.UserID {
+		return c.Status(403).SendString("Forbidden")
+	}
+
+	photo, err := h.repo.GetPhoto(photoID)
+
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	} else if photo == nil || photo.RecipeID != recipe.ID {
+		return c.Status(404).SendString("Photo not found")
+	}
+
+	c.Set("Content-Type", photo.ContentType)
+	c.Set("X-Content-Type-Options", "nosniff")
+	c.Set("Cache-Control", "private, max-age=86400")
+	return c.Send(photo.Data)
+}
+
+func (h *Handler) GetAllRecipes(c *fiber.Ctx) error {
+	user, err := h.getCurrentUserFromSession(c)
 	if err != nil {
 		return err
 	}
 
+	query := ParseLibraryQuery(c.Queries())
+
+	page, err := h.repo.GetLibrary(user.Id, query)
+	if errors.Is(err, ErrInvalidCursor) {
+		return c.Status(fiber.StatusBadRequest).SendString("That page of recipes couldn't be found")
+	} else if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
 	c.Set("Content-Type", "text/html")
-	component := GetAllRecipesView(recipes)
+
+	// Filter changes and infinite scroll only need the recipes, but history restores need the whole page
+	if c.Get("HX-Request") != "" && c.Get("HX-Boosted") == "" && c.Get("HX-History-Restore-Request") == "" {
+		component := RecipePage(page, query)
+		return component.Render(c.Context(), c.Response().BodyWriter())
+	}
+
+	drafts, err := h.repo.GetDraftsForUser(user.Id)
+	if err != nil {
+		return err
+	}
+
+	component := GetAllRecipesView(page, query, drafts)
 	return component.Render(c.Context(), c.Response().BodyWriter())
 }
 
@@ -113,18 +275,64 @@ func (h *Handler) SearchRecipes(c *fiber.Ctx) error {
 		return err
 	}
 
-	searchTerm := c.Query("term")
+	query := SearchQuery{Term: c.Query("term"), Dietary: ParseDietaryFilter(c.Queries())}
+
+	if c.Query("mode") == SearchByIngredients {
+		query.Ingredients = ParseIngredientList(query.Term)
+
+		if c.Query("staples") != "" {
+			if query.Staples, err = h.repo.GetPantryStaples(user.Id); err != nil {
+				return err
+			}
+		}
+	}
+
+	var results []*SearchResult
+
+	if len(query.Ingredients) == 0 && strings.TrimSpace(query.Term) != "" {
+		results, err = h.index.Search(user.Id, query.Term, query.Dietary)
+	} else {
+		results, err = h.repo.Search(user.Id, query)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := SearchResultsView(results)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
 
-	recipes, err := h.repo.Search(user.Id, searchTerm)
+func (h *Handler) GetPantry(c *fiber.Ctx) error {
+	user, err := h.getCurrentUserFromSession(c)
 	if err != nil {
 		return err
 	}
 
+	staples, err := h.repo.GetPantryStaples(user.Id)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
 	c.Set("Content-Type", "text/html")
-	component := SearchResultsView(recipes)
+	component := PantryView(staples)
 	return component.Render(c.Context(), c.Response().BodyWriter())
 }
 
+func (h *Handler) UpdatePantry(c *fiber.Ctx) error {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.repo.SetPantryStaples(user.Id, ParseIngredientList(c.FormValue("staples"))); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.Redirect("/")
+}
+
 func (h *Handler) CreateRecipe(c *fiber.Ctx) error {
 	user, err := h.getCurrentUserFromSession(c)
 	if err != nil {
@@ -136,26 +344,33 @@ func (h *Handler) CreateRecipe(c *fiber.Ctx) error {
 	}
 
 	var llmRecipe LLMRecipe
+	var draft Draft
 
-	// Check if an image was uploaded
-	imageFile, err := c.FormFile("recipeImage")
-	if err == nil && imageFile != nil {
-		// Process image
-		file, err := imageFile.Open()
-		if err != nil {
-			return c.Status(500).SendString("Failed to open image file")
-		}
-		defer file.Close()
+	form, err := c.MultipartForm()
+	if err != nil {
+		return c.Status(400).SendString("Invalid form data: " + err.Error())
+	}
 
-		imageData, err := io.ReadAll(file)
-		if err != nil {
-			return c.Status(500).SendString("Failed to read image file")
+	if pdfFiles := form.File["recipePDF"]; len(pdfFiles) > 0 {
+		return h.importPDF(c, user.Id, pdfFiles[0])
+	}
+
+	// Check if images were uploaded; recipes that span several pages come in as several images
+	if imageFiles := form.File["recipeImage"]; len(imageFiles) > 0 {
+		for _, imageFile := range imageFiles {
+			image, err := readSourceImage(imageFile)
+			if errors.Is(err, ErrNotAnImage) {
+				return c.Status(400).SendString(err.Error())
+			} else if err != nil {
+				return c.Status(500).SendString("Failed to read image file")
+			}
+
+			draft.SourceImages = append(draft.SourceImages, image)
 		}
 
-		base64Image := base64.StdEncoding.EncodeToString(imageData)
-		llmRecipe, err = h.llmService.FormatRecipeFromImage(base64Image, imageFile.Header.Get("Content-Type"))
+		llmRecipe, err = h.llmService.FormatRecipeFromImages(user.Id, draft.SourceImages)
 		if err != nil {
-			return c.Status(500).SendString(err.Error())
+			return h.llmError(c, err)
 		}
 	} else {
 		// Process text recipe
@@ -164,13 +379,35 @@ func (h *Handler) CreateRecipe(c *fiber.Ctx) error {
 			return c.Status(400).SendString("Please provide either a recipe text or paste an image")
 		}
 
-		llmRecipe, err = h.llmService.FormatRecipe(text)
+		llmRecipe, err = h.llmService.FormatRecipe(user.Id, text)
+		if err != nil {
+			return h.llmError(c, err)
+		}
+
+		draft.SourceText = text
+	}
+
+	return h.saveImport(c, user.Id, llmRecipe, draft)
+}
+
+// saveImport saves the extracted recipe, or a draft of it if the user asked to review it first.
+// The draft carries the sources the recipe was extracted from either way.
+func (h *Handler) saveImport(c *fiber.Ctx, userID int, llmRecipe LLMRecipe, draft Draft) error {
+	// Reviewing is optional; drafts live in the database so a refresh doesn't lose them
+	if c.FormValue("review") == "on" {
+		draft.UserID = userID
+		draft.Recipe.Data = llmRecipe
+
+		result, err := h.repo.CreateDraft(&draft)
 		if err != nil {
 			return c.Status(500).SendString(err.Error())
 		}
+
+		return c.Redirect(fmt.Sprintf("/drafts/%d", result.ID))
 	}
 
-	recipe := llmRecipe.ToRecipe(user.Id)
+	recipe := llmRecipe.ToRecipe(userID)
+	recipe.SourceID = draft.SourceID
 
 	result, err := h.repo.Create(&recipe)
 	if err != nil {
@@ -180,87 +417,1018 @@ func (h *Handler) CreateRecipe(c *fiber.Ctx) error {
 	return c.Redirect(fmt.Sprintf("/recipes/%d", result.ID))
 }
 
-func (h *Handler) UpdateRecipe(c *fiber.Ctx) error {
-	user, err := h.getCurrentUserFromSession(c)
+// importPDF keeps the PDF as the recipe's source. Single page PDFs are imported right away,
+// longer ones might hold several recipes so the user picks the pages first.
+func (h *Handler) importPDF(c *fiber.Ctx, userID int, pdfFile *multipart.FileHeader) error {
+	file, err := pdfFile.Open()
 	if err != nil {
-		if errors.Is(err, shared.ErrUnauthorized) {
-			return c.Status(401).Redirect("/login")
-		} else if errors.Is(err, shared.ErrUserNotFound) {
-			return c.Status(404).SendString("User not found")
-		}
+		return c.Status(500).SendString("Failed to open PDF file")
 	}
+	defer file.Close()
 
-	// Get the recipe ID from URL parameters
-	idParam := c.Params("id")
-	id, err := strconv.Atoi(idParam)
+	data, err := io.ReadAll(file)
 	if err != nil {
-		return c.Status(400).SendString("Invalid recipe ID")
+		return c.Status(500).SendString("Failed to read PDF file")
 	}
 
-	// Deserialize form data into FormRecipe struct
-	var formRecipe FormRecipe
-	if err := c.BodyParser(&formRecipe); err != nil {
-		return c.Status(400).SendString("Invalid form data: " + err.Error())
+	pages, err := ReadPDF(data)
+	if err != nil {
+		return c.Status(422).SendString(err.Error())
 	}
 
-	// Convert FormRecipe to Recipe model
-	recipe := formRecipe.ToRecipe(user.Id)
-	recipe.ID = id
+	source, err := h.repo.CreateSource(&Source{
+		UserID:      userID,
+		Filename:    pdfFile.Filename,
+		ContentType: "application/pdf",
+		Data:        data,
+	})
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if err := h.repo.SaveSourcePages(source.ID, pages); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if len(pages) > 1 {
+		return c.Redirect(fmt.Sprintf("/sources/%d/pages?review=%s", source.ID, c.FormValue("review")))
+	}
+
+	return h.extractPDFPages(c, userID, source, pages)
+}
 
-	// Update the recipe in the database (TODO: implement repo.Update)
-	_, err = h.repo.Update(&recipe)
+func (h *Handler) extractPDFPages(c *fiber.Ctx, userID int, source *Source, pages []PDFPage) error {
+	llmRecipe, err := h.llmService.FormatRecipeFromPDF(userID, pages)
 	if err != nil {
-		return c.Status(500).SendString("Failed to update recipe: " + err.Error())
+		return h.llmError(c, err)
 	}
 
-	// Check if this is an HTMX request
-	if c.Get("HX-Request") == "true" {
-		// For HTMX requests, send a redirect header to update the URL and content
-		c.Set("HX-Redirect", fmt.Sprintf("/recipes/%d", id))
-		return c.SendStatus(200)
-	} else {
-		// For regular requests, redirect back to the recipe page
-		return c.Redirect(fmt.Sprintf("/recipes/%d", id))
+	draft := Draft{SourceID: &source.ID}
+
+	var texts []string
+	for _, page := range pages {
+		if page.HasText() {
+			texts = append(texts, page.Text)
+		} else if page.Image != nil {
+			draft.SourceImages = append(draft.SourceImages, *page.Image)
+		}
 	}
+	draft.SourceText = strings.Join(texts, "\n\n")
+
+	return h.saveImport(c, userID, llmRecipe, draft)
 }
 
-func (h *Handler) DeleteRecipe(c *fiber.Ctx) error {
-	user, err := h.getCurrentUserFromSession(c)
+// getPDFPages returns the pages of a PDF source, reading the PDF only if they weren't saved
+// when it was imported.
+func (h *Handler) getPDFPages(source *Source) ([]PDFPage, error) {
+	pages, err := h.repo.GetSourcePages(source.ID)
+	if err != nil || len(pages) > 0 {
+		return pages, err
+	}
+
+	if pages, err = ReadPDF(source.Data); err != nil {
+		return nil, fiber.NewError(422, err.Error())
+	}
+
+	if err := h.repo.SaveSourcePages(source.ID, pages); err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+func (h *Handler) GetPDFPages(c *fiber.Ctx) error {
+	source, err := h.getSourceForCurrentUser(c)
 	if err != nil {
 		return err
 	}
 
-	idParam := c.Params("id")
-
-	if idParam == "" {
-		return c.Status(400).SendString("Missing recipe ID")
+	pages, err := h.getPDFPages(source)
+	if err != nil {
+		return err
 	}
 
-	id, err := strconv.Atoi(idParam)
+	c.Set("Content-Type", "text/html")
+	component := PDFPagesView(source, pages, c.Query("review") == "on")
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *Handler) GetPDFPageImage(c *fiber.Ctx) error {
+	source, err := h.getSourceForCurrentUser(c)
 	if err != nil {
-		return c.Status(400).SendString("Invalid recipe ID")
+		return err
 	}
 
-	recipe, err := h.repo.Get(id)
+	number, err := strconv.Atoi(c.Params("page"))
+	if err != nil {
+		return c.Status(400).SendString("Invalid page number")
+	}
 
+	page, err := h.repo.GetSourcePage(source.ID, number)
 	if err != nil {
 		return c.Status(500).SendString(err.Error())
-	} else if recipe == nil {
-		return c.Status(404).SendString("Recipe not found")
+	} else if page == nil || page.Image == nil {
+		return c.Status(404).SendString("Page not found")
 	}
 
-	if user.Id != recipe.UserID {
-		return c.Status(403).SendString("Forbidden")
+	c.Set("Content-Type", page.Image.ContentType)
+	c.Set("Cache-Control", "private, max-age=86400")
+	return c.Send(page.Image.Data)
+}
+
+func (h *Handler) ImportPDFPages(c *fiber.Ctx) error {
+	source, err := h.getSourceForCurrentUser(c)
+	if err != nil {
+		return err
 	}
 
-	_, err = h.repo.Delete(id)
+	pages, err := h.getPDFPages(source)
+	if err != nil {
+		return err
+	}
+
+	var numbers []string
+	for _, number := range c.Request().PostArgs().PeekMulti("page") {
+		numbers = append(numbers, string(number))
+	}
+
+	if len(numbers) == 0 {
+		return c.Status(400).SendString("Please pick at least one page")
+	}
+
+	return h.extractPDFPages(c, source.UserID, source, SelectPDFPages(pages, numbers))
+}
 
+func (h *Handler) GetSource(c *fiber.Ctx) error {
+	source, err := h.getSourceForCurrentUser(c)
 	if err != nil {
-		return c.Status(500).SendString(err.Error())
+		return err
 	}
 
-	c.Set("HX-Redirect", "/")
-	return c.SendStatus(204)
+	c.Set("Content-Type", source.ContentType)
+	c.Set("X-Content-Type-Options", "nosniff")
+	c.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", source.Filename))
+	return c.Send(source.Data)
+}
+
+// AddCookLogEntry records that the recipe was cooked, and shows the updated log.
+func (h *Handler) AddCookLogEntry(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	entry := &CookLogEntry{
+		RecipeID: recipe.ID,
+		CookedOn: today(),
+		Notes:    strings.TrimSpace(c.FormValue("notes")),
+		Tweaks:   strings.TrimSpace(c.FormValue("tweaks")),
+	}
+
+	if cookedOn := c.FormValue("cooked_on"); cookedOn != "" {
+		if entry.CookedOn, err = time.Parse(time.DateOnly, cookedOn); err != nil {
+			return c.Status(400).SendString("Invalid date")
+		}
+	}
+
+	if entry.Rating, err = strconv.Atoi(c.FormValue("rating", "0")); err != nil || entry.Rating < 0 || entry.Rating > MAX_RATING {
+		return c.Status(400).SendString("Invalid rating")
+	}
+
+	// The photo is optional, so a missing file isn't an error
+	if photoFile, err := c.FormFile("photo"); err == nil {
+		file, err := photoFile.Open()
+		if err != nil {
+			return c.Status(500).SendString(err.Error())
+		}
+		defer file.Close()
+
+		if entry.Photo, err = io.ReadAll(file); err != nil {
+			return c.Status(500).SendString(err.Error())
+		}
+
+		entry.PhotoContentType = http.DetectContentType(entry.Photo)
+		if !strings.HasPrefix(entry.PhotoContentType, "image/") {
+			return c.Status(400).SendString("The photo has to be an image")
+		}
+	}
+
+	if err := h.repo.AddToCookLog(entry); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return h.renderCookLog(c, recipe)
+}
+
+func (h *Handler) DeleteCookLogEntry(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	entry, err := h.getCookLogEntry(c, recipe)
+	if err != nil {
+		return err
+	}
+
+	if err := h.repo.DeleteFromCookLog(entry); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return h.renderCookLog(c, recipe)
+}
+
+func (h *Handler) GetCookLogPhoto(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	entry, err := h.getCookLogEntry(c, recipe)
+	if err != nil {
+		return err
+	}
+
+	if len(entry.Photo) == 0 {
+		return c.Status(404).SendString("Photo not found")
+	}
+
+	c.Set("Content-Type", entry.PhotoContentType)
+	c.Set("Cache-Control", "private, max-age=86400")
+	return c.Send(entry.Photo)
+}
+
+func (h *Handler) renderCookLog(c *fiber.Ctx, recipe *Recipe) error {
+	entries, err := h.repo.GetCookLog(recipe.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := CookLogSection(recipe.ID, entries)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+// LookUpNutrition asks the LLM about the ingredients the nutrition data doesn't know by name,
+// and shows the estimate again with its answers.
+func (h *Handler) LookUpNutrition(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	recipe, err = h.repo.Expand(recipe)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	foods, err := h.repo.GetIngredientFoods(recipe.UserID, ingredientNameKeys(recipe))
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if names := unmatchedIngredientNames(recipe, foods); len(names) > 0 {
+		matched, err := h.llmService.MatchFoods(recipe.UserID, names)
+		if err != nil {
+			return h.llmError(c, err)
+		}
+
+		if err := h.repo.SaveIngredientFoods(recipe.UserID, matched); err != nil {
+			return c.Status(500).SendString(err.Error())
+		}
+
+		for name, food := range matched {
+			foods[name] = food
+		}
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := NutritionPanel(recipe.ID, EstimateNutrition(recipe, foods))
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+// TransformRecipe asks the LLM for a version of the recipe changed the way the user asked.
+// The result is saved as a draft linked to the recipe, so the changes can be reviewed before
+// it's saved as a recipe of its own.
+func (h *Handler) TransformRecipe(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	staples, err := h.repo.GetPantryStaples(recipe.UserID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	request, ok := NewTransformRequest(c.FormValue("preset"), c.FormValue("instructions"), staples)
+	if !ok {
+		return c.Status(400).SendString("Pick a change, or describe the one you want")
+	}
+
+	expanded, err := h.repo.Expand(recipe)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	llmRecipe, err := h.llmService.TransformRecipe(recipe.UserID, expanded, request.Instructions)
+	if err != nil {
+		return h.llmError(c, err)
+	}
+
+	draft, err := h.repo.CreateDraft(&Draft{
+		UserID:    recipe.UserID,
+		Recipe:    database.JSONObject[LLMRecipe]{Data: llmRecipe},
+		ParentID:  &recipe.ID,
+		Transform: request.Summary,
+	})
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("HX-Redirect", fmt.Sprintf("/drafts/%d", draft.ID))
+	return c.SendStatus(200)
+}
+
+// UpdateDietaryLabels saves the labels the user set themselves. Labels left as detected
+// aren't saved, so they follow the ingredients when the recipe changes.
+func (h *Handler) UpdateDietaryLabels(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	overrides := map[string]bool{}
+	for _, label := range append(slices.Clone(Allergens), Diets...) {
+		switch c.FormValue(label.Name) {
+		case "yes":
+			overrides[label.Name] = true
+		case "no":
+			overrides[label.Name] = false
+		}
+	}
+
+	if err := h.repo.SetDietaryOverrides(recipe.ID, overrides); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	recipe.DietaryOverrides.Data = overrides
+
+	return h.renderDietaryLabels(c, recipe, nil)
+}
+
+// CheckDietaryLabels asks the LLM about the ingredients the dietary rules don't recognize,
+// and detects the recipe's labels again with its answers.
+func (h *Handler) CheckDietaryLabels(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	expanded, err := h.repo.Expand(recipe)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	traits, err := h.repo.GetIngredientTraits(recipe.UserID, ingredientTraitKeys(expanded))
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if names := uncheckedIngredientNames(expanded, traits); len(names) > 0 {
+		found, err := h.llmService.ClassifyIngredients(recipe.UserID, names)
+		if err != nil {
+			return h.llmError(c, err)
+		}
+
+		if err := h.repo.SaveIngredientTraits(recipe.UserID, found); err != nil {
+			return c.Status(500).SendString(err.Error())
+		}
+
+		var keys []string
+		for name, nameTraits := range found {
+			traits[name] = nameTraits
+
+			if len(nameTraits) > 0 {
+				keys = append(keys, name)
+			}
+		}
+
+		// The user's other recipes with these ingredients are labeled by the new traits too
+		if len(keys) > 0 {
+			if _, err := h.repo.RedetectDietary(recipe.UserID, keys); err != nil {
+				return c.Status(500).SendString(err.Error())
+			}
+		}
+	}
+
+	recipe.DietaryDetected = DetectDietary(expanded, traits)
+
+	if err := h.repo.SetDietaryDetected(recipe.ID, recipe.DietaryDetected); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return h.renderDietaryLabels(c, recipe, traits)
+}
+
+// renderDietaryLabels shows the recipe's labels with their editor open. traits is what the LLM
+// found in the recipe's ingredients, if it's been loaded already.
+func (h *Handler) renderDietaryLabels(c *fiber.Ctx, recipe *Recipe, traits map[string][]string) error {
+	expanded, err := h.repo.Expand(recipe)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if traits == nil {
+		if traits, err = h.repo.GetIngredientTraits(recipe.UserID, ingredientTraitKeys(expanded)); err != nil {
+			return c.Status(500).SendString(err.Error())
+		}
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := DietaryLabels(recipe, len(uncheckedIngredientNames(expanded, traits)) > 0, true)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *Handler) FavoriteRecipe(c *fiber.Ctx) error {
+	return h.setFavorite(c, true)
+}
+
+func (h *Handler) UnfavoriteRecipe(c *fiber.Ctx) error {
+	return h.setFavorite(c, false)
+}
+
+func (h *Handler) setFavorite(c *fiber.Ctx, favorite bool) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.repo.SetFavorite(recipe.ID, favorite); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	recipe.Favorite = favorite
+
+	c.Set("Content-Type", "text/html")
+	component := FavoriteButton(recipe)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+// UpdateRecipeCollections puts the recipe in exactly the collections that are checked.
+func (h *Handler) UpdateRecipeCollections(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var collectionIDs []int
+	for _, value := range c.Context().PostArgs().PeekMulti("collection") {
+		id, err := strconv.Atoi(string(value))
+		if err != nil {
+			return c.Status(400).SendString("Invalid collection ID")
+		}
+
+		collectionIDs = append(collectionIDs, id)
+	}
+
+	if err := h.repo.SetRecipeCollections(recipe.UserID, recipe.ID, collectionIDs); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return h.renderRecipeCollections(c, recipe)
+}
+
+// AddRecipeToNewCollection starts a collection with the recipe in it.
+func (h *Handler) AddRecipeToNewCollection(c *fiber.Ctx) error {
+	recipe, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return c.Status(400).SendString("Give the collection a name")
+	}
+
+	collection, err := h.repo.CreateCollection(&Collection{UserID: recipe.UserID, Name: name})
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if err := h.repo.AddToCollection(collection.ID, recipe.ID); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return h.renderRecipeCollections(c, recipe)
+}
+
+func (h *Handler) renderRecipeCollections(c *fiber.Ctx, recipe *Recipe) error {
+	collections, err := h.repo.GetRecipeCollections(recipe.UserID, recipe.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := RecipeCollectionsPicker(recipe.ID, collections, true)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *Handler) GetCollections(c *fiber.Ctx) error {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return err
+	}
+
+	collections, err := h.repo.GetCollections(user.Id)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := CollectionsView(collections)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *Handler) CreateCollection(c *fiber.Ctx) error {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return c.Status(400).SendString("Give the collection a name")
+	}
+
+	collection, err := h.repo.CreateCollection(&Collection{UserID: user.Id, Name: name})
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.Redirect(fmt.Sprintf("/collections/%d", collection.ID))
+}
+
+func (h *Handler) GetCollection(c *fiber.Ctx) error {
+	collection, err := h.getCollectionForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	recipes, err := h.repo.GetCollectionRecipes(collection.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := CollectionView(collection, recipes)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *Handler) RenameCollection(c *fiber.Ctx) error {
+	collection, err := h.getCollectionForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	collection.Name = strings.TrimSpace(c.FormValue("name"))
+	if collection.Name == "" {
+		return c.Status(400).SendString("Give the collection a name")
+	}
+
+	if err := h.repo.RenameCollection(collection); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := CollectionHeading(collection)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *Handler) DeleteCollection(c *fiber.Ctx) error {
+	collection, err := h.getCollectionForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.repo.DeleteCollection(collection.ID); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("HX-Redirect", "/collections")
+	return c.SendStatus(204)
+}
+
+// ReorderCollection saves the order the recipes were dragged into, given as "recipe" form values.
+func (h *Handler) ReorderCollection(c *fiber.Ctx) error {
+	collection, err := h.getCollectionForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var recipeIDs []int
+	for _, value := range c.Context().PostArgs().PeekMulti("recipe") {
+		id, err := strconv.Atoi(string(value))
+		if err != nil {
+			return c.Status(400).SendString("Invalid recipe ID")
+		}
+
+		recipeIDs = append(recipeIDs, id)
+	}
+
+	if err := h.repo.ReorderCollection(collection.ID, recipeIDs); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return h.renderCollectionRecipes(c, collection)
+}
+
+func (h *Handler) RemoveFromCollection(c *fiber.Ctx) error {
+	collection, err := h.getCollectionForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	recipeID, err := strconv.Atoi(c.Params("recipeId"))
+	if err != nil {
+		return c.Status(400).SendString("Invalid recipe ID")
+	}
+
+	if err := h.repo.RemoveFromCollection(collection.ID, recipeID); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return h.renderCollectionRecipes(c, collection)
+}
+
+func (h *Handler) renderCollectionRecipes(c *fiber.Ctx, collection *Collection) error {
+	recipes, err := h.repo.GetCollectionRecipes(collection.ID)
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := CollectionRecipeList(collection.ID, recipes)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+// getCollectionForCurrentUser loads the collection named in the URL, responding with the
+// appropriate error status if it can't be found or belongs to someone else.
+func (h *Handler) getCollectionForCurrentUser(c *fiber.Ctx) (*Collection, error) {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid collection ID")
+	}
+
+	collection, err := h.repo.GetCollection(id)
+
+	if err != nil {
+		return nil, err
+	} else if collection == nil {
+		return nil, fiber.NewError(404, "Collection not found")
+	}
+
+	if user.Id != collection.UserID {
+		return nil, fiber.NewError(403, "Forbidden")
+	}
+
+	return collection, nil
+}
+
+// getRecipeForCurrentUser loads the recipe named in the URL, responding with the
+// appropriate error status if it can't be found or belongs to someone else.
+func (h *Handler) getRecipeForCurrentUser(c *fiber.Ctx) (*Recipe, error) {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid recipe ID")
+	}
+
+	recipe, err := h.repo.Get(id)
+
+	if err != nil {
+		return nil, err
+	} else if recipe == nil {
+		return nil, fiber.NewError(404, "Recipe not found")
+	}
+
+	if user.Id != recipe.UserID {
+		return nil, fiber.NewError(403, "Forbidden")
+	}
+
+	return recipe, nil
+}
+
+func (h *Handler) getCookLogEntry(c *fiber.Ctx, recipe *Recipe) (*CookLogEntry, error) {
+	id, err := strconv.Atoi(c.Params("entryId"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid log entry ID")
+	}
+
+	entry, err := h.repo.GetCookLogEntry(id)
+
+	if err != nil {
+		return nil, err
+	} else if entry == nil || entry.RecipeID != recipe.ID {
+		return nil, fiber.NewError(404, "Log entry not found")
+	}
+
+	return entry, nil
+}
+
+func (h *Handler) getSourceForCurrentUser(c *fiber.Ctx) (*Source, error) {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid source ID")
+	}
+
+	source, err := h.repo.GetSource(id)
+
+	if err != nil {
+		return nil, err
+	} else if source == nil {
+		return nil, fiber.NewError(404, "Source not found")
+	}
+
+	if user.Id != source.UserID {
+		return nil, fiber.NewError(403, "Forbidden")
+	}
+
+	return source, nil
+}
+
+func (h *Handler) GetDraft(c *fiber.Ctx) error {
+	draft, err := h.getDraftForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Transformed recipes are checked against the recipe they were adapted from instead
+	if draft.ParentID != nil {
+		parent, err := h.repo.Get(*draft.ParentID)
+		if err != nil {
+			return c.Status(500).SendString(err.Error())
+		}
+
+		if parent != nil && parent.UserID == draft.UserID {
+			if parent, err = h.repo.Expand(parent); err != nil {
+				return c.Status(500).SendString(err.Error())
+			}
+
+			c.Set("Content-Type", "text/html")
+			component := DraftView(draft, map[string]string{}, CompareRecipes(parent, draft.Preview()))
+			return component.Render(c.Context(), c.Response().BodyWriter())
+		}
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := DraftView(draft, LowConfidenceFields(draft.Recipe.Data, draft.SourceText), nil)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *Handler) GetDraftSource(c *fiber.Ctx) error {
+	draft, err := h.getDraftForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil || index < 0 || index >= len(draft.SourceImages) {
+		return c.Status(404).SendString("Image not found")
+	}
+
+	// Drafts saved before uploads were checked kept whatever type the browser claimed, so
+	// only ever serve what the bytes say is an image
+	image := draft.SourceImages[index]
+	contentType := http.DetectContentType(image.Data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return c.Status(404).SendString("Image not found")
+	}
+
+	c.Set("Content-Type", contentType)
+	c.Set("X-Content-Type-Options", "nosniff")
+	return c.Send(image.Data)
+}
+
+func (h *Handler) ConfirmDraft(c *fiber.Ctx) error {
+	draft, err := h.getDraftForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var formRecipe FormRecipe
+	if err := c.BodyParser(&formRecipe); err != nil {
+		return c.Status(400).SendString("Invalid form data: " + err.Error())
+	}
+
+	recipe := formRecipe.ToRecipe(draft.UserID)
+	recipe.SourceID = draft.SourceID
+	recipe.ParentID = draft.ParentID
+
+	result, err := h.repo.Create(&recipe)
+	if errors.Is(err, ErrUnknownComponent) {
+		return c.Status(400).SendString(err.Error())
+	} else if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	if _, err := h.repo.DeleteDraft(draft.ID); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("HX-Redirect", fmt.Sprintf("/recipes/%d", result.ID))
+	return c.SendStatus(200)
+}
+
+func (h *Handler) DeleteDraft(c *fiber.Ctx) error {
+	draft, err := h.getDraftForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.repo.DeleteDraft(draft.ID); err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("HX-Redirect", "/")
+	return c.SendStatus(204)
+}
+
+// getDraftForCurrentUser loads the draft named in the URL, responding with the
+// appropriate error status if it can't be found or belongs to someone else.
+func (h *Handler) getDraftForCurrentUser(c *fiber.Ctx) (*Draft, error) {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid draft ID")
+	}
+
+	draft, err := h.repo.GetDraft(id)
+
+	if err != nil {
+		return nil, err
+	} else if draft == nil {
+		return nil, fiber.NewError(404, "Draft not found")
+	}
+
+	if user.Id != draft.UserID {
+		return nil, fiber.NewError(403, "Forbidden")
+	}
+
+	return draft, nil
+}
+
+func (h *Handler) UpdateRecipe(c *fiber.Ctx) error {
+	// Only the recipe's owner may change it
+	existing, err := h.getRecipeForCurrentUser(c)
+	if err != nil {
+		return err
+	}
+
+	id := existing.ID
+
+	// Deserialize form data into FormRecipe struct
+	var formRecipe FormRecipe
+	if err := c.BodyParser(&formRecipe); err != nil {
+		return c.Status(400).SendString("Invalid form data: " + err.Error())
+	}
+
+	// Convert FormRecipe to Recipe model
+	recipe := formRecipe.ToRecipe(existing.UserID)
+	recipe.ID = id
+
+	updated, err := h.repo.Update(&recipe)
+	if errors.Is(err, ErrUnknownComponent) {
+		return c.Status(400).SendString(err.Error())
+	} else if err != nil {
+		return c.Status(500).SendString("Failed to update recipe: " + err.Error())
+	} else if updated == nil {
+		return c.Status(404).SendString("Recipe not found")
+	}
+
+	// Check if this is an HTMX request
+	if c.Get("HX-Request") == "true" {
+		// For HTMX requests, send a redirect header to update the URL and content
+		c.Set("HX-Redirect", fmt.Sprintf("/recipes/%d", id))
+		return c.SendStatus(200)
+	} else {
+		// For regular requests, redirect back to the recipe page
+		return c.Redirect(fmt.Sprintf("/recipes/%d", id))
+	}
+}
+
+func (h *Handler) DeleteRecipe(c *fiber.Ctx) error {
+	user, err := h.getCurrentUserFromSession(c)
+	if err != nil {
+		return err
+	}
+
+	idParam := c.Params("id")
+
+	if idParam == "" {
+		return c.Status(400).SendString("Missing recipe ID")
+	}
+
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return c.Status(400).SendString("Invalid recipe ID")
+	}
+
+	recipe, err := h.repo.Get(id)
+
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	} else if recipe == nil {
+		return c.Status(404).SendString("Recipe not found")
+	}
+
+	if user.Id != recipe.UserID {
+		return c.Status(403).SendString("Forbidden")
+	}
+
+	_, err = h.repo.Delete(id)
+
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("HX-Redirect", "/")
+	return c.SendStatus(204)
+}
+
+func (h *Handler) GetCacheStats(c *fiber.Ctx) error {
+	stats, err := h.llmService.CacheStats()
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	return c.JSON(stats)
+}
+
+func (h *Handler) llmError(c *fiber.Ctx, err error) error {
+	var quotaErr *usage.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		// Like rate limits, htmx shows these in the flash area instead of whatever was targeted
+		if c.Get("HX-Request") == "true" {
+			c.Set("HX-Retarget", "#flash")
+			c.Set("HX-Reswap", "innerHTML")
+		}
+
+		return c.Status(429).SendString(quotaErr.Error())
+	}
+
+	if errors.Is(err, ErrEmptyPDF) {
+		return c.Status(422).SendString(err.Error())
+	}
+
+	var validationErr *RecipeValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(422).SendString(validationErr.Error())
+	}
+
+	return c.Status(500).SendString(err.Error())
+}
+
+var ErrNotAnImage = errors.New("that file isn't an image")
+
+// readSourceImage reads an uploaded image, with its type worked out from its contents rather
+// than what the browser said.
+func readSourceImage(imageFile *multipart.FileHeader) (SourceImage, error) {
+	file, err := imageFile.Open()
+	if err != nil {
+		return SourceImage{}, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return SourceImage{}, err
+	}
+
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return SourceImage{}, ErrNotAnImage
+	}
+
+	return SourceImage{ContentType: contentType, Data: data}, nil
 }
 
 func (h *Handler) getCurrentUserFromSession(c *fiber.Ctx) (*shared.UserInfo, error) {
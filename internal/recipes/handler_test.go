@@ -0,0 +1,64 @@
+package recipes
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"sourdough/internal/shared"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUpdateRecipeOnlyChangesYourOwnRecipes(t *testing.T) {
+	repo := newTestRepository(t)
+	handler := NewHandler(repo, nil, nil)
+
+	recipe, err := repo.Create(&Recipe{UserID: 1, Title: "Toast", Ingredients: []string{"bread"}, Directions: []string{"Toast it."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int
+		status int
+		title  string
+	}{
+		{"another user", 2, 403, "Toast"},
+		{"the owner", 1, 302, "Better toast"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Patch("/recipes/:id", func(c *fiber.Ctx) error {
+				c.Locals("user", &shared.UserInfo{Id: tt.userID})
+				return c.Next()
+			}, handler.UpdateRecipe)
+
+			form := url.Values{"title": {"Better toast"}, "ingredients": {"bread"}, "directions": {"Toast it well."}}
+			req := httptest.NewRequest("PATCH", "/recipes/"+strconv.Itoa(recipe.ID), strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			saved, err := repo.Get(recipe.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if saved.Title != tt.title || saved.UserID != 1 {
+				t.Errorf("got %q owned by %d", saved.Title, saved.UserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package recipes
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matches a leading quantity: whole numbers, decimals, fractions, mixed numbers like "1 1/2",
+// unicode fractions and ranges like "2-3" or "2 to 3". Plain fractions are tried first, or
+// "3/4" would stop at the "3".
+var ingredientQuantityPattern = regexp.MustCompile(`^(?:\d+/\d+|\d+(?:[.,]\d+)?(?:\s+\d+/\d+|\s*[½⅓⅔¼¾⅛⅜⅝⅞])?|[½⅓⅔¼¾⅛⅜⅝⅞])(?:\s*(?:-|–|to)\s*(?:\d+/\d+|\d+(?:[.,]\d+)?(?:\s+\d+/\d+)?))?`)
+
+// The units people write in ingredient lists, singular and plural, lower case and without periods
+var ingredientUnits = map[string]bool{
+	"bunch": true, "bunches": true, "c": true, "can": true, "cans": true, "clove": true, "cloves": true,
+	"cup": true, "cups": true, "dash": true, "dashes": true, "g": true, "gallon": true, "gallons": true,
+	"gram": true, "grams": true, "handful": true, "handfuls": true, "kg": true, "kilogram": true, "kilograms": true,
+	"l": true, "lb": true, "lbs": true, "liter": true, "liters": true, "litre": true, "litres": true,
+	"ml": true, "milliliter": true, "milliliters": true, "oz": true, "ounce": true, "ounces": true,
+	"package": true, "packages": true, "pinch": true, "pinches": true, "pint": true, "pints": true,
+	"pkg": true, "pound": true, "pounds": true, "pt": true, "qt": true, "quart": true, "quarts": true,
+	"slice": true, "slices": true, "sprig": true, "sprigs": true, "stick": true, "sticks": true,
+	"t": true, "tablespoon": true, "tablespoons": true, "tbsp": true, "tbs": true, "teaspoon": true,
+	"teaspoons": true, "tsp": true,
+}
+
+// Ingredient is one line of an ingredient list split into its parts, e.g.
+// "1 1/2 cups flour, sifted" is 1 1/2 (quantity) cups (unit) flour (name), sifted (note).
+type Ingredient struct {
+	Quantity string
+	Unit     string
+	Name     string
+	Note     string
+}
+
+// ParseIngredient splits an ingredient line into its parts. Anything it can't make sense of
+// ends up in the name, so no part of the line is lost.
+func ParseIngredient(text string) Ingredient {
+	var ingredient Ingredient
+	rest := strings.TrimSpace(text)
+
+	if quantity := ingredientQuantityPattern.FindString(rest); quantity != "" {
+		ingredient.Quantity = strings.TrimSpace(quantity)
+		rest = strings.TrimSpace(rest[len(quantity):])
+
+		if word, after, _ := strings.Cut(rest, " "); ingredientUnits[strings.TrimSuffix(strings.ToLower(word), ".")] {
+			ingredient.Unit = word
+			rest = strings.TrimSpace(after)
+		}
+
+		if after, ok := strings.CutPrefix(rest, "of "); ok {
+			rest = strings.TrimSpace(after)
+		}
+	}
+
+	if name, note, ok := strings.Cut(rest, ","); ok {
+		ingredient.Name = strings.TrimSpace(name)
+		ingredient.Note = strings.TrimSpace(note)
+	} else {
+		ingredient.Name = rest
+	}
+
+	return ingredient
+}
+
+var unicodeFractions = map[rune]float64{
+	'½': 1.0 / 2, '⅓': 1.0 / 3, '⅔': 2.0 / 3, '¼': 1.0 / 4, '¾': 3.0 / 4,
+	'⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+var quantityRangePattern = regexp.MustCompile(`\s*(?:-|–|\bto\b)\s*`)
+
+// Amount is the quantity as a number, e.g. 1.5 for "1 1/2" or "1½". Ranges like "2-3"
+// use their midpoint. It's false when there's no quantity or it can't be read.
+func (i Ingredient) Amount() (float64, bool) {
+	if i.Quantity == "" {
+		return 0, false
+	}
+
+	parts := quantityRangePattern.Split(i.Quantity, 2)
+	total := 0.0
+
+	for _, part := range parts {
+		amount, ok := parseAmount(part)
+		if !ok {
+			return 0, false
+		}
+
+		total += amount
+	}
+
+	return total / float64(len(parts)), true
+}
+
+// parseAmount reads one number in any of the forms ingredientQuantityPattern matches.
+func parseAmount(text string) (float64, bool) {
+	total := 0.0
+
+	for _, field := range strings.Fields(text) {
+		// A unicode fraction can follow a whole number without a space, as in "1½"
+		if runes := []rune(field); unicodeFractions[runes[len(runes)-1]] > 0 {
+			total += unicodeFractions[runes[len(runes)-1]]
+			field = string(runes[:len(runes)-1])
+
+			if field == "" {
+				continue
+			}
+		}
+
+		if numerator, denominator, ok := strings.Cut(field, "/"); ok {
+			n, err1 := strconv.ParseFloat(numerator, 64)
+			d, err2 := strconv.ParseFloat(denominator, 64)
+			if err1 != nil || err2 != nil || d == 0 {
+				return 0, false
+			}
+
+			total += n / d
+			continue
+		}
+
+		number, err := strconv.ParseFloat(strings.Replace(field, ",", ".", 1), 64)
+		if err != nil {
+			return 0, false
+		}
+
+		total += number
+	}
+
+	return total, true
+}
+
+func (i Ingredient) String() string {
+	var parts []string
+
+	for _, part := range []string{i.Quantity, i.Unit, i.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	text := strings.Join(parts, " ")
+
+	if i.Note != "" {
+		text += ", " + i.Note
+	}
+
+	return text
+}
@@ -0,0 +1,246 @@
+package recipes
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Ingredients nearly every kitchen has, which shouldn't count against a recipe when searching by
+// what's on hand. Users can replace these with their own list.
+var DEFAULT_PANTRY_STAPLES = []string{"salt", "pepper", "black pepper", "oil", "olive oil", "vegetable oil", "water", "sugar", "flour"}
+
+const (
+	SearchByText        = "text"
+	SearchByIngredients = "ingredients"
+)
+
+// SearchQuery is either a search of titles, or the ingredients the user has on hand.
+// Recipes' ingredients matching a staple are treated as on hand too. Either kind of search
+// only finds recipes that pass the dietary filter.
+type SearchQuery struct {
+	Term        string
+	Ingredients []string
+	Staples     []string
+	Dietary     DietaryFilter
+}
+
+// SearchResult is a recipe that matched a search. For ingredient searches it also says how
+// much of the recipe is covered by what's on hand and what's missing.
+type SearchResult struct {
+	Recipe   *Recipe
+	Required int
+	Covered  int
+	Missing  []string
+}
+
+func (r *SearchResult) ByIngredients() bool {
+	return r.Required > 0
+}
+
+// ParseIngredientList splits what the user typed, e.g. "eggs, milk, spinach", into ingredients.
+func ParseIngredientList(text string) []string {
+	var ingredients []string
+
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '\n' || r == ';' }) {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+			ingredients = append(ingredients, part)
+		}
+	}
+
+	return ingredients
+}
+
+// rankByIngredients keeps the recipes that use at least one of the ingredients on hand, with
+// the ones missing the fewest ingredients first.
+func rankByIngredients(recipes []*Recipe, onHand, staples []string) []*SearchResult {
+	var results []*SearchResult
+
+	for _, recipe := range recipes {
+		result := &SearchResult{Recipe: recipe, Missing: []string{}}
+
+		for _, line := range recipe.Ingredients {
+			name := strings.ToLower(ParseIngredient(line).Name)
+
+			if strings.TrimSpace(name) == "" || isStaple(name, staples) {
+				continue
+			}
+
+			result.Required++
+
+			if matchesAny(name, onHand) {
+				result.Covered++
+			} else {
+				result.Missing = append(result.Missing, ParseIngredient(line).Name)
+			}
+		}
+
+		if result.Covered > 0 {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+
+		if len(a.Missing) != len(b.Missing) {
+			return len(a.Missing) < len(b.Missing)
+		}
+
+		return a.Covered*b.Required > b.Covered*a.Required
+	})
+
+	return results
+}
+
+func matchesAny(name string, items []string) bool {
+	for _, item := range items {
+		if ingredientMatches(name, item) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ingredientMatches reports whether every word of item is in the ingredient's name, ignoring
+// plurals, so "egg" matches "2 large eggs" and "olive oil" matches "extra virgin olive oil".
+func ingredientMatches(name, item string) bool {
+	nameWords := map[string]bool{}
+	for _, word := range ingredientWords(name) {
+		nameWords[word] = true
+	}
+
+	itemWords := ingredientWords(item)
+	if len(itemWords) == 0 {
+		return false
+	}
+
+	for _, word := range itemWords {
+		if !nameWords[word] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Words that describe an ingredient without changing what it is, so "kosher salt" and "freshly
+// ground black pepper" are still salt and pepper. Most past participles and adverbs ("softened",
+// "finely") are too, and are recognized by their endings.
+var ingredientModifiers = map[string]bool{
+	"all": true, "black": true, "boiling": true, "caster": true, "coarse": true, "cold": true,
+	"extra": true, "fine": true, "flaky": true, "fresh": true, "good": true, "ground": true, "hot": true,
+	"kosher": true, "large": true, "light": true, "lukewarm": true, "medium": true, "neutral": true,
+	"plain": true, "purpose": true, "quality": true, "sea": true, "small": true, "table": true,
+	"tap": true, "virgin": true, "warm": true, "white": true,
+}
+
+// isStaple reports whether an ingredient is one of the staples. Unlike ingredientMatches, the
+// whole staple has to be what the ingredient is, not just some of its words, so "coconut
+// water", "water chestnuts" and "red bell peppers" aren't staples even though water and pepper
+// are. A line like "salt and pepper" is a staple when everything in it is, and "butter or
+// oil" when either is.
+func isStaple(name string, staples []string) bool {
+	words := ingredientWords(name)
+
+	// What it's for or how much doesn't change what it is, e.g. "oil for frying" or "salt to taste"
+	for i, word := range words {
+		if word == "for" || word == "to" {
+			words = words[:i]
+			break
+		}
+	}
+
+	if len(words) == 0 {
+		return false
+	}
+
+	for _, part := range splitWords(words, "and") {
+		staple := false
+
+		for _, alternative := range splitWords(part, "or") {
+			if staple = isStapleNoun(alternative, staples); staple {
+				break
+			}
+		}
+
+		if !staple {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isStapleNoun reports whether words end with one of the staples, with only modifiers before it.
+func isStapleNoun(words []string, staples []string) bool {
+	for _, staple := range staples {
+		stapleWords := ingredientWords(staple)
+		if len(stapleWords) == 0 || len(stapleWords) > len(words) {
+			continue
+		}
+
+		head := len(words) - len(stapleWords)
+		if !slices.Equal(words[head:], stapleWords) {
+			continue
+		}
+
+		if !slices.ContainsFunc(words[:head], func(word string) bool { return !isModifier(word) }) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isModifier(word string) bool {
+	return ingredientModifiers[word] ||
+		len(word) > 4 && (strings.HasSuffix(word, "ed") || strings.HasSuffix(word, "ly")) ||
+		len(word) > 5 && strings.HasSuffix(word, "ing")
+}
+
+// splitWords splits words around each separator, dropping empty parts.
+func splitWords(words []string, separator string) [][]string {
+	var parts [][]string
+	start := 0
+
+	for i := 0; i <= len(words); i++ {
+		if i < len(words) && words[i] != separator {
+			continue
+		}
+
+		if i > start {
+			parts = append(parts, words[start:i])
+		}
+		start = i + 1
+	}
+
+	return parts
+}
+
+func ingredientWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+
+	for i, word := range words {
+		words[i] = singular(word)
+	}
+
+	return words
+}
+
+// singular undoes the common English plurals, which is enough to match ingredient names.
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "oes") || strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 3:
+		return strings.TrimSuffix(word, "s")
+	default:
+		return word
+	}
+}
@@ -0,0 +1,81 @@
+package recipes
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIsStaple(t *testing.T) {
+	tests := []struct {
+		name   string
+		staple bool
+	}{
+		{"salt", true},
+		{"kosher salt", true},
+		{"freshly ground black pepper", true},
+		{"extra virgin olive oil", true},
+		{"all-purpose flour", true},
+		{"granulated sugar", true},
+		{"warm water", true},
+		{"salt and pepper", true},
+		{"salt to taste", true},
+		{"oil for frying", true},
+		{"butter or oil", true},
+		{"red bell peppers", false},
+		{"water chestnuts", false},
+		{"sugar snap peas", false},
+		{"oil-cured olives", false},
+		{"coconut water", false},
+		{"cayenne pepper", false},
+		{"salt and butter", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if staple := isStaple(test.name, DEFAULT_PANTRY_STAPLES); staple != test.staple {
+			t.Errorf("%q: got %v, want %v", test.name, staple, test.staple)
+		}
+	}
+}
+
+func TestRankByIngredientsSkipsOnlyStaples(t *testing.T) {
+	recipe := &Recipe{Ingredients: []string{"2 eggs", "1 cup water chestnuts", "salt", "1 tbsp olive oil"}}
+
+	results := rankByIngredients([]*Recipe{recipe}, []string{"eggs"}, DEFAULT_PANTRY_STAPLES)
+	if len(results) != 1 {
+		t.Fatalf("got %d results", len(results))
+	}
+
+	if results[0].Required != 2 || !slices.Equal(results[0].Missing, []string{"water chestnuts"}) {
+		t.Errorf("required %d, missing %q", results[0].Required, results[0].Missing)
+	}
+}
+
+func TestPantryStaplesCanBeEmptied(t *testing.T) {
+	repo := newTestRepository(t)
+
+	staples, err := repo.GetPantryStaples(1)
+	if err != nil || !slices.Equal(staples, DEFAULT_PANTRY_STAPLES) {
+		t.Fatalf("before saving: got %q, %v", staples, err)
+	}
+
+	if err := repo.SetPantryStaples(1, []string{"salt", "butter"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if staples, _ := repo.GetPantryStaples(1); !slices.Equal(staples, []string{"butter", "salt"}) {
+		t.Errorf("after saving: got %q", staples)
+	}
+
+	if err := repo.SetPantryStaples(1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if staples, _ := repo.GetPantryStaples(1); len(staples) != 0 {
+		t.Errorf("after emptying: got %q", staples)
+	}
+
+	if staples, _ := repo.GetPantryStaples(2); !slices.Equal(staples, DEFAULT_PANTRY_STAPLES) {
+		t.Errorf("another user: got %q", staples)
+	}
+}
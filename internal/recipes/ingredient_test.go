@@ -0,0 +1,23 @@
+package recipes
+
+import "testing"
+
+func TestParseIngredient(t *testing.T) {
+	tests := []struct {
+		text string
+		want Ingredient
+	}{
+		{"3/4 cup sugar", Ingredient{Quantity: "3/4", Unit: "cup", Name: "sugar"}},
+		{"1 1/2 cups flour, sifted", Ingredient{Quantity: "1 1/2", Unit: "cups", Name: "flour", Note: "sifted"}},
+		{"1½ tsp salt", Ingredient{Quantity: "1½", Unit: "tsp", Name: "salt"}},
+		{"2-3 cloves of garlic", Ingredient{Quantity: "2-3", Unit: "cloves", Name: "garlic"}},
+		{"1/2 to 3/4 cup milk", Ingredient{Quantity: "1/2 to 3/4", Unit: "cup", Name: "milk"}},
+		{"salt and pepper", Ingredient{Name: "salt and pepper"}},
+	}
+
+	for _, test := range tests {
+		if got := ParseIngredient(test.text); got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.text, got, test.want)
+		}
+	}
+}
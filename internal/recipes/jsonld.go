@@ -0,0 +1,182 @@
+package recipes
+
+import (
+	"sourdough/internal/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONLDRecipe is a recipe as a schema.org Recipe, the format most recipe sites and
+// managers understand. See https://schema.org/Recipe.
+type JSONLDRecipe struct {
+	Context            string         `json:"@context"`
+	Type               string         `json:"@type"`
+	Name               string         `json:"name"`
+	URL                string         `json:"url,omitempty"`
+	RecipeIngredient   []string       `json:"recipeIngredient"`
+	RecipeInstructions []JSONLDStep   `json:"recipeInstructions"`
+	RecipeYield        string         `json:"recipeYield,omitempty"`
+	PrepTime           string         `json:"prepTime,omitempty"`
+	CookTime           string         `json:"cookTime,omitempty"`
+	TotalTime          string         `json:"totalTime,omitempty"`
+	Comment            *JSONLDComment `json:"comment,omitempty"`
+	Image              []string       `json:"image,omitempty"`
+	DateCreated        string         `json:"dateCreated,omitempty"`
+	DateModified       string         `json:"dateModified,omitempty"`
+}
+
+// JSONLDStep is a HowToStep, or a HowToSection holding steps under a heading.
+type JSONLDStep struct {
+	Type            string       `json:"@type"`
+	Text            string       `json:"text,omitempty"`
+	Name            string       `json:"name,omitempty"`
+	ItemListElement []JSONLDStep `json:"itemListElement,omitempty"`
+}
+
+// JSONLDComment holds a recipe's notes, which schema.org has no better place for.
+type JSONLDComment struct {
+	Type string `json:"@type"`
+	Text string `json:"text"`
+}
+
+// NewJSONLDRecipe describes the recipe as schema.org, with images pointing wherever the caller serves them.
+// Times that can't be parsed are left out rather than written in a form other tools would reject.
+func NewJSONLDRecipe(recipe *Recipe, images []string) JSONLDRecipe {
+	jsonLD := JSONLDRecipe{
+		Context:            "https://schema.org",
+		Type:               "Recipe",
+		Name:               recipe.Title,
+		RecipeIngredient:   nonBlank(recipe.Ingredients),
+		RecipeInstructions: []JSONLDStep{},
+		Image:              images,
+	}
+
+	if !recipe.CreatedAt.IsZero() {
+		jsonLD.DateCreated = recipe.CreatedAt.Format(time.RFC3339)
+	}
+
+	if !recipe.UpdatedAt.IsZero() {
+		jsonLD.DateModified = recipe.UpdatedAt.Format(time.RFC3339)
+	}
+
+	for _, group := range Groups(recipe.Directions, recipe.DirectionSections) {
+		steps := []JSONLDStep{}
+		for _, direction := range group.Lines {
+			steps = append(steps, JSONLDStep{Type: "HowToStep", Text: direction})
+		}
+
+		if group.Title == "" {
+			jsonLD.RecipeInstructions = append(jsonLD.RecipeInstructions, steps...)
+		} else {
+			jsonLD.RecipeInstructions = append(jsonLD.RecipeInstructions, JSONLDStep{Type: "HowToSection", Name: group.Title, ItemListElement: steps})
+		}
+	}
+
+	if recipe.Servings > 0 {
+		jsonLD.RecipeYield = strconv.Itoa(recipe.Servings)
+	}
+
+	// Recipes that haven't been saved yet don't have their times in minutes
+	times := *recipe
+	times.normalizeTimes()
+
+	jsonLD.PrepTime = isoMinutes(times.PrepMinutes)
+	jsonLD.CookTime = isoMinutes(times.CookMinutes)
+	jsonLD.TotalTime = isoMinutes(times.TotalMinutes)
+
+	if notes := strings.TrimSpace(recipe.Notes); notes != "" {
+		jsonLD.Comment = &JSONLDComment{Type: "Comment", Text: notes}
+	}
+
+	return jsonLD
+}
+
+// ToRecipe turns the schema.org description back into a recipe for userID.
+func (r JSONLDRecipe) ToRecipe(userID int) Recipe {
+	directions := []string{}
+	directionSections := []Section{}
+
+	// Steps outside of sections are grouped together so they don't run into the section before
+	var loose []string
+
+	for _, step := range r.RecipeInstructions {
+		if step.Type != "HowToSection" {
+			loose = append(loose, stepTexts([]JSONLDStep{step})...)
+			continue
+		}
+
+		directions, directionSections = appendGroup(directions, directionSections, Group{Lines: loose})
+		directions, directionSections = appendGroup(directions, directionSections, Group{
+			Title: strings.TrimSpace(step.Name),
+			Lines: stepTexts(step.ItemListElement),
+		})
+		loose = nil
+	}
+
+	directions, directionSections = appendGroup(directions, directionSections, Group{Lines: loose})
+
+	ingredients := nonBlank(r.RecipeIngredient)
+
+	recipe := Recipe{
+		UserID:              userID,
+		Title:               strings.TrimSpace(r.Name),
+		Ingredients:         database.JSONArray[string](ingredients),
+		NumberOfIngredients: len(ingredients),
+		Directions:          database.JSONArray[string](directions),
+		DirectionSections:   database.JSONArray[Section](directionSections),
+		PrepTime:            fromISODuration(r.PrepTime),
+		CookTime:            fromISODuration(r.CookTime),
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+
+	recipe.Servings, _ = strconv.Atoi(strings.TrimSpace(r.RecipeYield))
+
+	if r.Comment != nil {
+		recipe.Notes = strings.TrimSpace(r.Comment.Text)
+	}
+
+	return recipe
+}
+
+func stepTexts(steps []JSONLDStep) []string {
+	texts := []string{}
+
+	for _, step := range steps {
+		if text := strings.TrimSpace(step.Text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	return texts
+}
+
+func isoMinutes(minutes *int) string {
+	if minutes == nil {
+		return ""
+	}
+
+	return FormatISODuration(time.Duration(*minutes) * time.Minute)
+}
+
+func fromISODuration(text string) string {
+	if d, err := ParseISODuration(text); err == nil {
+		return FormatDuration(d)
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// nonBlank drops the empty lines the recipe form leaves behind. It never returns nil.
+func nonBlank(lines []string) []string {
+	result := []string{}
+
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+
+	return result
+}
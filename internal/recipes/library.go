@@ -0,0 +1,128 @@
+package recipes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+const LIBRARY_PAGE_SIZE = 24
+
+var ErrInvalidCursor = errors.New("that page of recipes couldn't be found")
+
+const (
+	SortRecent      = "recent"
+	SortTitle       = "title"
+	SortTime        = "time"
+	SortLastCooked  = "last-cooked"
+	SortTimesCooked = "most-cooked"
+)
+
+var SortLabels = []struct{ Value, Label string }{
+	{SortRecent, "Recently added"},
+	{SortTitle, "Title"},
+	{SortTime, "Total time"},
+	{SortLastCooked, "Recently cooked"},
+	{SortTimesCooked, "Most cooked"},
+}
+
+// LibraryQuery is how the user wants their recipe library sorted and filtered. Zero values
+// mean no filter. It round-trips through the URL, so the library looks the same after a reload.
+type LibraryQuery struct {
+	Sort           string
+	MaxMinutes     int
+	MaxIngredients int
+	MinServings    int
+	Favorites      bool
+	Dietary        DietaryFilter
+	Cursor         string
+}
+
+// LibraryPage is one page of the library. NextCursor is empty on the last page.
+type LibraryPage struct {
+	Recipes    []*Recipe
+	NextCursor string
+}
+
+// libraryCursor is the sort key and ID of the last recipe on a page. Pages continue after it,
+// so recipes added or removed meanwhile don't shift later pages the way offsets would.
+type libraryCursor struct {
+	Key any `json:"k"`
+	ID  int `json:"id"`
+}
+
+// ParseLibraryQuery reads a query from URL parameters. Anything unrecognizable is ignored.
+func ParseLibraryQuery(params map[string]string) LibraryQuery {
+	query := LibraryQuery{
+		Sort:   params["sort"],
+		Cursor: params["cursor"],
+	}
+
+	query.MaxMinutes, _ = strconv.Atoi(params["maxMinutes"])
+	query.MaxIngredients, _ = strconv.Atoi(params["maxIngredients"])
+	query.MinServings, _ = strconv.Atoi(params["minServings"])
+	query.Favorites = params["favorites"] != ""
+	query.Dietary = ParseDietaryFilter(params)
+
+	switch query.Sort {
+	case SortTitle, SortTime, SortLastCooked, SortTimesCooked:
+	default:
+		query.Sort = SortRecent
+	}
+
+	return query
+}
+
+// Values is the query as URL parameters, leaving out defaults so URLs stay short.
+func (q LibraryQuery) Values() url.Values {
+	values := url.Values{}
+
+	if q.Sort != SortRecent {
+		values.Set("sort", q.Sort)
+	}
+	if q.MaxMinutes > 0 {
+		values.Set("maxMinutes", strconv.Itoa(q.MaxMinutes))
+	}
+	if q.MaxIngredients > 0 {
+		values.Set("maxIngredients", strconv.Itoa(q.MaxIngredients))
+	}
+	if q.MinServings > 0 {
+		values.Set("minServings", strconv.Itoa(q.MinServings))
+	}
+	if q.Favorites {
+		values.Set("favorites", "on")
+	}
+	q.Dietary.setValues(values)
+	if q.Cursor != "" {
+		values.Set("cursor", q.Cursor)
+	}
+
+	return values
+}
+
+// NextPageURL is where the page after this one is loaded from, for infinite scroll.
+func (q LibraryQuery) NextPageURL(cursor string) string {
+	q.Cursor = cursor
+	return "/?" + q.Values().Encode()
+}
+
+func encodeCursor(cursor libraryCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(text string) (*libraryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor libraryCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,41 @@
+package recipes
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGetLibraryPages(t *testing.T) {
+	repo := newTestRepository(t)
+
+	for i := range LIBRARY_PAGE_SIZE + 1 {
+		if _, err := repo.Create(&Recipe{UserID: 1, Title: fmt.Sprintf("Recipe %02d", i), Ingredients: []string{}, Directions: []string{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := repo.GetLibrary(1, LibraryQuery{Sort: SortTitle})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Recipes) != LIBRARY_PAGE_SIZE || page.NextCursor == "" {
+		t.Fatalf("first page: got %d recipes and cursor %q", len(page.Recipes), page.NextCursor)
+	}
+
+	page, err = repo.GetLibrary(1, LibraryQuery{Sort: SortTitle, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Recipes) != 1 || page.Recipes[0].Title != fmt.Sprintf("Recipe %02d", LIBRARY_PAGE_SIZE) || page.NextCursor != "" {
+		t.Errorf("last page: got %d recipes and cursor %q", len(page.Recipes), page.NextCursor)
+	}
+
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24"} {
+		if _, err := repo.GetLibrary(1, LibraryQuery{Cursor: cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: got error %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
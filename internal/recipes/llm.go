@@ -2,7 +2,12 @@ package recipes
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"log"
+	"sourdough/internal/usage"
+	"strings"
 
 	openai "github.com/sashabaranov/go-openai"
 	"github.com/sashabaranov/go-openai/jsonschema"
@@ -12,21 +17,23 @@ const LLM_SYSTEM_PROMPT = `
 	You are a helpful model that specializes in formatting recipe text into a structured JSON output.
 	When you are given text input, if it looks like a recipe, you will do the following steps:
 		1. Clean up the formatting of individual ingredients, normalizing the measurements to American standards
-		2. Simplify individual steps in the instructions where it makes sense, but DO NOT remove or skip steps
+		2. Simplify individual steps in the directions where it makes sense, but DO NOT remove or skip steps
 		3. If you cannot determine a value for any of fields, output an empty string ("") for the value, DO NOT substitute any other value or skip the field
 		4. If the recipe you're given is missing cook time or prep time, output an empty string ("") for the value, DO NOT substitute any other value or skip the field
 		5. Some recipes include a "notes" section, which is separate from the directions. If the recipe has one of those, clean up the text and include it in the "notes" field.
-		6. Return your modified version of the recipe in JSON format, adhering to the following schema:
+		6. If the recipe doesn't say how many servings it makes, estimate a reasonable number of servings from the ingredient quantities
+		7. If the recipe groups its ingredients or directions under headings like "For the dough", keep each heading in place as its own line starting with "# ", e.g. "# For the dough"
+		8. Return your modified version of the recipe in JSON format, adhering to the following schema:
 			{
 				"title": "string",
 				"prepTime": "string", // in hours and minutes
 				"cookTime": "string", // in hours and minutes
 				"servings": "number",
 				"ingredients": [
-					"string"
+					"string" // or "# heading" to start a section
 				],
-				"instructions": [
-					"string"
+				"directions": [
+					"string" // or "# heading" to start a section
 				],
 				"notes": "string" // extract this from the recipe input if possible
 			}
@@ -34,69 +41,96 @@ const LLM_SYSTEM_PROMPT = `
 
 const LLM_IMAGE_SYSTEM_PROMPT = `
 	You are a helpful model that specializes in extracting recipe information from images and formatting it into structured JSON output.
-	When you are given an image that contains a recipe, you will do the following steps:
-		1. Extract all visible text from the image, paying special attention to ingredients lists and cooking instructions
+	You may be given several images. When you are, they are consecutive pages of the same recipe, in order, and you must merge them into a single recipe.
+	When you are given images that contain a recipe, you will do the following steps:
+		1. Extract all visible text from the images, paying special attention to ingredients lists and cooking directions, including lists that continue from one page to the next
 		2. Clean up the formatting of individual ingredients, normalizing the measurements to American standards
-		3. Organize the instructions into clear, sequential steps
+		3. Organize the directions into clear, sequential steps
 		4. If you cannot determine a value for any of the fields, output an empty string ("") for the value, DO NOT substitute any other value or skip the field
 		5. If the recipe is missing cook time or prep time, output an empty string ("") for the value, DO NOT substitute any other value or skip the field
 		6. Some recipes include a "notes" section, which is separate from the directions. If the recipe has one of those, clean up the text and include it in the "notes" field.
-		7. Return your extracted and formatted version of the recipe in JSON format, adhering to the following schema:
+		7. If the recipe doesn't say how many servings it makes, estimate a reasonable number of servings from the ingredient quantities
+		8. If the recipe groups its ingredients or directions under headings like "For the dough", keep each heading in place as its own line starting with "# ", e.g. "# For the dough"
+		9. Return your extracted and formatted version of the recipe in JSON format, adhering to the following schema:
 			{
 				"title": "string",
 				"prepTime": "string", // in hours and minutes
 				"cookTime": "string", // in hours and minutes
 				"servings": "number",
 				"ingredients": [
-					"string"
+					"string" // or "# heading" to start a section
 				],
-				"instructions": [
-					"string"
+				"directions": [
+					"string" // or "# heading" to start a section
 				],
 				"notes": "string" // extract this from the recipe input if possible
 			}
 `
 
+const LLM_TRANSFORM_SYSTEM_PROMPT = `
+	You are a helpful model that specializes in adapting recipes.
+	You will be given a recipe in JSON format, followed by how the user wants it changed. You will do the following steps:
+		1. Change the recipe as asked, and only as asked: keep the ingredients and steps the change doesn't affect exactly as they are
+		2. When you replace an ingredient, use an amount that works in the recipe, and update every step that mentions it
+		3. Update the prep time, cook time and servings if the change affects them
+		4. Give the recipe a title that says how it differs from the original, e.g. "Vegetarian Chili" for a chili made vegetarian
+		5. Start the "notes" field with a short summary of what you changed and why, followed by any notes the recipe already had
+		6. Keep each heading among the ingredients or directions in place as its own line starting with "# ", e.g. "# For the dough"
+		7. If the change can't be made to this recipe, make the closest change you can and say so in the notes
+		8. Return the adapted recipe in JSON format, adhering to the same schema as the recipe you were given
+`
+
 type LLMService struct {
 	client *openai.Client
 	model  string
+	meter  *usage.Meter
+	cache  *LLMCache
 }
 
-func NewLLMService(client *openai.Client, model string) *LLMService {
+func NewLLMService(client *openai.Client, model string, meter *usage.Meter, cache *LLMCache) *LLMService {
 	return &LLMService{
 		client: client,
 		model:  model,
+		meter:  meter,
+		cache:  cache,
+	}
+}
+
+// PromptVersions lists the versions of every system prompt in use, anything else in the cache is stale.
+func PromptVersions() []string {
+	return []string{
+		PromptVersion(LLM_SYSTEM_PROMPT),
+		PromptVersion(LLM_IMAGE_SYSTEM_PROMPT),
+		PromptVersion(LLM_TRANSFORM_SYSTEM_PROMPT),
 	}
 }
 
-func (s *LLMService) FormatRecipe(recipeText string) (LLMRecipe, error) {
-	var llmRecipe LLMRecipe
+func (s *LLMService) CacheStats() (CacheStats, error) {
+	return s.cache.Stats()
+}
 
-	schema, err := jsonschema.GenerateSchemaForType(llmRecipe)
+// The cache is an optimization, so problems with it are logged rather than failing the request
+func (s *LLMService) fromCache(key string) *LLMRecipe {
+	cached, err := s.cache.Get(key)
 	if err != nil {
-		return LLMRecipe{}, err
+		log.Printf("Failed to read LLM cache: %v", err)
+		return nil
 	}
 
-	req := openai.ChatCompletionRequest{
-		Model: s.model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: LLM_SYSTEM_PROMPT,
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: recipeText,
-			},
-		},
-		ResponseFormat: &openai.ChatCompletionResponseFormat{
-			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
-			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
-				Name:   "recipe",
-				Schema: schema,
-				Strict: true,
-			},
-		},
+	return cached
+}
+
+func (s *LLMService) storeInCache(key, promptVersion string, llmRecipe LLMRecipe) {
+	if err := s.cache.Set(key, s.model, promptVersion, llmRecipe); err != nil {
+		log.Printf("Failed to write LLM cache: %v", err)
+	}
+}
+
+// createChatCompletion enforces the user's quota before calling the provider and
+// records the tokens the provider reports afterwards.
+func (s *LLMService) createChatCompletion(userID int, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if err := s.meter.Check(userID); err != nil {
+		return openai.ChatCompletionResponse{}, err
 	}
 
 	resp, err := s.client.CreateChatCompletion(
@@ -105,22 +139,120 @@ func (s *LLMService) FormatRecipe(recipeText string) (LLMRecipe, error) {
 	)
 
 	if err != nil {
-		return LLMRecipe{}, err
+		return openai.ChatCompletionResponse{}, err
 	}
 
-	err = json.Unmarshal([]byte(resp.Choices[0].Message.Content), &llmRecipe)
+	// A failure to record usage shouldn't cost the user the recipe they just paid for
+	if err := s.meter.Record(userID, s.model, resp.Usage); err != nil {
+		log.Printf("Failed to record LLM usage: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (s *LLMService) FormatRecipe(userID int, recipeText string) (LLMRecipe, error) {
+	promptVersion := PromptVersion(LLM_SYSTEM_PROMPT)
+	cacheKey := CacheKey(s.model, promptVersion, "text", NormalizeRecipeText(recipeText))
+
+	return s.extract(userID, LLM_SYSTEM_PROMPT, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: recipeText,
+	}, cacheKey)
+}
+
+func (s *LLMService) FormatRecipeFromImages(userID int, images []SourceImage) (LLMRecipe, error) {
+	return s.formatRecipeFromImages(userID, images, "")
+}
+
+// FormatRecipeFromPDF uses the text path when every selected page has a text layer. Otherwise it
+// uses the vision path for the scanned pages, passing along any text the other pages had.
+func (s *LLMService) FormatRecipeFromPDF(userID int, pages []PDFPage) (LLMRecipe, error) {
+	var texts []string
+	var images []SourceImage
+
+	for _, page := range pages {
+		if page.HasText() {
+			texts = append(texts, page.Text)
+		} else if page.Image != nil {
+			images = append(images, *page.Image)
+		}
+	}
 
+	if len(images) > 0 {
+		return s.formatRecipeFromImages(userID, images, strings.Join(texts, "\n\n"))
+	}
+
+	if len(texts) > 0 {
+		return s.FormatRecipe(userID, strings.Join(texts, "\n\n"))
+	}
+
+	return LLMRecipe{}, ErrEmptyPDF
+}
+
+// TransformRecipe asks the model for a version of the recipe changed as the instructions say.
+// The recipe should have its components expanded, so the model sees all of it.
+func (s *LLMService) TransformRecipe(userID int, recipe *Recipe, instructions string) (LLMRecipe, error) {
+	recipeJSON, err := json.Marshal(recipe.toLLMRecipe())
 	if err != nil {
 		return LLMRecipe{}, err
 	}
 
-	return llmRecipe, nil
+	promptVersion := PromptVersion(LLM_TRANSFORM_SYSTEM_PROMPT)
+	cacheKey := CacheKey(s.model, promptVersion, "transform", string(recipeJSON), instructions)
+
+	return s.extract(userID, LLM_TRANSFORM_SYSTEM_PROMPT, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: string(recipeJSON) + "\n\nChange it like this:\n" + instructions,
+	}, cacheKey)
 }
 
-func (s *LLMService) FormatRecipeFromImage(base64Image, contentType string) (LLMRecipe, error) {
-	var llmRecipe LLMRecipe
+func (s *LLMService) formatRecipeFromImages(userID int, images []SourceImage, text string) (LLMRecipe, error) {
+	promptVersion := PromptVersion(LLM_IMAGE_SYSTEM_PROMPT)
+	cacheParts := []string{"image", text}
+
+	parts := []openai.ChatMessagePart{
+		{
+			Type: openai.ChatMessagePartTypeText,
+			Text: fmt.Sprintf("This recipe has %d page(s), in order.", len(images)),
+		},
+	}
+
+	if text != "" {
+		parts = append(parts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeText,
+			Text: "Some of the recipe is available as text, which follows. The rest is in the images.\n\n" + text,
+		})
+	}
+
+	for _, image := range images {
+		base64Image := base64.StdEncoding.EncodeToString(image.Data)
+		cacheParts = append(cacheParts, image.ContentType, base64Image)
+
+		parts = append(parts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL:    "data:" + image.ContentType + ";base64," + base64Image,
+				Detail: openai.ImageURLDetailHigh,
+			},
+		})
+	}
+
+	cacheKey := CacheKey(s.model, promptVersion, cacheParts...)
+
+	return s.extract(userID, LLM_IMAGE_SYSTEM_PROMPT, openai.ChatCompletionMessage{
+		Role:         openai.ChatMessageRoleUser,
+		MultiContent: parts,
+	}, cacheKey)
+}
+
+// extract asks the model to turn the input into an LLMRecipe. If the result doesn't pass
+// validation, the model gets LLM_REPAIR_ATTEMPTS chances to fix it before we give up.
+func (s *LLMService) extract(userID int, systemPrompt string, input openai.ChatCompletionMessage, cacheKey string) (LLMRecipe, error) {
+	if cached := s.fromCache(cacheKey); cached != nil {
+		return *cached, nil
+	}
 
-	schema, err := jsonschema.GenerateSchemaForType(llmRecipe)
+	schema, err := jsonschema.GenerateSchemaForType(LLMRecipe{})
 	if err != nil {
 		return LLMRecipe{}, err
 	}
@@ -130,20 +262,9 @@ func (s *LLMService) FormatRecipeFromImage(base64Image, contentType string) (LLM
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: LLM_IMAGE_SYSTEM_PROMPT,
-			},
-			{
-				Role: openai.ChatMessageRoleUser,
-				MultiContent: []openai.ChatMessagePart{
-					{
-						Type: openai.ChatMessagePartTypeImageURL,
-						ImageURL: &openai.ChatMessageImageURL{
-							URL:    "data:" + contentType + ";base64," + base64Image,
-							Detail: openai.ImageURLDetailHigh,
-						},
-					},
-				},
+				Content: systemPrompt,
 			},
+			input,
 		},
 		ResponseFormat: &openai.ChatCompletionResponseFormat{
 			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
@@ -155,20 +276,47 @@ func (s *LLMService) FormatRecipeFromImage(base64Image, contentType string) (LLM
 		},
 	}
 
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
-		req,
-	)
+	for attempt := 0; ; attempt++ {
+		resp, err := s.createChatCompletion(userID, req)
 
-	if err != nil {
-		return LLMRecipe{}, err
-	}
+		if err != nil {
+			return LLMRecipe{}, err
+		}
 
-	err = json.Unmarshal([]byte(resp.Choices[0].Message.Content), &llmRecipe)
+		if len(resp.Choices) == 0 {
+			return LLMRecipe{}, ErrEmptyLLMResponse
+		}
 
-	if err != nil {
-		return LLMRecipe{}, err
-	}
+		content := resp.Choices[0].Message.Content
+
+		var llmRecipe LLMRecipe
+		var problems []string
+
+		if err := json.Unmarshal([]byte(content), &llmRecipe); err != nil {
+			problems = []string{"the response is not valid JSON: " + err.Error()}
+		} else {
+			problems = llmRecipe.Validate()
+		}
+
+		if len(problems) == 0 {
+			s.storeInCache(cacheKey, PromptVersion(systemPrompt), llmRecipe)
+			return llmRecipe, nil
+		}
 
-	return llmRecipe, nil
+		if attempt >= LLM_REPAIR_ATTEMPTS {
+			return LLMRecipe{}, &RecipeValidationError{Problems: problems}
+		}
+
+		req.Messages = append(
+			req.Messages,
+			openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: content,
+			},
+			openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: repairPrompt(problems),
+			},
+		)
+	}
 }
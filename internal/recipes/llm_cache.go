@@ -0,0 +1,127 @@
+package recipes
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sourdough/internal/database"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CacheStats counts hits and misses since the process started.
+type CacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// LLMCache stores successful extractions keyed by a hash of the model, the system prompt
+// and the normalized input, so pasting the same recipe twice only costs one LLM call.
+type LLMCache struct {
+	db     *database.DB
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func NewLLMCache(db *database.DB) *LLMCache {
+	return &LLMCache{db: db}
+}
+
+// PromptVersion identifies a system prompt. Editing a prompt changes its version,
+// which changes every key built from it.
+func PromptVersion(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:8])
+}
+
+func CacheKey(model, promptVersion string, parts ...string) string {
+	hash := sha256.New()
+	hash.Write([]byte(model + "\x00" + promptVersion))
+
+	for _, part := range parts {
+		hash.Write([]byte("\x00" + part))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// NormalizeRecipeText collapses whitespace so that the same recipe copied from two
+// places still hits the cache.
+func NormalizeRecipeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func (c *LLMCache) Get(key string) (*LLMRecipe, error) {
+	var result string
+
+	err := c.db.Get(&result, "SELECT result FROM llm_cache WHERE key = ?", key)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.misses.Add(1)
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var llmRecipe LLMRecipe
+	if err := json.Unmarshal([]byte(result), &llmRecipe); err != nil {
+		return nil, err
+	}
+
+	c.hits.Add(1)
+
+	return &llmRecipe, nil
+}
+
+func (c *LLMCache) Set(key, model, promptVersion string, llmRecipe LLMRecipe) error {
+	result, err := json.Marshal(llmRecipe)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(
+		"INSERT OR REPLACE INTO llm_cache (key, model, prompt_version, result) VALUES (?, ?, ?, ?)",
+		key,
+		model,
+		promptVersion,
+		string(result),
+	)
+
+	return err
+}
+
+// DeleteStale removes entries created with a prompt that is no longer in use.
+func (c *LLMCache) DeleteStale(promptVersions []string) (int64, error) {
+	query, args, err := sqlx.In("DELETE FROM llm_cache WHERE prompt_version NOT IN (?)", promptVersions)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := c.db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (c *LLMCache) Stats() (CacheStats, error) {
+	stats := CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+
+	err := c.db.Get(&stats.Entries, "SELECT COUNT(*) FROM llm_cache")
+	if err != nil {
+		return CacheStats{}, err
+	}
+
+	return stats, nil
+}
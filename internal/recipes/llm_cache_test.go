@@ -0,0 +1,98 @@
+package recipes
+
+import (
+	"path/filepath"
+	"sourdough/internal/database"
+	"testing"
+)
+
+func newTestCache(t *testing.T) *LLMCache {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return NewLLMCache(db)
+}
+
+func TestLLMCacheHitAndMiss(t *testing.T) {
+	cache := newTestCache(t)
+	version := PromptVersion(LLM_SYSTEM_PROMPT)
+
+	key := CacheKey("model", version, "text", NormalizeRecipeText("Toast\n\n  1 slice bread"))
+
+	if cached, err := cache.Get(key); err != nil || cached != nil {
+		t.Fatalf("empty cache: got %v, %v", cached, err)
+	}
+
+	if err := cache.Set(key, "model", version, LLMRecipe{Title: "Toast"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Differently spaced input is the same recipe
+	cached, err := cache.Get(CacheKey("model", version, "text", NormalizeRecipeText("Toast 1 slice\tbread")))
+	if err != nil || cached == nil || cached.Title != "Toast" {
+		t.Fatalf("got %v, %v", cached, err)
+	}
+
+	// Another model isn't
+	if cached, err := cache.Get(CacheKey("other-model", version, "text", NormalizeRecipeText("Toast 1 slice bread"))); err != nil || cached != nil {
+		t.Fatalf("other model: got %v, %v", cached, err)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats != (CacheStats{Hits: 1, Misses: 2, Entries: 1}) {
+		t.Errorf("got %+v", stats)
+	}
+}
+
+func TestLLMCacheInvalidatedByPromptChange(t *testing.T) {
+	cache := newTestCache(t)
+
+	oldVersion := PromptVersion(LLM_SYSTEM_PROMPT + "\nAn instruction that has since been removed")
+	currentVersion := PromptVersion(LLM_SYSTEM_PROMPT)
+
+	if oldVersion == currentVersion {
+		t.Fatal("editing a prompt didn't change its version")
+	}
+
+	oldKey := CacheKey("model", oldVersion, "text", "Toast")
+	currentKey := CacheKey("model", currentVersion, "text", "Toast")
+
+	if err := cache.Set(oldKey, "model", oldVersion, LLMRecipe{Title: "Old toast"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if cached, err := cache.Get(currentKey); err != nil || cached != nil {
+		t.Fatalf("current prompt read the old prompt's entry: got %v, %v", cached, err)
+	}
+
+	if err := cache.Set(currentKey, "model", currentVersion, LLMRecipe{Title: "Toast"}); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := cache.DeleteStale(PromptVersions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted != 1 {
+		t.Errorf("deleted %d entries, want 1", deleted)
+	}
+
+	if cached, err := cache.Get(oldKey); err != nil || cached != nil {
+		t.Errorf("stale entry: got %v, %v", cached, err)
+	}
+
+	if cached, err := cache.Get(currentKey); err != nil || cached == nil || cached.Title != "Toast" {
+		t.Errorf("current entry: got %v, %v", cached, err)
+	}
+}
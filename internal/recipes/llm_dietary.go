@@ -0,0 +1,125 @@
+package recipes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sourdough/internal/nutrition"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+const LLM_INGREDIENT_TRAITS_PROMPT = `
+You check recipe ingredients for allergens and animal products, for people with allergies and diets.
+The user will send a list of ingredients.
+
+For each ingredient, say what it usually contains, including what's hidden in prepared ingredients,
+e.g. "pesto" contains nuts and dairy, "worcestershire sauce" contains fish and "naan" contains gluten and dairy.
+- nuts: peanuts or tree nuts
+- dairy: milk or anything made from it
+- gluten: wheat, barley, rye or anything made from them
+- eggs, shellfish and soy as named
+- meat: meat, poultry or fish, including stocks, gelatin and animal fats
+- animal: any other animal product that isn't vegan, like honey
+Answer for every ingredient, in the order given.
+`
+
+type llmIngredientTraits struct {
+	Ingredients []llmIngredientTrait `json:"ingredients"`
+}
+
+type llmIngredientTrait struct {
+	Ingredient string `json:"ingredient"`
+	Nuts       bool   `json:"nuts"`
+	Dairy      bool   `json:"dairy"`
+	Gluten     bool   `json:"gluten"`
+	Eggs       bool   `json:"eggs"`
+	Shellfish  bool   `json:"shellfish"`
+	Soy        bool   `json:"soy"`
+	Meat       bool   `json:"meat"`
+	Animal     bool   `json:"animal"`
+}
+
+func (t llmIngredientTrait) traits() []string {
+	traits := []string{}
+
+	for _, answer := range []struct {
+		trait string
+		has   bool
+	}{
+		{LabelNuts, t.Nuts}, {LabelDairy, t.Dairy}, {LabelGluten, t.Gluten}, {LabelEggs, t.Eggs},
+		{LabelShellfish, t.Shellfish}, {LabelSoy, t.Soy}, {traitMeat, t.Meat}, {traitAnimal, t.Animal},
+	} {
+		if answer.has {
+			traits = append(traits, answer.trait)
+		}
+	}
+
+	return traits
+}
+
+// ClassifyIngredients asks the LLM for the allergens and animal products in ingredients the
+// dietary rules don't recognize. The result is keyed by nutrition.Key, with no traits for
+// names that have none, so they aren't asked about again.
+func (s *LLMService) ClassifyIngredients(userID int, names []string) (map[string][]string, error) {
+	schema, err := jsonschema.GenerateSchemaForType(llmIngredientTraits{})
+	if err != nil {
+		return nil, err
+	}
+
+	var list strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&list, "- %s\n", name)
+	}
+
+	resp, err := s.createChatCompletion(userID, openai.ChatCompletionRequest{
+		Model: s.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: LLM_INGREDIENT_TRAITS_PROMPT,
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: list.String(),
+			},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "ingredient_traits",
+				Schema: schema,
+				Strict: true,
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, ErrEmptyLLMResponse
+	}
+
+	var result llmIngredientTraits
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
+		return nil, err
+	}
+
+	asked := map[string]bool{}
+	for _, name := range names {
+		asked[nutrition.Key(name)] = true
+	}
+
+	// Only answers about the names asked are worth keeping
+	traits := map[string][]string{}
+	for _, answer := range result.Ingredients {
+		if key := nutrition.Key(answer.Ingredient); asked[key] {
+			traits[key] = answer.traits()
+		}
+	}
+
+	return traits, nil
+}
@@ -0,0 +1,109 @@
+package recipes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sourdough/internal/nutrition"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+const LLM_FOOD_MATCH_PROMPT = `
+You match recipe ingredients to foods in a nutrition table.
+The user will send the list of foods in the table, followed by the ingredients to match.
+
+For each ingredient, answer with the food from the table that is the closest match nutritionally,
+e.g. "scallions" is "green onion" and "double-smoked bacon" is "bacon".
+Write the food's name exactly as it appears in the table.
+If no food in the table is close, answer with an empty string rather than guessing.
+Answer for every ingredient, in the order given.
+`
+
+type llmFoodMatches struct {
+	Matches []llmFoodMatch `json:"matches"`
+}
+
+type llmFoodMatch struct {
+	Ingredient string `json:"ingredient"`
+	Food       string `json:"food"` // the food's name in the table, or "" if none is close
+}
+
+// MatchFoods asks the LLM which foods in the nutrition data the ingredient names are, for the
+// ones the data doesn't know by name. The result is keyed by nutrition.Key, with an empty food
+// for names that have no match, so they aren't asked about again.
+func (s *LLMService) MatchFoods(userID int, names []string) (map[string]string, error) {
+	schema, err := jsonschema.GenerateSchemaForType(llmFoodMatches{})
+	if err != nil {
+		return nil, err
+	}
+
+	var table strings.Builder
+	table.WriteString("Foods:\n")
+	for _, food := range nutrition.Foods() {
+		fmt.Fprintf(&table, "- %s\n", food.Name)
+	}
+
+	table.WriteString("\nIngredients:\n")
+	for _, name := range names {
+		fmt.Fprintf(&table, "- %s\n", name)
+	}
+
+	resp, err := s.createChatCompletion(userID, openai.ChatCompletionRequest{
+		Model: s.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: LLM_FOOD_MATCH_PROMPT,
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: table.String(),
+			},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "food_matches",
+				Schema: schema,
+				Strict: true,
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, ErrEmptyLLMResponse
+	}
+
+	var result llmFoodMatches
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
+		return nil, err
+	}
+
+	asked := map[string]bool{}
+	for _, name := range names {
+		asked[nutrition.Key(name)] = true
+	}
+
+	// Only answers about the names asked, naming foods that exist, are worth keeping
+	foods := map[string]string{}
+	for _, match := range result.Matches {
+		key := nutrition.Key(match.Ingredient)
+		if !asked[key] {
+			continue
+		}
+
+		if food := nutrition.FindFood(match.Food); food != nil {
+			foods[key] = food.Name
+		} else {
+			foods[key] = ""
+		}
+	}
+
+	return foods, nil
+}
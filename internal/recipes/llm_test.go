@@ -0,0 +1,153 @@
+package recipes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sourdough/internal/database"
+	"sourdough/internal/usage"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const validRecipeJSON = `{"title":"Toast","prepTime":"","cookTime":"5 minutes","servings":1,"ingredients":["1 slice bread"],"directions":["Toast the bread"],"notes":""}`
+
+// stubbedRequest is the part of a chat completion request the tests look at
+type stubbedRequest struct {
+	Messages []openai.ChatCompletionMessage `json:"messages"`
+}
+
+// newStubbedLLMService returns a service whose provider answers each request with the next of
+// the given responses, and the requests the provider received.
+func newStubbedLLMService(t *testing.T, responses ...string) (*LLMService, *[]stubbedRequest) {
+	t.Helper()
+
+	var requests []stubbedRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req stubbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+
+		if len(requests) >= len(responses) {
+			t.Errorf("unexpected request %d", len(requests)+1)
+			http.Error(w, "no more responses", http.StatusInternalServerError)
+			return
+		}
+
+		content := responses[len(requests)]
+		requests = append(requests, req)
+
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content}},
+			},
+			Usage: openai.Usage{PromptTokens: 10, CompletionTokens: 10, TotalTokens: 20},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL
+
+	meter := usage.NewMeter(usage.NewRepository(db), usage.Quota{}, usage.Pricing{})
+	service := NewLLMService(openai.NewClientWithConfig(config), "model", meter, NewLLMCache(db))
+
+	return service, &requests
+}
+
+func TestExtractRepairsAnInvalidResponse(t *testing.T) {
+	service, requests := newStubbedLLMService(t,
+		`{"title":"","prepTime":"","cookTime":"","servings":0,"ingredients":[],"directions":["Toast the bread"],"notes":""}`,
+		validRecipeJSON,
+	)
+
+	llmRecipe, err := service.FormatRecipe(1, "Toast: toast 1 slice of bread")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if llmRecipe.Title != "Toast" {
+		t.Errorf("got %+v", llmRecipe)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(*requests))
+	}
+
+	// The repair request carries the whole conversation so far, ending with what was wrong
+	messages := (*requests)[1].Messages
+	if len(messages) != 4 || messages[2].Role != openai.ChatMessageRoleAssistant {
+		t.Fatalf("got messages %+v", messages)
+	}
+
+	repair := messages[3].Content
+	for _, problem := range []string{"the title is empty", "there are no ingredients", "servings must be between"} {
+		if !strings.Contains(repair, problem) {
+			t.Errorf("repair prompt %q doesn't mention %q", repair, problem)
+		}
+	}
+}
+
+func TestExtractGivesUpAfterTheRepairAttempts(t *testing.T) {
+	responses := make([]string, LLM_REPAIR_ATTEMPTS+1)
+	for i := range responses {
+		responses[i] = "not JSON"
+	}
+
+	service, requests := newStubbedLLMService(t, responses...)
+
+	_, err := service.FormatRecipe(1, "Toast: toast 1 slice of bread")
+
+	var validationErr *RecipeValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a RecipeValidationError", err)
+	}
+
+	if len(validationErr.Problems) != 1 || !strings.Contains(validationErr.Problems[0], "not valid JSON") {
+		t.Errorf("got problems %q", validationErr.Problems)
+	}
+
+	if len(*requests) != LLM_REPAIR_ATTEMPTS+1 {
+		t.Errorf("got %d requests, want %d", len(*requests), LLM_REPAIR_ATTEMPTS+1)
+	}
+}
+
+func TestExtractCachesOnlyValidRecipes(t *testing.T) {
+	responses := make([]string, LLM_REPAIR_ATTEMPTS+1)
+	for i := range responses {
+		responses[i] = "not JSON"
+	}
+
+	service, requests := newStubbedLLMService(t, append(responses, validRecipeJSON)...)
+
+	if _, err := service.FormatRecipe(1, "Toast"); err == nil {
+		t.Fatal("expected the invalid recipe to fail")
+	}
+
+	// The failure wasn't cached, so this asks the provider again
+	if _, err := service.FormatRecipe(1, "Toast"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Which it doesn't need to a third time
+	if _, err := service.FormatRecipe(1, "Toast"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != LLM_REPAIR_ATTEMPTS+2 {
+		t.Errorf("got %d requests, want %d", len(*requests), LLM_REPAIR_ATTEMPTS+2)
+	}
+}
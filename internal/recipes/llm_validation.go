@@ -0,0 +1,81 @@
+package recipes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const LLM_REPAIR_ATTEMPTS = 1
+
+const MAX_SERVINGS = 100
+
+var ErrEmptyLLMResponse = errors.New("the LLM provider returned no response")
+
+// RecipeValidationError is returned when the model's output still isn't a usable recipe
+// after it has been asked to repair it.
+type RecipeValidationError struct {
+	Problems []string
+}
+
+func (e *RecipeValidationError) Error() string {
+	return "We couldn't turn that into a recipe: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate returns a list of human (and model) readable problems with the recipe.
+func (r LLMRecipe) Validate() []string {
+	var problems []string
+
+	if strings.TrimSpace(r.Title) == "" {
+		problems = append(problems, "the title is empty")
+	}
+
+	ingredients, _ := ParseSections(r.Ingredients, false)
+	directions, _ := ParseSections(r.Directions, false)
+
+	if countNonBlank(ingredients) == 0 {
+		problems = append(problems, "there are no ingredients")
+	}
+
+	if countNonBlank(directions) == 0 {
+		problems = append(problems, "there are no directions")
+	}
+
+	if r.Servings < 1 || r.Servings > MAX_SERVINGS {
+		problems = append(problems, fmt.Sprintf("servings must be between 1 and %d, got %d", MAX_SERVINGS, r.Servings))
+	}
+
+	if _, err := ParseDuration(r.PrepTime); r.PrepTime != "" && err != nil {
+		problems = append(problems, fmt.Sprintf("prepTime %q is not a duration in hours and minutes", r.PrepTime))
+	}
+
+	if _, err := ParseDuration(r.CookTime); r.CookTime != "" && err != nil {
+		problems = append(problems, fmt.Sprintf("cookTime %q is not a duration in hours and minutes", r.CookTime))
+	}
+
+	return problems
+}
+
+func repairPrompt(problems []string) string {
+	var sb strings.Builder
+
+	sb.WriteString("Your response has the following problems:\n")
+	for _, problem := range problems {
+		sb.WriteString("- " + problem + "\n")
+	}
+	sb.WriteString("Fix them using the original recipe and return the complete recipe again, adhering to the same schema.")
+
+	return sb.String()
+}
+
+func countNonBlank(values []string) int {
+	count := 0
+
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			count++
+		}
+	}
+
+	return count
+}
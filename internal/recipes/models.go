@@ -7,18 +7,125 @@ import (
 )
 
 type Recipe struct {
-	ID                  int                        `db:"id"`
-	UserID              int                        `db:"user_id"`
-	Title               string                     `db:"title"`
-	Ingredients         database.JSONArray[string] `db:"ingredients"`
-	NumberOfIngredients int                        `db:"number_of_ingredients"`
-	Directions          database.JSONArray[string] `db:"directions"`
-	Notes               string                     `db:"notes"`
-	PrepTime            string                     `db:"prep_time"`
-	CookTime            string                     `db:"cook_time"`
-	Servings            int                        `db:"servings"`
-	CreatedAt           time.Time                  `db:"created_at"`
-	UpdatedAt           time.Time                  `db:"updated_at"`
+	ID                  int                                  `db:"id"`
+	UserID              int                                  `db:"user_id"`
+	Title               string                               `db:"title"`
+	Ingredients         database.JSONArray[string]           `db:"ingredients"`
+	NumberOfIngredients int                                  `db:"number_of_ingredients"`
+	Directions          database.JSONArray[string]           `db:"directions"`
+	IngredientSections  database.JSONArray[Section]          `db:"ingredient_sections"`
+	DirectionSections   database.JSONArray[Section]          `db:"direction_sections"`
+	Notes               string                               `db:"notes"`
+	PrepTime            string                               `db:"prep_time"`
+	CookTime            string                               `db:"cook_time"`
+	Servings            int                                  `db:"servings"`
+	SourceID            *int                                 `db:"source_id"`
+	PrepMinutes         *int                                 `db:"prep_minutes"`
+	CookMinutes         *int                                 `db:"cook_minutes"`
+	ActiveMinutes       *int                                 `db:"active_minutes"`
+	InactiveMinutes     *int                                 `db:"inactive_minutes"`
+	TotalMinutes        *int                                 `db:"total_minutes"`
+	TimesCooked         int                                  `db:"times_cooked"`
+	LastCookedOn        *time.Time                           `db:"last_cooked_on"`
+	Favorite            bool                                 `db:"favorite"`
+	DietaryDetected     database.JSONArray[string]           `db:"dietary_detected"`
+	DietaryOverrides    database.JSONObject[map[string]bool] `db:"dietary_overrides"`
+	ParentID            *int                                 `db:"parent_id"`
+	CreatedAt           time.Time                            `db:"created_at"`
+	UpdatedAt           time.Time                            `db:"updated_at"`
+}
+
+// Source is an original file a recipe was imported from, like a PDF.
+type Source struct {
+	ID          int       `db:"id"`
+	UserID      int       `db:"user_id"`
+	Filename    string    `db:"filename"`
+	ContentType string    `db:"content_type"`
+	Data        []byte    `db:"data"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// SourcePage is a page of a PDF source as ReadPDF found it, kept so the PDF is only read once.
+type SourcePage struct {
+	SourceID    int    `db:"source_id"`
+	Number      int    `db:"number"`
+	Text        string `db:"text"`
+	ContentType string `db:"content_type"`
+	Image       []byte `db:"image"`
+}
+
+func (p SourcePage) PDFPage() PDFPage {
+	page := PDFPage{Number: p.Number, Text: p.Text}
+	if p.Image != nil {
+		page.Image = &SourceImage{ContentType: p.ContentType, Data: p.Image}
+	}
+
+	return page
+}
+
+// Photo is a picture of the finished dish, as opposed to a Source it was imported from.
+type Photo struct {
+	ID          int       `db:"id"`
+	RecipeID    int       `db:"recipe_id"`
+	Position    int       `db:"position"`
+	ContentType string    `db:"content_type"`
+	Data        []byte    `db:"data"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+const MAX_RATING = 5
+
+// CookLogEntry is one time a recipe was cooked, with how it went. Rating is 1 to 5 stars,
+// or 0 if it wasn't rated. The photo is only loaded on its own, see GetCookLogPhoto.
+type CookLogEntry struct {
+	ID               int       `db:"id"`
+	RecipeID         int       `db:"recipe_id"`
+	CookedOn         time.Time `db:"cooked_on"`
+	Rating           int       `db:"rating"`
+	Notes            string    `db:"notes"`
+	Tweaks           string    `db:"tweaks"`
+	PhotoContentType string    `db:"photo_content_type"`
+	Photo            []byte    `db:"photo"`
+	CreatedAt        time.Time `db:"created_at"`
+}
+
+// Collection is a user's named, ordered group of recipes, like "Thanksgiving". A recipe
+// can be in any number of collections. RecipeCount is only set when listing collections.
+type Collection struct {
+	ID          int       `db:"id"`
+	UserID      int       `db:"user_id"`
+	Name        string    `db:"name"`
+	CreatedAt   time.Time `db:"created_at"`
+	RecipeCount int       `db:"recipe_count"`
+}
+
+// RecipeCollection is one of the user's collections and whether a given recipe is in it.
+type RecipeCollection struct {
+	Collection
+	Contains bool `db:"contains"`
+}
+
+// today is the date in the server's time zone, stored as midnight UTC so log entries compare by day.
+func today() time.Time {
+	year, month, day := time.Now().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Embedding is a vector describing what a recipe is about, for semantic search. ContentHash
+// identifies the text it was computed from, so edits can be detected.
+type Embedding struct {
+	RecipeID    int             `db:"recipe_id"`
+	Model       string          `db:"model"`
+	ContentHash string          `db:"content_hash"`
+	Vector      database.Vector `db:"vector"`
+	UpdatedAt   time.Time       `db:"updated_at"`
+}
+
+// Similarity is how alike two of a user's recipes are, from 0 to 1.
+type Similarity struct {
+	RecipeID  int     `db:"recipe_id"`
+	SimilarID int     `db:"similar_id"`
+	Score     float64 `db:"score"`
 }
 
 type FormRecipe struct {
@@ -29,19 +136,28 @@ type FormRecipe struct {
 	Notes               string `form:"notes"`
 	PrepTime            string `form:"prep_time"`
 	CookTime            string `form:"cook_time"`
+	ActiveTime          string `form:"active_time"`
+	InactiveTime        string `form:"inactive_time"`
 	Servings            int    `form:"servings"`
 }
 
 func (r FormRecipe) ToRecipe(userID int) Recipe {
+	ingredients, ingredientSections := ParseSections(strings.Split(r.Ingredients, "\n"), true)
+	directions, directionSections := ParseSections(strings.Split(r.Directions, "\n"), false)
+
 	return Recipe{
 		UserID:              userID,
 		Title:               r.Title,
-		Ingredients:         database.JSONArray[string](strings.Split(r.Ingredients, "\n")),
+		Ingredients:         database.JSONArray[string](ingredients),
 		NumberOfIngredients: r.NumberOfIngredients,
-		Directions:          database.JSONArray[string](strings.Split(r.Directions, "\n")),
+		Directions:          database.JSONArray[string](directions),
+		IngredientSections:  database.JSONArray[Section](ingredientSections),
+		DirectionSections:   database.JSONArray[Section](directionSections),
 		Notes:               r.Notes,
 		PrepTime:            r.PrepTime,
 		CookTime:            r.CookTime,
+		ActiveMinutes:       ParseMinutes(r.ActiveTime),
+		InactiveMinutes:     ParseMinutes(r.InactiveTime),
 		Servings:            r.Servings,
 		CreatedAt:           time.Now(),
 		UpdatedAt:           time.Now(),
@@ -59,12 +175,17 @@ type LLMRecipe struct {
 }
 
 func (r LLMRecipe) ToRecipe(userID int) Recipe {
+	ingredients, ingredientSections := ParseSections(r.Ingredients, false)
+	directions, directionSections := ParseSections(r.Directions, false)
+
 	return Recipe{
 		UserID:              userID,
 		Title:               r.Title,
-		Ingredients:         database.JSONArray[string](r.Ingredients),
-		NumberOfIngredients: len(r.Ingredients),
-		Directions:          database.JSONArray[string](r.Directions),
+		Ingredients:         database.JSONArray[string](ingredients),
+		NumberOfIngredients: len(ingredients),
+		Directions:          database.JSONArray[string](directions),
+		IngredientSections:  database.JSONArray[Section](ingredientSections),
+		DirectionSections:   database.JSONArray[Section](directionSections),
 		Notes:               r.Notes,
 		PrepTime:            r.PrepTime,
 		CookTime:            r.CookTime,
@@ -73,3 +194,32 @@ func (r LLMRecipe) ToRecipe(userID int) Recipe {
 		UpdatedAt:           time.Now(),
 	}
 }
+
+// SourceImage is one photo of a recipe. Recipes that span several pages have several.
+type SourceImage struct {
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
+}
+
+// Draft is an LLM-extracted recipe waiting for the user to review it, along with
+// the original text or image it was extracted from. Drafts of a transformed recipe have
+// a parent instead, and say how it was asked to change.
+type Draft struct {
+	ID           int                             `db:"id"`
+	UserID       int                             `db:"user_id"`
+	Recipe       database.JSONObject[LLMRecipe]  `db:"recipe"`
+	SourceText   string                          `db:"source_text"`
+	SourceImages database.JSONArray[SourceImage] `db:"source_images"`
+	SourceID     *int                            `db:"source_id"`
+	ParentID     *int                            `db:"parent_id"`
+	Transform    string                          `db:"transform"`
+	CreatedAt    time.Time                       `db:"created_at"`
+}
+
+// Preview is the recipe as it will be saved, for rendering in the recipe form.
+func (d Draft) Preview() *Recipe {
+	recipe := d.Recipe.Data.ToRecipe(d.UserID)
+	recipe.SourceID = d.SourceID
+	recipe.normalizeTimes()
+	return &recipe
+}
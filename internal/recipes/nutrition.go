@@ -0,0 +1,109 @@
+package recipes
+
+import (
+	"regexp"
+	"sourdough/internal/nutrition"
+	"strings"
+)
+
+// Matches a package size written after the quantity, as in "2 (15 oz) cans chickpeas". The
+// size in parentheses is what gets weighed, once for each package.
+var packageSizePattern = regexp.MustCompile(`(?i)^\(\s*([\d.,/½⅓⅔¼¾⅛⅜⅝⅞ -]+?)\s*-?\s*([a-z]+)\.?\s*\)\s*(?:(?:cans?|packages?|pkgs?|jars?|boxes|box|bags?|containers?|bottles?|tins?)\s+)?(?:of\s+)?`)
+
+// NutritionItems parses the recipe's ingredients for a nutrition estimate. Pass it an expanded
+// recipe so components count too. foods holds the foods the LLM matched to names the data
+// didn't have, keyed by nutrition.Key; they win over matching the name against the data.
+func NutritionItems(recipe *Recipe, foods map[string]string) []nutrition.Item {
+	items := []nutrition.Item{}
+
+	for _, line := range nonBlank(recipe.Ingredients) {
+		ingredient, amount, ok := parseNutritionIngredient(line)
+
+		item := nutrition.Item{
+			Line:      line,
+			Amount:    amount,
+			HasAmount: ok,
+			Unit:      ingredient.Unit,
+		}
+
+		if food, matched := foods[nutrition.Key(ingredient.Name)]; matched {
+			item.Food = nutrition.FindFood(food)
+		} else {
+			item.Food = nutrition.MatchFood(ingredient.Name)
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// parseNutritionIngredient parses an ingredient line and reads its amount, folding a package
+// size into the amount and unit.
+func parseNutritionIngredient(line string) (Ingredient, float64, bool) {
+	ingredient := ParseIngredient(line)
+	amount, ok := ingredient.Amount()
+
+	if ok && ingredient.Unit == "" {
+		if size := packageSizePattern.FindStringSubmatch(ingredient.Name); size != nil {
+			if each, sized := (Ingredient{Quantity: strings.TrimSpace(size[1])}).Amount(); sized {
+				amount *= each
+				ingredient.Unit = size[2]
+				ingredient.Name = strings.TrimSpace(ingredient.Name[len(size[0]):])
+			}
+		}
+	}
+
+	return ingredient, amount, ok
+}
+
+// NutritionEstimate is a recipe's estimate as its page shows it. CanLookUp says whether some
+// of the ingredients the data didn't have haven't been asked about yet.
+type NutritionEstimate struct {
+	*nutrition.Estimate
+	CanLookUp bool
+}
+
+// EstimateNutrition estimates the recipe's nutrients per serving from its ingredients.
+func EstimateNutrition(recipe *Recipe, foods map[string]string) *NutritionEstimate {
+	return &NutritionEstimate{
+		Estimate:  nutrition.EstimateItems(NutritionItems(recipe, foods), recipe.Servings),
+		CanLookUp: len(unmatchedIngredientNames(recipe, foods)) > 0,
+	}
+}
+
+// unmatchedIngredientNames are the names of the ingredients the data had no food for, to ask
+// the LLM about.
+func unmatchedIngredientNames(recipe *Recipe, foods map[string]string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	for _, item := range NutritionItems(recipe, foods) {
+		if item.Food != nil {
+			continue
+		}
+
+		ingredient, _, _ := parseNutritionIngredient(item.Line)
+		if key := nutrition.Key(ingredient.Name); key != "" && !seen[key] {
+			if _, asked := foods[key]; !asked {
+				names = append(names, ingredient.Name)
+			}
+			seen[key] = true
+		}
+	}
+
+	return names
+}
+
+// ingredientNameKeys are the keys of all of the recipe's ingredient names, for looking up
+// the foods the LLM matched before.
+func ingredientNameKeys(recipe *Recipe) []string {
+	keys := []string{}
+
+	for _, line := range nonBlank(recipe.Ingredients) {
+		ingredient, _, _ := parseNutritionIngredient(line)
+		keys = append(keys, nutrition.Key(ingredient.Name))
+	}
+
+	return keys
+}
@@ -0,0 +1,76 @@
+package recipes
+
+import (
+	"reflect"
+	"sourdough/internal/nutrition"
+	"testing"
+)
+
+func TestParseNutritionIngredient(t *testing.T) {
+	tests := []struct {
+		line   string
+		amount float64
+		ok     bool
+		unit   string
+		name   string
+	}{
+		{"2 cups all-purpose flour", 2, true, "cups", "all-purpose flour"},
+		{"1 1/2 cups milk", 1.5, true, "cups", "milk"},
+		{"3 eggs", 3, true, "", "eggs"},
+		// Package sizes are weighed once for each package
+		{"2 (15 oz) cans chickpeas", 30, true, "oz", "chickpeas"},
+		{"1 (14.5-oz.) can of diced tomatoes", 14.5, true, "oz", "diced tomatoes"},
+		{"Salt to taste", 0, false, "", "Salt to taste"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			ingredient, amount, ok := parseNutritionIngredient(tt.line)
+
+			if amount != tt.amount || ok != tt.ok || ingredient.Unit != tt.unit || ingredient.Name != tt.name {
+				t.Errorf("got %v, %v, %q, %q, want %v, %v, %q, %q", amount, ok, ingredient.Unit, ingredient.Name, tt.amount, tt.ok, tt.unit, tt.name)
+			}
+		})
+	}
+}
+
+func TestEstimateNutrition(t *testing.T) {
+	recipe := &Recipe{
+		Servings:    2,
+		Ingredients: []string{"2 cups all-purpose flour", "", "2 eggs", "1 cup mystery relish", "salt to taste"},
+	}
+
+	estimate := EstimateNutrition(recipe, map[string]string{})
+
+	// 250 g of flour and 100 g of egg
+	flour, egg := nutrition.FindFood("all-purpose flour"), nutrition.FindFood("egg")
+	if total := flour.Per100g.Calories*2.5 + egg.Per100g.Calories; estimate.Total.Calories != total {
+		t.Errorf("got %v calories, want %v", estimate.Total.Calories, total)
+	}
+
+	if estimate.PerServing.Calories != estimate.Total.Calories/2 {
+		t.Errorf("got %v calories per serving, want half of %v", estimate.PerServing.Calories, estimate.Total.Calories)
+	}
+
+	if want := []string{"1 cup mystery relish"}; !reflect.DeepEqual(estimate.Unmatched, want) {
+		t.Errorf("unmatched: got %q, want %q", estimate.Unmatched, want)
+	}
+
+	if !estimate.CanLookUp {
+		t.Error("the relish hasn't been looked up yet")
+	}
+
+	// Once the LLM has been asked, its answer is used, and isn't asked for again
+	estimate = EstimateNutrition(recipe, map[string]string{nutrition.Key("mystery relish"): "tomato sauce"})
+
+	if len(estimate.Unmatched) != 0 || len(estimate.Counted) != 3 || estimate.CanLookUp {
+		t.Errorf("matched relish: got %+v", estimate)
+	}
+
+	// Including when it found nothing
+	estimate = EstimateNutrition(recipe, map[string]string{nutrition.Key("mystery relish"): ""})
+
+	if len(estimate.Unmatched) != 1 || estimate.CanLookUp {
+		t.Errorf("unmatchable relish: got %+v", estimate)
+	}
+}
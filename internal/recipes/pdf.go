@@ -0,0 +1,153 @@
+package recipes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Pages with less text than this are treated as scans
+const PDF_MIN_PAGE_TEXT = 40
+
+// Longer PDFs are whole cookbooks, which are better imported a recipe at a time
+const PDF_MAX_PAGES = 50
+
+var ErrUnreadablePDF = errors.New("we couldn't read that PDF")
+var ErrEmptyPDF = errors.New("we couldn't find any text or scanned pages in that PDF")
+var ErrPDFTooLong = fmt.Errorf("that PDF is too long, we can import PDFs of up to %d pages", PDF_MAX_PAGES)
+
+// PDFPage is what we could get out of a single page: its embedded text if it has a text layer,
+// and otherwise the page's scanned image, for previews and for the vision path.
+type PDFPage struct {
+	Number int
+	Text   string
+	Image  *SourceImage
+}
+
+func (p PDFPage) HasText() bool {
+	return len(strings.TrimSpace(p.Text)) >= PDF_MIN_PAGE_TEXT
+}
+
+// ReadPDF extracts every page of a PDF in pure Go. Only the pages without a text layer have
+// their images extracted, so a PDF that was typed up costs no more than its text.
+func ReadPDF(data []byte) (pages []PDFPage, err error) {
+	// The PDF parser panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("%w: %v", ErrUnreadablePDF, r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadablePDF, err)
+	}
+
+	if reader.NumPage() > PDF_MAX_PAGES {
+		return nil, ErrPDFTooLong
+	}
+
+	var scanned []string
+
+	for number := 1; number <= reader.NumPage(); number++ {
+		page := PDFPage{Number: number}
+
+		if p := reader.Page(number); !p.V.IsNull() {
+			// A page without extractable text is still worth trying as an image
+			page.Text, _ = p.GetPlainText(nil)
+		}
+
+		if !page.HasText() {
+			scanned = append(scanned, strconv.Itoa(number))
+		}
+
+		pages = append(pages, page)
+	}
+
+	if len(scanned) == 0 {
+		return pages, nil
+	}
+
+	images, err := pdfPageImages(data, scanned)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pages {
+		if image, ok := images[pages[i].Number]; ok {
+			pages[i].Image = &image
+		}
+	}
+
+	return pages, nil
+}
+
+// pdfPageImages returns the largest JPEG or PNG on each of the given pages. A page without a text
+// layer is almost always a scan or photo, so its largest image is the page itself and can go
+// straight to the model.
+func pdfPageImages(data []byte, pageNumbers []string) (map[int]SourceImage, error) {
+	images := map[int]SourceImage{}
+	sizes := map[int]int{}
+
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+
+	err := api.ExtractImages(bytes.NewReader(data), pageNumbers, func(extracted model.Image, _ bool, _ int) error {
+		contentType, ok := map[string]string{"jpg": "image/jpeg", "png": "image/png"}[extracted.FileType]
+		if !ok {
+			return nil
+		}
+
+		imageData, err := io.ReadAll(extracted)
+		if err != nil {
+			return err
+		}
+
+		// pdfcpu doesn't always know an image's size, but the image itself does
+		config, _, err := image.DecodeConfig(bytes.NewReader(imageData))
+		if err != nil || config.Width*config.Height <= sizes[extracted.PageNr] {
+			return nil
+		}
+
+		images[extracted.PageNr] = SourceImage{ContentType: contentType, Data: imageData}
+		sizes[extracted.PageNr] = config.Width * config.Height
+
+		return nil
+	}, conf)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadablePDF, err)
+	}
+
+	return images, nil
+}
+
+// SelectPDFPages keeps the pages whose numbers are listed. No selection means every page.
+func SelectPDFPages(pages []PDFPage, numbers []string) []PDFPage {
+	if len(numbers) == 0 {
+		return pages
+	}
+
+	var selected []PDFPage
+
+	for _, page := range pages {
+		for _, number := range numbers {
+			if n, err := strconv.Atoi(number); err == nil && n == page.Number {
+				selected = append(selected, page)
+				break
+			}
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,168 @@
+package recipes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+// testPDF builds a PDF with a page per text. A page whose text is empty is a scan instead:
+// a JPEG covering the page, with no text layer.
+func testPDF(texts ...string) []byte {
+	var scan bytes.Buffer
+	jpeg.Encode(&scan, image.NewGray(image.Rect(0, 0, 40, 40)), nil)
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // The page tree, once the pages' object numbers are known
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 40 /Height 40 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n%s\nendstream", scan.Len(), scan.String()),
+	}
+
+	var kids []string
+	for _, text := range texts {
+		content := fmt.Sprintf("BT /F1 12 Tf 20 150 Td (%s) Tj ET", text)
+		if text == "" {
+			content = "q 200 0 0 200 0 0 cm /Im1 Do Q"
+		}
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Contents %d 0 R /Resources << /Font << /F1 3 0 R >> /XObject << /Im1 4 0 R >> >> >>", len(objects)+2),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)-1))
+	}
+
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+
+	var offsets []int
+	for i, object := range objects {
+		offsets = append(offsets, pdf.Len())
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return pdf.Bytes()
+}
+
+func TestReadPDFExtractsImagesOnlyFromScans(t *testing.T) {
+	pages, err := ReadPDF(testPDF("Mix the flour and water, then leave it overnight to ferment", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2", len(pages))
+	}
+
+	if !pages[0].HasText() || !strings.Contains(pages[0].Text, "Mix the flour and water") {
+		t.Errorf("got text %q", pages[0].Text)
+	}
+
+	if pages[0].Image != nil {
+		t.Error("the page with text has an image")
+	}
+
+	if pages[1].HasText() {
+		t.Errorf("the scan has text %q", pages[1].Text)
+	}
+
+	if pages[1].Image == nil {
+		t.Fatal("the scan has no image")
+	}
+
+	if _, err := jpeg.Decode(bytes.NewReader(pages[1].Image.Data)); err != nil || pages[1].Image.ContentType != "image/jpeg" {
+		t.Errorf("the scan isn't a JPEG: %v", err)
+	}
+}
+
+func TestReadPDFRejectsLongPDFs(t *testing.T) {
+	texts := make([]string, PDF_MAX_PAGES)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("Page %d", i+1)
+	}
+
+	if pages, err := ReadPDF(testPDF(texts...)); err != nil || len(pages) != PDF_MAX_PAGES {
+		t.Fatalf("at the limit: got %d pages, %v", len(pages), err)
+	}
+
+	if _, err := ReadPDF(testPDF(append(texts, "One page too many")...)); !errors.Is(err, ErrPDFTooLong) {
+		t.Errorf("over the limit: got %v, want %v", err, ErrPDFTooLong)
+	}
+}
+
+func TestReadPDFRejectsGarbage(t *testing.T) {
+	if _, err := ReadPDF([]byte("not a pdf")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSourcePagesAreSavedAndOrphansDeleted(t *testing.T) {
+	repo := newTestRepository(t)
+
+	pages, err := ReadPDF(testPDF(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, err := repo.CreateSource(&Source{UserID: 1, ContentType: "application/pdf", Data: []byte("kept")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	abandoned, err := repo.CreateSource(&Source{UserID: 1, ContentType: "application/pdf", Data: []byte("abandoned")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, source := range []*Source{kept, abandoned} {
+		if err := repo.SaveSourcePages(source.ID, pages); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := repo.GetSourcePage(kept.ID, 1)
+	if err != nil || page == nil || !bytes.Equal(page.Image.Data, pages[0].Image.Data) {
+		t.Fatalf("saved page didn't load: %v %v", page, err)
+	}
+
+	if _, err := repo.CreateDraft(&Draft{UserID: 1, SourceID: &kept.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.db.MustExec("UPDATE recipe_sources SET created_at = datetime('now', '-2 days')")
+
+	deleted, err := repo.DeleteOrphanedSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted != 1 {
+		t.Errorf("deleted %d sources, want 1", deleted)
+	}
+
+	if source, _ := repo.GetSource(abandoned.ID); source != nil {
+		t.Error("the abandoned source wasn't deleted")
+	}
+
+	if pages, _ := repo.GetSourcePages(abandoned.ID); len(pages) != 0 {
+		t.Error("the abandoned source's pages weren't deleted")
+	}
+
+	if pages, _ := repo.GetSourcePages(kept.ID); len(pages) != 1 {
+		t.Error("the kept source's pages were deleted")
+	}
+}
@@ -3,17 +3,45 @@ package recipes
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"sourdough/internal/database"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
 )
 
 type Repository struct {
-	db *database.DB
+	db              *database.DB
+	listeners       []func(recipe *Recipe)
+	deleteListeners []func(recipe *Recipe)
 }
 
 func NewRepository(db *database.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// OnSave registers a function to call whenever a recipe is created or updated, e.g. to keep
+// an index up to date. Listeners are called synchronously, so slow work belongs elsewhere.
+func (repo *Repository) OnSave(listener func(recipe *Recipe)) {
+	repo.listeners = append(repo.listeners, listener)
+}
+
+// OnDelete registers a function to call with each recipe after it's deleted. Like OnSave's
+// listeners, they're called synchronously.
+func (repo *Repository) OnDelete(listener func(recipe *Recipe)) {
+	repo.deleteListeners = append(repo.deleteListeners, listener)
+}
+
+func (repo *Repository) saved(recipe *Recipe, err error) (*Recipe, error) {
+	if err == nil && recipe != nil {
+		for _, listener := range repo.listeners {
+			listener(recipe)
+		}
+	}
+
+	return recipe, err
+}
+
 func (repo *Repository) Get(id int) (*Recipe, error) {
 	var recipe Recipe
 
@@ -30,7 +58,64 @@ func (repo *Repository) Get(id int) (*Recipe, error) {
 	return &recipe, nil
 }
 
+// GetByTitle finds one of the user's recipes by its title, ignoring case.
+func (repo *Repository) GetByTitle(userID int, title string) (*Recipe, error) {
+	var recipe Recipe
+
+	err := repo.db.Get(&recipe, "SELECT * FROM recipes WHERE user_id = ? AND title = ? COLLATE NOCASE ORDER BY id LIMIT 1", userID, strings.TrimSpace(title))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &recipe, nil
+}
+
+// Expand returns a copy of the recipe with the other recipes it uses as components
+// written out in place, for showing or printing it in full.
+func (repo *Repository) Expand(recipe *Recipe) (*Recipe, error) {
+	return expandComponents(recipe, repo.Get, map[int]bool{})
+}
+
 func (repo *Repository) Delete(id int) (bool, error) {
+	recipe, err := repo.Get(id)
+	if err != nil || recipe == nil {
+		return false, err
+	}
+
+	if _, err := repo.db.Exec("DELETE FROM recipe_photos WHERE recipe_id = ?", id); err != nil {
+		return false, err
+	}
+
+	if _, err := repo.db.Exec("DELETE FROM recipe_embeddings WHERE recipe_id = ?", id); err != nil {
+		return false, err
+	}
+
+	if _, err := repo.db.Exec("DELETE FROM recipe_similarities WHERE recipe_id = ? OR similar_id = ?", id, id); err != nil {
+		return false, err
+	}
+
+	if _, err := repo.db.Exec("DELETE FROM cook_log WHERE recipe_id = ?", id); err != nil {
+		return false, err
+	}
+
+	if _, err := repo.db.Exec("DELETE FROM collection_recipes WHERE recipe_id = ?", id); err != nil {
+		return false, err
+	}
+
+	// Recipes and drafts adapted from this one stand on their own
+	if _, err := repo.db.Exec("UPDATE recipes SET parent_id = NULL WHERE parent_id = ?", id); err != nil {
+		return false, err
+	}
+
+	if _, err := repo.db.Exec("UPDATE recipe_drafts SET parent_id = NULL WHERE parent_id = ?", id); err != nil {
+		return false, err
+	}
+
 	result, err := repo.db.Exec("DELETE FROM recipes WHERE id = ?", id)
 
 	if err != nil {
@@ -47,13 +132,24 @@ func (repo *Repository) Delete(id int) (bool, error) {
 		return false, err
 	}
 
+	if rows > 0 {
+		// Recipes that used it as a component no longer have its ingredients
+		if err := repo.redetectDependents(recipe); err != nil {
+			return true, err
+		}
+
+		for _, listener := range repo.deleteListeners {
+			listener(recipe)
+		}
+	}
+
 	return rows > 0, nil
 }
 
 func (repo *Repository) GetForUser(userID int) ([]*Recipe, error) {
 	var recipes []*Recipe
 
-	err := repo.db.Select(&recipes, "SELECT * FROM recipes WHERE user_id = ?", userID)
+	err := repo.db.Select(&recipes, "SELECT * FROM recipes WHERE user_id = ? ORDER BY id DESC", userID)
 
 	if err != nil {
 		return nil, err
@@ -62,10 +158,203 @@ func (repo *Repository) GetForUser(userID int) ([]*Recipe, error) {
 	return recipes, nil
 }
 
-func (repo *Repository) Search(userID int, searchTerm string) ([]*Recipe, error) {
+// Sort keys for the library. Recipes without a parsable time sort after those with one.
+var librarySorts = map[string]struct {
+	key       string
+	direction string
+}{
+	SortRecent: {key: "id", direction: "DESC"},
+	SortTitle:  {key: "title COLLATE NOCASE", direction: "ASC"},
+	SortTime:   {key: "COALESCE(total_minutes, 1000000)", direction: "ASC"},
+
+	// Recipes that were never cooked sort last
+	SortLastCooked:  {key: "COALESCE(last_cooked_on, '')", direction: "DESC"},
+	SortTimesCooked: {key: "times_cooked", direction: "DESC"},
+}
+
+// GetLibrary returns a page of the user's recipes, sorted and filtered as asked.
+func (repo *Repository) GetLibrary(userID int, query LibraryQuery) (*LibraryPage, error) {
+	sort, ok := librarySorts[query.Sort]
+	if !ok {
+		sort = librarySorts[SortRecent]
+	}
+
+	where := []string{"user_id = ?"}
+	args := []any{userID}
+
+	if query.MaxMinutes > 0 {
+		where = append(where, "total_minutes <= ?")
+		args = append(args, query.MaxMinutes)
+	}
+	if query.MaxIngredients > 0 {
+		where = append(where, "number_of_ingredients <= ?")
+		args = append(args, query.MaxIngredients)
+	}
+	if query.MinServings > 0 {
+		where = append(where, "servings >= ?")
+		args = append(args, query.MinServings)
+	}
+	if query.Favorites {
+		where = append(where, "favorite = 1")
+	}
+
+	dietaryWhere, dietaryArgs := query.Dietary.conditions()
+	where = append(where, dietaryWhere...)
+	args = append(args, dietaryArgs...)
+
+	comparison := ">"
+	if sort.direction == "DESC" {
+		comparison = "<"
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", sort.key, comparison, sort.key, comparison))
+		args = append(args, cursor.Key, cursor.Key, cursor.ID)
+	}
+
+	// One extra row says whether there's another page
+	args = append(args, LIBRARY_PAGE_SIZE+1)
+
+	var rows []struct {
+		Recipe
+		SortKey any `db:"sort_key"`
+	}
+
+	err := repo.db.Select(
+		&rows,
+		fmt.Sprintf(
+			"SELECT *, %s AS sort_key FROM recipes WHERE %s ORDER BY %s %s, id %s LIMIT ?",
+			sort.key, strings.Join(where, " AND "), sort.key, sort.direction, sort.direction,
+		),
+		args...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page := &LibraryPage{Recipes: []*Recipe{}}
+
+	for i := range rows[:min(len(rows), LIBRARY_PAGE_SIZE)] {
+		page.Recipes = append(page.Recipes, &rows[i].Recipe)
+	}
+
+	if len(rows) > LIBRARY_PAGE_SIZE {
+		last := rows[LIBRARY_PAGE_SIZE-1]
+		page.NextCursor = encodeCursor(libraryCursor{Key: last.SortKey, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+// BackfillTimes parses the times of recipes saved before times were stored in minutes, and
+// clears totals that were saved when only some of their parts were known.
+func (repo *Repository) BackfillTimes() (int, error) {
+	result, err := repo.db.Exec(
+		`UPDATE recipes SET total_minutes = NULL WHERE total_minutes IS NOT NULL
+		AND (prep_minutes IS NULL OR cook_minutes IS NULL) AND (active_minutes IS NULL OR inactive_minutes IS NULL)`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	cleared, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	var recipes []*Recipe
+
+	err = repo.db.Select(&recipes, "SELECT * FROM recipes WHERE prep_minutes IS NULL AND cook_minutes IS NULL AND (prep_time != '' OR cook_time != '')")
+
+	if err != nil {
+		return 0, err
+	}
+
+	updated := int(cleared)
+
+	for _, recipe := range recipes {
+		prepTime, cookTime := recipe.PrepTime, recipe.CookTime
+		recipe.normalizeTimes()
+
+		if recipe.PrepMinutes == nil && recipe.CookMinutes == nil && recipe.PrepTime == prepTime && recipe.CookTime == cookTime {
+			continue
+		}
+
+		_, err := repo.db.NamedExec(
+			"UPDATE recipes SET prep_time = :prep_time, cook_time = :cook_time, prep_minutes = :prep_minutes, cook_minutes = :cook_minutes, total_minutes = :total_minutes WHERE id = :id",
+			recipe,
+		)
+		if err != nil {
+			return updated, err
+		}
+
+		updated++
+	}
+
+	return updated, nil
+}
+
+// BackfillDietary detects the labels of recipes saved before they were detected.
+func (repo *Repository) BackfillDietary() (int, error) {
+	var recipes []*Recipe
+
+	err := repo.db.Select(&recipes, "SELECT * FROM recipes WHERE dietary_detected IS NULL")
+
+	if err != nil {
+		return 0, err
+	}
+
+	for i, recipe := range recipes {
+		if err := repo.detectDietary(recipe); err != nil {
+			return i, err
+		}
+
+		if err := repo.SetDietaryDetected(recipe.ID, recipe.DietaryDetected); err != nil {
+			return i, err
+		}
+	}
+
+	return len(recipes), nil
+}
+
+// Search finds recipes by title, or ranks them by how many of their ingredients the user has on
+// hand when the query lists ingredients.
+func (repo *Repository) Search(userID int, query SearchQuery) ([]*SearchResult, error) {
+	if len(query.Ingredients) > 0 {
+		// Ingredients are free text, so they can only be matched once they're parsed
+		recipes, err := repo.GetForUser(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		recipes = query.Dietary.Filter(recipes)
+
+		// Recipes that use others as components need their ingredients too
+		byID := map[int]*Recipe{}
+		for _, recipe := range recipes {
+			byID[recipe.ID] = recipe
+		}
+
+		lookup := func(id int) (*Recipe, error) { return byID[id], nil }
+
+		for i, recipe := range recipes {
+			if recipes[i], err = expandComponents(recipe, lookup, map[int]bool{}); err != nil {
+				return nil, err
+			}
+		}
+
+		return rankByIngredients(recipes, query.Ingredients, query.Staples), nil
+	}
+
 	var recipes []*Recipe
 
-	likeClause := "%" + searchTerm + "%"
+	likeClause := "%" + query.Term + "%"
 
 	err := repo.db.Select(&recipes, "SELECT * FROM recipes WHERE user_id = ? and title LIKE ?", userID, likeClause)
 
@@ -73,14 +362,96 @@ func (repo *Repository) Search(userID int, searchTerm string) ([]*Recipe, error)
 		return nil, err
 	}
 
+	recipes = query.Dietary.Filter(recipes)
+
+	results := make([]*SearchResult, len(recipes))
+	for i, recipe := range recipes {
+		results[i] = &SearchResult{Recipe: recipe}
+	}
+
+	return results, nil
+}
+
+// GetVariations lists the recipes that were adapted from this one, oldest first.
+func (repo *Repository) GetVariations(recipeID int) ([]*Recipe, error) {
+	var recipes []*Recipe
+
+	err := repo.db.Select(&recipes, "SELECT * FROM recipes WHERE parent_id = ? ORDER BY id", recipeID)
+
+	if err != nil {
+		return nil, err
+	}
+
 	return recipes, nil
 }
 
+// GetPantryStaples returns the user's pantry staples, or the defaults if they've never saved
+// their own. A saved list can be empty, for users who don't want anything treated as on hand.
+func (repo *Repository) GetPantryStaples(userID int) ([]string, error) {
+	staples := []string{}
+
+	err := repo.db.Select(&staples, "SELECT name FROM pantry_staples WHERE user_id = ? ORDER BY name", userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(staples) > 0 {
+		return staples, nil
+	}
+
+	var saved bool
+	if err := repo.db.Get(&saved, "SELECT EXISTS (SELECT 1 FROM pantries WHERE user_id = ?)", userID); err != nil {
+		return nil, err
+	}
+
+	if !saved {
+		return DEFAULT_PANTRY_STAPLES, nil
+	}
+
+	return staples, nil
+}
+
+func (repo *Repository) SetPantryStaples(userID int, staples []string) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM pantry_staples WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT OR REPLACE INTO pantries (user_id, updated_at) VALUES (?, CURRENT_TIMESTAMP)", userID); err != nil {
+		return err
+	}
+
+	for _, staple := range staples {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO pantry_staples (user_id, name) VALUES (?, ?)", userID, staple); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (repo *Repository) Create(recipe *Recipe) (*Recipe, error) {
+	recipe.normalizeTimes()
+
+	if err := repo.resolveComponents(recipe); err != nil {
+		return nil, err
+	}
+
+	if err := repo.detectDietary(recipe); err != nil {
+		return nil, err
+	}
 
 	// Use SQLx's NamedExec to automatically map struct fields to query parameters
 	result, err := repo.db.NamedExec(
-		"INSERT INTO recipes (user_id, title, ingredients, number_of_ingredients, directions, notes, prep_time, cook_time, servings) VALUES (:user_id, :title, :ingredients, :number_of_ingredients, :directions, :notes, :prep_time, :cook_time, :servings)",
+		"INSERT INTO recipes (user_id, title, ingredients, number_of_ingredients, directions, ingredient_sections, direction_sections, notes, prep_time, cook_time, servings, source_id, prep_minutes, cook_minutes, active_minutes, inactive_minutes, total_minutes, dietary_detected, parent_id) VALUES (:user_id, :title, :ingredients, :number_of_ingredients, :directions, :ingredient_sections, :direction_sections, :notes, :prep_time, :cook_time, :servings, :source_id, :prep_minutes, :cook_minutes, :active_minutes, :inactive_minutes, :total_minutes, :dietary_detected, :parent_id)",
 		recipe,
 	)
 	if err != nil {
@@ -93,19 +464,760 @@ func (repo *Repository) Create(recipe *Recipe) (*Recipe, error) {
 	}
 
 	// Fetch and return the inserted recipe
-	return repo.Get(int(id))
+	return repo.saved(repo.Get(int(id)))
 }
 
+// Update saves the recipe over the one with its ID, and returns nil if that recipe doesn't
+// exist or belongs to another user.
 func (repo *Repository) Update(recipe *Recipe) (*Recipe, error) {
+	recipe.normalizeTimes()
+
+	if err := repo.resolveComponents(recipe); err != nil {
+		return nil, err
+	}
+
+	if err := repo.detectDietary(recipe); err != nil {
+		return nil, err
+	}
+
 	// Use SQLx's NamedExec to automatically map struct fields to query parameters
-	_, err := repo.db.NamedExec(
-		"UPDATE recipes SET title = :title, ingredients = :ingredients, number_of_ingredients = :number_of_ingredients, directions = :directions, notes = :notes, prep_time = :prep_time, cook_time = :cook_time, servings = :servings WHERE id = :id",
+	result, err := repo.db.NamedExec(
+		"UPDATE recipes SET title = :title, ingredients = :ingredients, number_of_ingredients = :number_of_ingredients, directions = :directions, ingredient_sections = :ingredient_sections, direction_sections = :direction_sections, notes = :notes, prep_time = :prep_time, cook_time = :cook_time, servings = :servings, prep_minutes = :prep_minutes, cook_minutes = :cook_minutes, active_minutes = :active_minutes, inactive_minutes = :inactive_minutes, total_minutes = :total_minutes, dietary_detected = :dietary_detected, updated_at = CURRENT_TIMESTAMP WHERE id = :id AND user_id = :user_id",
 		recipe,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Another user's recipe is as good as missing
+	if updated, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if updated == 0 {
+		return nil, nil
+	}
+
+	if err := repo.redetectDependents(recipe); err != nil {
+		return nil, err
+	}
+
 	// Fetch and return the inserted recipe
-	return repo.Get(recipe.ID)
+	return repo.saved(repo.Get(recipe.ID))
+}
+
+func (repo *Repository) GetDraft(id int) (*Draft, error) {
+	var draft Draft
+
+	err := repo.db.Get(&draft, "SELECT * FROM recipe_drafts WHERE id = ?", id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &draft, nil
+}
+
+func (repo *Repository) GetDraftsForUser(userID int) ([]*Draft, error) {
+	var drafts []*Draft
+
+	err := repo.db.Select(&drafts, "SELECT * FROM recipe_drafts WHERE user_id = ? ORDER BY created_at DESC", userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return drafts, nil
+}
+
+func (repo *Repository) CreateDraft(draft *Draft) (*Draft, error) {
+	result, err := repo.db.NamedExec(
+		"INSERT INTO recipe_drafts (user_id, recipe, source_text, source_images, source_id, parent_id, transform) VALUES (:user_id, :recipe, :source_text, :source_images, :source_id, :parent_id, :transform)",
+		draft,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetDraft(int(id))
+}
+
+func (repo *Repository) DeleteDraft(id int) (bool, error) {
+	result, err := repo.db.Exec("DELETE FROM recipe_drafts WHERE id = ?", id)
+
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+func (repo *Repository) GetSource(id int) (*Source, error) {
+	var source Source
+
+	err := repo.db.Get(&source, "SELECT * FROM recipe_sources WHERE id = ?", id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &source, nil
+}
+
+func (repo *Repository) CreateSource(source *Source) (*Source, error) {
+	result, err := repo.db.NamedExec(
+		"INSERT INTO recipe_sources (user_id, filename, content_type, data) VALUES (:user_id, :filename, :content_type, :data)",
+		source,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetSource(int(id))
+}
+
+// GetSourcePages returns the pages saved for a PDF source, in order. Sources saved before
+// pages were kept have none.
+func (repo *Repository) GetSourcePages(sourceID int) ([]PDFPage, error) {
+	var rows []SourcePage
+
+	if err := repo.db.Select(&rows, "SELECT * FROM recipe_source_pages WHERE source_id = ? ORDER BY number", sourceID); err != nil {
+		return nil, err
+	}
+
+	pages := make([]PDFPage, len(rows))
+	for i, row := range rows {
+		pages[i] = row.PDFPage()
+	}
+
+	return pages, nil
+}
+
+// GetSourcePage returns one saved page of a PDF source, or nil if there isn't one.
+func (repo *Repository) GetSourcePage(sourceID int, number int) (*PDFPage, error) {
+	var row SourcePage
+
+	err := repo.db.Get(&row, "SELECT * FROM recipe_source_pages WHERE source_id = ? AND number = ?", sourceID, number)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	page := row.PDFPage()
+	return &page, nil
+}
+
+func (repo *Repository) SaveSourcePages(sourceID int, pages []PDFPage) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	for _, page := range pages {
+		row := SourcePage{SourceID: sourceID, Number: page.Number, Text: page.Text}
+		if page.Image != nil {
+			row.ContentType, row.Image = page.Image.ContentType, page.Image.Data
+		}
+
+		_, err := tx.NamedExec(
+			"INSERT OR REPLACE INTO recipe_source_pages (source_id, number, text, content_type, image) VALUES (:source_id, :number, :text, :content_type, :image)",
+			&row,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteOrphanedSources deletes the sources no recipe or draft came from, like a PDF whose pages
+// were never picked, once they're older than a day so imports in progress are left alone.
+func (repo *Repository) DeleteOrphanedSources() (int64, error) {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		DELETE FROM recipe_sources
+		WHERE created_at < datetime('now', '-1 day')
+		AND id NOT IN (SELECT source_id FROM recipes WHERE source_id IS NOT NULL)
+		AND id NOT IN (SELECT source_id FROM recipe_drafts WHERE source_id IS NOT NULL)
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM recipe_source_pages WHERE source_id NOT IN (SELECT id FROM recipe_sources)"); err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+func (repo *Repository) GetPhoto(id int) (*Photo, error) {
+	var photo Photo
+
+	err := repo.db.Get(&photo, "SELECT * FROM recipe_photos WHERE id = ?", id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &photo, nil
+}
+
+// GetPhotoIDs lists a recipe's photos in order without loading the images themselves.
+func (repo *Repository) GetPhotoIDs(recipeID int) ([]int, error) {
+	var ids []int
+
+	err := repo.db.Select(&ids, "SELECT id FROM recipe_photos WHERE recipe_id = ? ORDER BY position, id", recipeID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (repo *Repository) CreatePhoto(photo *Photo) (*Photo, error) {
+	result, err := repo.db.NamedExec(
+		"INSERT INTO recipe_photos (recipe_id, position, content_type, data) VALUES (:recipe_id, :position, :content_type, :data)",
+		photo,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetPhoto(int(id))
+}
+
+// GetCookLog lists the times a recipe was cooked, most recent first, without their photos.
+func (repo *Repository) GetCookLog(recipeID int) ([]*CookLogEntry, error) {
+	var entries []*CookLogEntry
+
+	err := repo.db.Select(
+		&entries,
+		"SELECT id, recipe_id, cooked_on, rating, notes, tweaks, photo_content_type, created_at FROM cook_log WHERE recipe_id = ? ORDER BY cooked_on DESC, id DESC",
+		recipeID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (repo *Repository) GetCookLogEntry(id int) (*CookLogEntry, error) {
+	var entry CookLogEntry
+
+	err := repo.db.Get(&entry, "SELECT * FROM cook_log WHERE id = ?", id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// AddToCookLog records a time the recipe was cooked and updates its cooking stats.
+func (repo *Repository) AddToCookLog(entry *CookLogEntry) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	_, err = tx.NamedExec(
+		"INSERT INTO cook_log (recipe_id, cooked_on, rating, notes, tweaks, photo_content_type, photo) VALUES (:recipe_id, :cooked_on, :rating, :notes, :tweaks, :photo_content_type, :photo)",
+		entry,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := updateCookStats(tx, entry.RecipeID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *Repository) DeleteFromCookLog(entry *CookLogEntry) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM cook_log WHERE id = ?", entry.ID); err != nil {
+		return err
+	}
+
+	if err := updateCookStats(tx, entry.RecipeID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateCookStats keeps the copies of the cooking stats on the recipe in step with its log,
+// so the library can sort by them.
+func updateCookStats(tx *sqlx.Tx, recipeID int) error {
+	_, err := tx.Exec(
+		`UPDATE recipes SET
+			times_cooked = (SELECT COUNT(*) FROM cook_log WHERE recipe_id = recipes.id),
+			last_cooked_on = (SELECT MAX(cooked_on) FROM cook_log WHERE recipe_id = recipes.id)
+		WHERE id = ?`,
+		recipeID,
+	)
+
+	return err
+}
+
+func (repo *Repository) SetFavorite(recipeID int, favorite bool) error {
+	_, err := repo.db.Exec("UPDATE recipes SET favorite = ? WHERE id = ?", favorite, recipeID)
+	return err
+}
+
+func (repo *Repository) SetDietaryDetected(recipeID int, labels []string) error {
+	_, err := repo.db.Exec("UPDATE recipes SET dietary_detected = ? WHERE id = ?", database.JSONArray[string](labels), recipeID)
+	return err
+}
+
+// SetDietaryOverrides replaces the labels the user set themselves, which win over detected ones.
+func (repo *Repository) SetDietaryOverrides(recipeID int, overrides map[string]bool) error {
+	_, err := repo.db.Exec("UPDATE recipes SET dietary_overrides = ? WHERE id = ?", database.JSONObject[map[string]bool]{Data: overrides}, recipeID)
+	return err
+}
+
+// GetCollections lists the user's collections by name, with how many recipes are in each.
+func (repo *Repository) GetCollections(userID int) ([]*Collection, error) {
+	var collections []*Collection
+
+	err := repo.db.Select(
+		&collections,
+		`SELECT c.*, (SELECT COUNT(*) FROM collection_recipes WHERE collection_id = c.id) AS recipe_count
+		FROM collections c WHERE c.user_id = ? ORDER BY c.name COLLATE NOCASE`,
+		userID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+func (repo *Repository) GetCollection(id int) (*Collection, error) {
+	var collection Collection
+
+	err := repo.db.Get(&collection, "SELECT * FROM collections WHERE id = ?", id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &collection, nil
+}
+
+func (repo *Repository) CreateCollection(collection *Collection) (*Collection, error) {
+	result, err := repo.db.NamedExec("INSERT INTO collections (user_id, name) VALUES (:user_id, :name)", collection)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetCollection(int(id))
+}
+
+func (repo *Repository) RenameCollection(collection *Collection) error {
+	_, err := repo.db.NamedExec("UPDATE collections SET name = :name WHERE id = :id", collection)
+	return err
+}
+
+// DeleteCollection deletes the collection but not the recipes in it.
+func (repo *Repository) DeleteCollection(id int) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM collection_recipes WHERE collection_id = ?", id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM collections WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetCollectionRecipes returns the recipes in a collection, in the order the user put them.
+func (repo *Repository) GetCollectionRecipes(collectionID int) ([]*Recipe, error) {
+	var recipes []*Recipe
+
+	err := repo.db.Select(
+		&recipes,
+		"SELECT r.* FROM collection_recipes cr JOIN recipes r ON r.id = cr.recipe_id WHERE cr.collection_id = ? ORDER BY cr.position, r.id",
+		collectionID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return recipes, nil
+}
+
+// GetRecipeCollections lists all of the user's collections, marking the ones the recipe is in.
+func (repo *Repository) GetRecipeCollections(userID int, recipeID int) ([]*RecipeCollection, error) {
+	var collections []*RecipeCollection
+
+	err := repo.db.Select(
+		&collections,
+		`SELECT c.*, EXISTS (SELECT 1 FROM collection_recipes WHERE collection_id = c.id AND recipe_id = ?) AS contains
+		FROM collections c WHERE c.user_id = ? ORDER BY c.name COLLATE NOCASE`,
+		recipeID,
+		userID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// SetRecipeCollections puts the recipe in exactly the given collections of the user's, adding
+// it to the end of the ones it's new to.
+func (repo *Repository) SetRecipeCollections(userID int, recipeID int, collectionIDs []int) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	query, args, err := sqlx.In(
+		"DELETE FROM collection_recipes WHERE recipe_id = ? AND collection_id IN (SELECT id FROM collections WHERE user_id = ?) AND collection_id NOT IN (?)",
+		recipeID, userID, append([]int{0}, collectionIDs...),
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	for _, collectionID := range collectionIDs {
+		_, err := tx.Exec(
+			`INSERT OR IGNORE INTO collection_recipes (collection_id, recipe_id, position)
+			SELECT id, ?, (SELECT COALESCE(MAX(position) + 1, 0) FROM collection_recipes WHERE collection_id = collections.id)
+			FROM collections WHERE id = ? AND user_id = ?`,
+			recipeID, collectionID, userID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AddToCollection puts the recipe at the end of the collection, if it isn't in it already.
+func (repo *Repository) AddToCollection(collectionID int, recipeID int) error {
+	_, err := repo.db.Exec(
+		"INSERT OR IGNORE INTO collection_recipes (collection_id, recipe_id, position) SELECT ?, ?, COALESCE(MAX(position) + 1, 0) FROM collection_recipes WHERE collection_id = ?",
+		collectionID, recipeID, collectionID,
+	)
+
+	return err
+}
+
+func (repo *Repository) RemoveFromCollection(collectionID int, recipeID int) error {
+	_, err := repo.db.Exec("DELETE FROM collection_recipes WHERE collection_id = ? AND recipe_id = ?", collectionID, recipeID)
+	return err
+}
+
+// ReorderCollection puts the collection's recipes in the given order. Recipes that aren't
+// listed keep their old positions, so a stale page can't drop anything.
+func (repo *Repository) ReorderCollection(collectionID int, recipeIDs []int) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	for position, recipeID := range recipeIDs {
+		if _, err := tx.Exec("UPDATE collection_recipes SET position = ? WHERE collection_id = ? AND recipe_id = ?", position, collectionID, recipeID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetIngredientFoods returns the foods the LLM matched to the user's ingredient names before,
+// keyed by nutrition.Key. An empty food means the LLM found no match. Like traits, each user
+// has their own.
+func (repo *Repository) GetIngredientFoods(userID int, names []string) (map[string]string, error) {
+	foods := map[string]string{}
+
+	if len(names) == 0 {
+		return foods, nil
+	}
+
+	query, args, err := sqlx.In("SELECT name, food FROM user_ingredient_foods WHERE user_id = ? AND name IN (?)", userID, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Name string `db:"name"`
+		Food string `db:"food"`
+	}
+
+	if err := repo.db.Select(&rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		foods[row.Name] = row.Food
+	}
+
+	return foods, nil
+}
+
+func (repo *Repository) SaveIngredientFoods(userID int, foods map[string]string) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	for name, food := range foods {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO user_ingredient_foods (user_id, name, food) VALUES (?, ?, ?)", userID, name, food); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetIngredientTraits returns the traits the LLM found in the user's ingredient names before,
+// keyed by nutrition.Key. No traits means the LLM found none. Each user has their own, since
+// what the LLM says about a name depends on what it was asked, which is up to the user.
+func (repo *Repository) GetIngredientTraits(userID int, names []string) (map[string][]string, error) {
+	traits := map[string][]string{}
+
+	if len(names) == 0 {
+		return traits, nil
+	}
+
+	query, args, err := sqlx.In("SELECT name, traits FROM user_ingredient_traits WHERE user_id = ? AND name IN (?)", userID, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Name   string                     `db:"name"`
+		Traits database.JSONArray[string] `db:"traits"`
+	}
+
+	if err := repo.db.Select(&rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		traits[row.Name] = row.Traits
+	}
+
+	return traits, nil
+}
+
+func (repo *Repository) SaveIngredientTraits(userID int, traits map[string][]string) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	for name, found := range traits {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO user_ingredient_traits (user_id, name, traits) VALUES (?, ?, ?)", userID, name, database.JSONArray[string](found)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetEmbeddings returns the stored embeddings of the user's recipes, keyed by recipe ID.
+func (repo *Repository) GetEmbeddings(userID int) (map[int]*Embedding, error) {
+	var embeddings []*Embedding
+
+	err := repo.db.Select(&embeddings, "SELECT e.* FROM recipe_embeddings e JOIN recipes r ON r.id = e.recipe_id WHERE r.user_id = ?", userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byRecipe := make(map[int]*Embedding, len(embeddings))
+	for _, embedding := range embeddings {
+		byRecipe[embedding.RecipeID] = embedding
+	}
+
+	return byRecipe, nil
+}
+
+func (repo *Repository) SaveEmbedding(embedding *Embedding) error {
+	_, err := repo.db.NamedExec(
+		`INSERT INTO recipe_embeddings (recipe_id, model, content_hash, vector, updated_at) VALUES (:recipe_id, :model, :content_hash, :vector, CURRENT_TIMESTAMP)
+		ON CONFLICT (recipe_id) DO UPDATE SET model = excluded.model, content_hash = excluded.content_hash, vector = excluded.vector, updated_at = excluded.updated_at`,
+		embedding,
+	)
+
+	return err
+}
+
+func (repo *Repository) GetUserIDsWithRecipes() ([]int, error) {
+	var userIDs []int
+
+	err := repo.db.Select(&userIDs, "SELECT DISTINCT user_id FROM recipes")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// GetSimilarRecipes returns the recipes most like the given one, best match first.
+func (repo *Repository) GetSimilarRecipes(recipeID int) ([]*Recipe, error) {
+	var recipes []*Recipe
+
+	err := repo.db.Select(&recipes, "SELECT r.* FROM recipe_similarities s JOIN recipes r ON r.id = s.similar_id WHERE s.recipe_id = ? ORDER BY s.score DESC", recipeID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return recipes, nil
+}
+
+// GetSimilarityFingerprint returns the fingerprint of the recipes the user's similarities were
+// last computed from, or "" if they never have been.
+func (repo *Repository) GetSimilarityFingerprint(userID int) (string, error) {
+	var fingerprint string
+
+	err := repo.db.Get(&fingerprint, "SELECT fingerprint FROM recipe_similarity_fingerprints WHERE user_id = ?", userID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	return fingerprint, nil
+}
+
+// ReplaceSimilarRecipes swaps out all of a user's similarities for a freshly computed set, and
+// records the fingerprint of the recipes they were computed from.
+func (repo *Repository) ReplaceSimilarRecipes(userID int, similarities []Similarity, fingerprint string) error {
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM recipe_similarities WHERE recipe_id IN (SELECT id FROM recipes WHERE user_id = ?)", userID); err != nil {
+		return err
+	}
+
+	for _, similarity := range similarities {
+		if _, err := tx.NamedExec("INSERT INTO recipe_similarities (recipe_id, similar_id, score) VALUES (:recipe_id, :similar_id, :score)", similarity); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("INSERT OR REPLACE INTO recipe_similarity_fingerprints (user_id, fingerprint) VALUES (?, ?)", userID, fingerprint); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
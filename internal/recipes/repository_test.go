@@ -0,0 +1,164 @@
+package recipes
+
+import (
+	"path/filepath"
+	"reflect"
+	"sourdough/internal/database"
+	"testing"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepository(db)
+}
+
+func TestUpdateOnlyChangesTheOwnersRecipe(t *testing.T) {
+	repo := newTestRepository(t)
+
+	recipe, err := repo.Create(&Recipe{UserID: 1, Title: "Toast", Ingredients: []string{"bread"}, Directions: []string{"Toast it."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := *recipe
+	forged.UserID = 2
+	forged.Title = "Not your toast"
+
+	if updated, err := repo.Update(&forged); err != nil || updated != nil {
+		t.Fatalf("another user's update: got %v, %v", updated, err)
+	}
+
+	if saved, _ := repo.Get(recipe.ID); saved.Title != "Toast" {
+		t.Errorf("another user renamed the recipe to %q", saved.Title)
+	}
+
+	recipe.Title = "Better toast"
+	if updated, err := repo.Update(recipe); err != nil || updated == nil || updated.Title != "Better toast" {
+		t.Errorf("the owner's update: got %v, %v", updated, err)
+	}
+}
+
+func collectionRecipeIDs(t *testing.T, repo *Repository, collectionID int) []int {
+	t.Helper()
+
+	recipes, err := repo.GetCollectionRecipes(collectionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []int{}
+	for _, recipe := range recipes {
+		ids = append(ids, recipe.ID)
+	}
+
+	return ids
+}
+
+func TestSetRecipeCollectionsIgnoresOtherUsersCollections(t *testing.T) {
+	repo := newTestRepository(t)
+
+	recipe, _ := repo.Create(&Recipe{UserID: 1, Title: "Toast", Ingredients: []string{"bread"}, Directions: []string{"Toast it."}})
+	theirRecipe, _ := repo.Create(&Recipe{UserID: 2, Title: "Jam", Ingredients: []string{"plums"}, Directions: []string{"Boil them."}})
+
+	mine, _ := repo.CreateCollection(&Collection{UserID: 1, Name: "Breakfast"})
+	theirs, _ := repo.CreateCollection(&Collection{UserID: 2, Name: "Preserves"})
+
+	if err := repo.AddToCollection(theirs.ID, theirRecipe.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.SetRecipeCollections(1, recipe.ID, []int{mine.ID, theirs.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := collectionRecipeIDs(t, repo, mine.ID); !reflect.DeepEqual(got, []int{recipe.ID}) {
+		t.Errorf("own collection: got %v", got)
+	}
+
+	if got := collectionRecipeIDs(t, repo, theirs.ID); !reflect.DeepEqual(got, []int{theirRecipe.ID}) {
+		t.Errorf("another user's collection: got %v", got)
+	}
+
+	// Clearing the collections of a recipe only takes it out of the user's own
+	if err := repo.SetRecipeCollections(1, theirRecipe.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := collectionRecipeIDs(t, repo, theirs.ID); !reflect.DeepEqual(got, []int{theirRecipe.ID}) {
+		t.Errorf("another user's collection after clearing: got %v", got)
+	}
+
+	if err := repo.SetRecipeCollections(1, recipe.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := collectionRecipeIDs(t, repo, mine.ID); len(got) != 0 {
+		t.Errorf("own collection after clearing: got %v", got)
+	}
+}
+
+func TestReorderCollectionIgnoresRecipesNotInIt(t *testing.T) {
+	repo := newTestRepository(t)
+
+	toast, _ := repo.Create(&Recipe{UserID: 1, Title: "Toast", Ingredients: []string{"bread"}, Directions: []string{"Toast it."}})
+	eggs, _ := repo.Create(&Recipe{UserID: 1, Title: "Eggs", Ingredients: []string{"eggs"}, Directions: []string{"Scramble them."}})
+	jam, _ := repo.Create(&Recipe{UserID: 2, Title: "Jam", Ingredients: []string{"plums"}, Directions: []string{"Boil them."}})
+
+	mine, _ := repo.CreateCollection(&Collection{UserID: 1, Name: "Breakfast"})
+	theirs, _ := repo.CreateCollection(&Collection{UserID: 2, Name: "Preserves"})
+
+	for _, recipe := range []*Recipe{toast, eggs} {
+		if err := repo.AddToCollection(mine.ID, recipe.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.AddToCollection(theirs.ID, jam.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.ReorderCollection(mine.ID, []int{jam.ID, eggs.ID, 9999, toast.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := collectionRecipeIDs(t, repo, mine.ID); !reflect.DeepEqual(got, []int{eggs.ID, toast.ID}) {
+		t.Errorf("own collection: got %v, want %v", got, []int{eggs.ID, toast.ID})
+	}
+
+	if got := collectionRecipeIDs(t, repo, theirs.ID); !reflect.DeepEqual(got, []int{jam.ID}) {
+		t.Errorf("another user's collection: got %v", got)
+	}
+}
+
+func TestIngredientFoodsBelongToTheirUser(t *testing.T) {
+	repo := newTestRepository(t)
+
+	if err := repo.SaveIngredientFoods(1, map[string]string{"relish": "tomato sauce", "mystery": ""}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.SaveIngredientFoods(2, map[string]string{"relish": "pickle"}); err != nil {
+		t.Fatal(err)
+	}
+
+	foods, err := repo.GetIngredientFoods(1, []string{"relish", "mystery", "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]string{"relish": "tomato sauce", "mystery": ""}; !reflect.DeepEqual(foods, want) {
+		t.Errorf("got %v, want %v", foods, want)
+	}
+
+	if foods, _ := repo.GetIngredientFoods(2, []string{"relish", "mystery"}); !reflect.DeepEqual(foods, map[string]string{"relish": "pickle"}) {
+		t.Errorf("another user's foods: got %v", foods)
+	}
+}
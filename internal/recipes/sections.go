@@ -0,0 +1,279 @@
+package recipes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// How sections are written in the recipe form and by the LLM, one per line among the
+// ingredients or directions: "# For the dough" for a heading and "+ Pie crust" for
+// another recipe used as a component
+const (
+	SECTION_PREFIX   = "#"
+	COMPONENT_PREFIX = "+"
+)
+
+var ErrUnknownComponent = errors.New("there's no recipe with that name to use as a component")
+
+// Section is a heading in a recipe's ingredients or directions, like "For the dough". It
+// covers the lines from Start up to the next section.
+//
+// A component section stands for another of the user's recipes, like a pie crust, whose
+// ingredients and directions are shown in its place. It has no lines of its own. Expanded
+// components become ordinary sections that keep the ID of the recipe they came from.
+type Section struct {
+	Title     string `json:"title"`
+	Start     int    `json:"start"`
+	Component bool   `json:"component,omitempty"`
+	RecipeID  int    `json:"recipeId,omitempty"`
+}
+
+// Group is the lines under one section, or before the first one.
+type Group struct {
+	Title     string
+	Component bool
+	RecipeID  int
+	Lines     []string
+}
+
+// ParseSections takes the headings out of lines, returning the remaining lines and
+// where each heading goes. Components are only recognized when asked for, since
+// only the recipe form offers them, and are saved by title until they're resolved.
+func ParseSections(lines []string, components bool) ([]string, []Section) {
+	result := []string{}
+	sections := []Section{}
+
+	for _, line := range lines {
+		if title, ok := cutMarker(line, SECTION_PREFIX); ok {
+			sections = append(sections, Section{Title: title, Start: len(result)})
+		} else if title, ok := cutMarker(line, COMPONENT_PREFIX); ok && components {
+			sections = append(sections, Section{Title: title, Start: len(result), Component: true})
+		} else {
+			result = append(result, line)
+		}
+	}
+
+	return result, sections
+}
+
+// cutMarker reads a section line. The marker has to be followed by a space, so
+// ingredients like "#10 can tomatoes" aren't mistaken for headings.
+func cutMarker(line, marker string) (string, bool) {
+	line = strings.TrimSpace(line)
+
+	if !strings.HasPrefix(line, marker) {
+		return "", false
+	}
+
+	rest := strings.TrimLeft(line, marker)
+	if !strings.HasPrefix(rest, " ") || strings.TrimSpace(rest) == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(rest), true
+}
+
+// WriteSections puts the headings back among the lines, for editing.
+func WriteSections(lines []string, sections []Section) []string {
+	var result []string
+
+	next := 0
+	sections = sortedSections(sections)
+
+	for i := 0; i <= len(lines); i++ {
+		for next < len(sections) && (sections[next].Start <= i || i == len(lines)) {
+			marker := SECTION_PREFIX
+			if sections[next].Component {
+				marker = COMPONENT_PREFIX
+			}
+
+			if sections[next].Title != "" {
+				result = append(result, marker+" "+sections[next].Title)
+			}
+
+			next++
+		}
+
+		if i < len(lines) {
+			result = append(result, lines[i])
+		}
+	}
+
+	return result
+}
+
+// Groups splits lines by their sections, leaving out blank lines and untitled groups
+// that end up empty.
+func Groups(lines []string, sections []Section) []Group {
+	groups := []Group{{}}
+
+	next := 0
+	sections = sortedSections(sections)
+
+	for i := 0; i <= len(lines); i++ {
+		for next < len(sections) && (sections[next].Start <= i || i == len(lines)) {
+			section := sections[next]
+			groups = append(groups, Group{Title: section.Title, Component: section.Component, RecipeID: section.RecipeID})
+
+			// Lines after a component belong to no section
+			if section.Component {
+				groups = append(groups, Group{})
+			}
+
+			next++
+		}
+
+		if i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+			last := &groups[len(groups)-1]
+			last.Lines = append(last.Lines, strings.TrimSpace(lines[i]))
+		}
+	}
+
+	result := []Group{}
+
+	for _, group := range groups {
+		if group.Title != "" || len(group.Lines) > 0 {
+			result = append(result, group)
+		}
+	}
+
+	return result
+}
+
+func sortedSections(sections []Section) []Section {
+	sorted := append([]Section{}, sections...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	return sorted
+}
+
+// appendGroup adds a group to the end of lines and sections. Untitled groups after a
+// section get an untitled section of their own, so they don't run into the one before.
+func appendGroup(lines []string, sections []Section, group Group) ([]string, []Section) {
+	if group.Title == "" && len(group.Lines) == 0 {
+		return lines, sections
+	}
+
+	if group.Title != "" || len(sections) > 0 {
+		sections = append(sections, Section{Title: group.Title, Start: len(lines), RecipeID: group.RecipeID})
+	}
+
+	return append(lines, group.Lines...), sections
+}
+
+func (r *Recipe) hasComponents() bool {
+	for _, section := range r.IngredientSections {
+		if section.Component {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandComponents returns a copy of the recipe with the recipes it uses as components
+// written out in their place: their ingredients where they're listed, and their
+// directions before the recipe's own. Components that have been deleted, or that would
+// include themselves, are left as bare headings.
+func expandComponents(recipe *Recipe, lookup func(id int) (*Recipe, error), path map[int]bool) (*Recipe, error) {
+	if !recipe.hasComponents() {
+		return recipe, nil
+	}
+
+	path[recipe.ID] = true
+	defer delete(path, recipe.ID)
+
+	expanded := *recipe
+	expanded.Ingredients, expanded.IngredientSections = []string{}, []Section{}
+
+	directions, directionSections := []string{}, []Section{}
+
+	for _, group := range Groups(recipe.Ingredients, recipe.IngredientSections) {
+		if !group.Component {
+			expanded.Ingredients, expanded.IngredientSections = appendGroup(expanded.Ingredients, expanded.IngredientSections, group)
+			continue
+		}
+
+		component, err := lookup(group.RecipeID)
+		if err != nil {
+			return nil, err
+		}
+
+		if component == nil || component.UserID != recipe.UserID || path[component.ID] {
+			expanded.Ingredients, expanded.IngredientSections = appendGroup(expanded.Ingredients, expanded.IngredientSections, Group{Title: group.Title})
+			continue
+		}
+
+		inner, err := expandComponents(component, lookup, path)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded.Ingredients, expanded.IngredientSections = appendGroup(expanded.Ingredients, expanded.IngredientSections, Group{
+			Title:    component.Title,
+			RecipeID: component.ID,
+			Lines:    nonBlank(inner.Ingredients),
+		})
+
+		directions, directionSections = appendGroup(directions, directionSections, Group{
+			Title:    component.Title,
+			RecipeID: component.ID,
+			Lines:    nonBlank(inner.Directions),
+		})
+	}
+
+	for _, group := range Groups(recipe.Directions, recipe.DirectionSections) {
+		directions, directionSections = appendGroup(directions, directionSections, group)
+	}
+
+	expanded.Directions, expanded.DirectionSections = directions, directionSections
+	expanded.NumberOfIngredients = len(expanded.Ingredients)
+
+	return &expanded, nil
+}
+
+// resolveComponents finds the recipes named by component sections, which are saved by title
+// until then.
+func (repo *Repository) resolveComponents(recipe *Recipe) error {
+	for i, section := range recipe.IngredientSections {
+		if !section.Component || section.RecipeID != 0 {
+			continue
+		}
+
+		component, err := repo.GetByTitle(recipe.UserID, section.Title)
+		if err != nil {
+			return err
+		}
+
+		if component == nil || component.ID == recipe.ID {
+			return fmt.Errorf("%w: %q", ErrUnknownComponent, section.Title)
+		}
+
+		recipe.IngredientSections[i].Title = component.Title
+		recipe.IngredientSections[i].RecipeID = component.ID
+	}
+
+	return nil
+}
+
+// refreshComponentTitles updates component sections to their recipes' current titles, which
+// may have changed since they were saved, so the recipe form names them correctly.
+func (repo *Repository) refreshComponentTitles(recipe *Recipe) error {
+	for i, section := range recipe.IngredientSections {
+		if !section.Component {
+			continue
+		}
+
+		component, err := repo.Get(section.RecipeID)
+		if err != nil {
+			return err
+		}
+
+		if component != nil && component.UserID == recipe.UserID {
+			recipe.IngredientSections[i].Title = component.Title
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package recipes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	SIMILAR_RECIPES_LIMIT  = 4
+	SIMILARITY_MIN_SCORE   = 0.1
+	INGREDIENT_WEIGHT      = 1.0
+	INGREDIENT_WORD_WEIGHT = 0.5
+	TECHNIQUE_WEIGHT       = 0.5
+)
+
+// Cooking techniques worth comparing recipes by, keyed by the forms they take in directions
+var techniques = map[string]string{
+	"bake": "bake", "baked": "bake", "baking": "bake",
+	"roast": "roast", "roasted": "roast", "roasting": "roast",
+	"braise": "braise", "braised": "braise", "braising": "braise",
+	"fry": "fry", "fried": "fry", "frying": "fry", "deep-fry": "fry",
+	"saute": "saute", "sauté": "saute", "sauteed": "saute", "sautéed": "saute",
+	"grill": "grill", "grilled": "grill", "grilling": "grill",
+	"simmer": "simmer", "simmering": "simmer",
+	"boil": "boil", "boiling": "boil",
+	"steam": "steam", "steamed": "steam", "steaming": "steam",
+	"poach": "poach", "poached": "poach", "poaching": "poach",
+	"broil": "broil", "broiled": "broil", "broiling": "broil",
+	"smoke": "smoke", "smoked": "smoke",
+	"stir-fry": "stir-fry", "stir-fried": "stir-fry",
+	"knead": "knead", "kneading": "knead",
+	"marinate": "marinate", "marinated": "marinate",
+	"whisk": "whisk", "whip": "whip", "whipped": "whip",
+	"blend": "blend", "puree": "puree", "purée": "puree",
+	"proof": "proof", "rise": "proof",
+	"slow cooker": "slow-cook", "pressure cooker": "pressure-cook", "instant pot": "pressure-cook",
+}
+
+// SimilarityEngine recommends recipes like the one being viewed, from the same user's library.
+// Recipes are compared by TF-IDF over their ingredients and techniques, so rare ingredients they
+// share count for more than common ones. Scores depend on the whole library, so a user's
+// recommendations are recomputed together in the background whenever one of their recipes changes.
+type SimilarityEngine struct {
+	repo *Repository
+	wake chan struct{}
+
+	mu    sync.Mutex
+	dirty map[int]bool
+}
+
+func NewSimilarityEngine(repo *Repository) *SimilarityEngine {
+	return &SimilarityEngine{
+		repo:  repo,
+		wake:  make(chan struct{}, 1),
+		dirty: map[int]bool{},
+	}
+}
+
+// Start recomputes recommendations in the background until the process exits, beginning with
+// the libraries that changed since their recommendations were computed, like those the CLI
+// imported into.
+func (e *SimilarityEngine) Start() {
+	go func() {
+		userIDs, err := e.repo.GetUserIDsWithRecipes()
+		if err != nil {
+			log.Printf("Failed to list users for similar recipes: %v", err)
+		}
+
+		for _, userID := range userIDs {
+			e.recompute(userID, false)
+		}
+
+		for range e.wake {
+			e.recomputeDirty()
+		}
+	}()
+}
+
+// Enqueue marks the recipe's owner's recommendations to be recomputed, after it's saved or
+// deleted. It never blocks or drops a user: a burst of saves, like an import, only marks them
+// once, and the next pass picks up everyone marked since the last one.
+func (e *SimilarityEngine) Enqueue(recipe *Recipe) {
+	e.mu.Lock()
+	e.dirty[recipe.UserID] = true
+	e.mu.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+		// A pass is already due, and will see this user
+	}
+}
+
+// recomputeDirty recomputes the recommendations of every user marked since the last pass.
+func (e *SimilarityEngine) recomputeDirty() {
+	e.mu.Lock()
+	dirty := e.dirty
+	e.dirty = map[int]bool{}
+	e.mu.Unlock()
+
+	for userID := range dirty {
+		e.recompute(userID, true)
+	}
+}
+
+// recompute recomputes the user's recommendations, unless force is false and their recipes
+// haven't changed since the last time.
+func (e *SimilarityEngine) recompute(userID int, force bool) {
+	recipes, err := e.repo.GetForUser(userID)
+	if err != nil {
+		log.Printf("Failed to load recipes for similar recipes of user %d: %v", userID, err)
+		return
+	}
+
+	fingerprint := similarityFingerprint(recipes)
+
+	if !force {
+		if computed, err := e.repo.GetSimilarityFingerprint(userID); err != nil {
+			log.Printf("Failed to load the similar recipes fingerprint of user %d: %v", userID, err)
+		} else if computed == fingerprint {
+			return
+		}
+	}
+
+	if err := e.repo.ReplaceSimilarRecipes(userID, SimilarRecipes(recipes), fingerprint); err != nil {
+		log.Printf("Failed to save similar recipes of user %d: %v", userID, err)
+	}
+}
+
+// similarityFingerprint identifies everything recommendations are computed from: which recipes
+// there are, and their ingredients and directions.
+func similarityFingerprint(recipes []*Recipe) string {
+	hash := sha256.New()
+
+	for _, recipe := range recipes {
+		fmt.Fprintf(hash, "%d\n%s\n%s\n\x00", recipe.ID, strings.Join(recipe.Ingredients, "\n"), strings.Join(recipe.Directions, "\n"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// SimilarRecipes scores every pair of recipes and keeps each recipe's closest matches.
+func SimilarRecipes(recipes []*Recipe) []Similarity {
+	vectors := tfidf(recipes)
+	var similarities []Similarity
+
+	for i, recipe := range recipes {
+		var candidates []Similarity
+
+		for j, other := range recipes {
+			if i == j {
+				continue
+			}
+
+			if score := cosine(vectors[i], vectors[j]); score >= SIMILARITY_MIN_SCORE {
+				candidates = append(candidates, Similarity{RecipeID: recipe.ID, SimilarID: other.ID, Score: score})
+			}
+		}
+
+		sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].Score > candidates[b].Score })
+		similarities = append(similarities, candidates[:min(len(candidates), SIMILAR_RECIPES_LIMIT)]...)
+	}
+
+	return similarities
+}
+
+func tfidf(recipes []*Recipe) []map[string]float64 {
+	features := make([]map[string]float64, len(recipes))
+	documentFrequency := map[string]int{}
+
+	for i, recipe := range recipes {
+		features[i] = recipeFeatures(recipe)
+
+		for feature := range features[i] {
+			documentFrequency[feature]++
+		}
+	}
+
+	for _, vector := range features {
+		for feature, weight := range vector {
+			idf := math.Log(float64(len(recipes)+1)/float64(documentFrequency[feature]+1)) + 1
+			vector[feature] = weight * idf
+		}
+	}
+
+	return features
+}
+
+// recipeFeatures describes a recipe by its normalized ingredients other than pantry staples,
+// the words in them (so "chicken thighs" is a little like "chicken breast") and the techniques
+// its directions use.
+func recipeFeatures(recipe *Recipe) map[string]float64 {
+	features := map[string]float64{}
+
+	for _, line := range recipe.Ingredients {
+		name := ParseIngredient(line).Name
+
+		// Nearly everything has salt in it, which says nothing about what a recipe is like
+		words := ingredientWords(name)
+		if len(words) == 0 || isStaple(name, DEFAULT_PANTRY_STAPLES) {
+			continue
+		}
+
+		features["ingredient:"+strings.Join(words, " ")] = INGREDIENT_WEIGHT
+
+		for _, word := range words {
+			if len(word) > 2 {
+				features["word:"+word] = max(features["word:"+word], INGREDIENT_WORD_WEIGHT)
+			}
+		}
+	}
+
+	directions := " " + strings.ToLower(strings.Join(recipe.Directions, " ")) + " "
+	for form, technique := range techniques {
+		if strings.Contains(directions, " "+form+" ") || strings.Contains(directions, " "+form+",") || strings.Contains(directions, " "+form+".") {
+			features["technique:"+technique] = TECHNIQUE_WEIGHT
+		}
+	}
+
+	return features
+}
+
+func cosine(a, b map[string]float64) float64 {
+	var product, lengthA, lengthB float64
+
+	for feature, weight := range a {
+		product += weight * b[feature]
+		lengthA += weight * weight
+	}
+
+	for _, weight := range b {
+		lengthB += weight * weight
+	}
+
+	if lengthA == 0 || lengthB == 0 {
+		return 0
+	}
+
+	return product / math.Sqrt(lengthA*lengthB)
+}
@@ -0,0 +1,106 @@
+package recipes
+
+import "testing"
+
+func countSimilarities(t *testing.T, repo *Repository) int {
+	t.Helper()
+
+	var count int
+	if err := repo.db.Get(&count, "SELECT COUNT(*) FROM recipe_similarities"); err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestSimilaritiesAreOnlyRecomputedWhenStale(t *testing.T) {
+	repo := newTestRepository(t)
+	engine := NewSimilarityEngine(repo)
+
+	var recipes []*Recipe
+	for _, title := range []string{"Pesto pasta", "Pesto gnocchi"} {
+		recipe, err := repo.Create(&Recipe{UserID: 1, Title: title, Ingredients: []string{"basil", "pine nuts", "parmesan"}, Directions: []string{"Boil, then toss with the pesto."}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recipes = append(recipes, recipe)
+	}
+
+	engine.recompute(1, false)
+	if count := countSimilarities(t, repo); count != 2 {
+		t.Fatalf("got %d similarities, want 2", count)
+	}
+
+	// Nothing changed, so as at startup they're left alone
+	repo.db.MustExec("DELETE FROM recipe_similarities")
+	engine.recompute(1, false)
+	if count := countSimilarities(t, repo); count != 0 {
+		t.Errorf("unchanged recipes were recomputed")
+	}
+
+	recipes[1].Ingredients = []string{"basil", "pine nuts", "parmesan", "potatoes"}
+	if _, err := repo.Update(recipes[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.recompute(1, false)
+	if count := countSimilarities(t, repo); count != 2 {
+		t.Errorf("changed recipes weren't recomputed: got %d similarities", count)
+	}
+}
+
+func TestDeletingARecipeNotifiesListeners(t *testing.T) {
+	repo := newTestRepository(t)
+
+	recipe, err := repo.Create(&Recipe{UserID: 7, Title: "Toast", Ingredients: []string{"bread"}, Directions: []string{"Toast it."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted []*Recipe
+	repo.OnDelete(func(recipe *Recipe) { deleted = append(deleted, recipe) })
+
+	if ok, err := repo.Delete(recipe.ID); err != nil || !ok {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+
+	if ok, err := repo.Delete(recipe.ID); err != nil || ok {
+		t.Fatalf("deleting again: got %v, %v", ok, err)
+	}
+
+	if len(deleted) != 1 || deleted[0].ID != recipe.ID || deleted[0].UserID != 7 {
+		t.Errorf("got %+v", deleted)
+	}
+}
+
+func TestEveryEnqueuedUserIsRecomputed(t *testing.T) {
+	repo := newTestRepository(t)
+	engine := NewSimilarityEngine(repo)
+
+	// More users than there's room for signals, saving while no pass is running
+	for userID := 1; userID <= 5; userID++ {
+		for _, title := range []string{"Pesto pasta", "Pesto gnocchi"} {
+			recipe, err := repo.Create(&Recipe{UserID: userID, Title: title, Ingredients: []string{"basil", "pine nuts", "parmesan"}, Directions: []string{"Boil, then toss with the pesto."}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			engine.Enqueue(recipe)
+		}
+	}
+
+	if len(engine.wake) != 1 {
+		t.Errorf("got %d passes due, want 1", len(engine.wake))
+	}
+
+	engine.recomputeDirty()
+
+	if count := countSimilarities(t, repo); count != 10 {
+		t.Errorf("got %d similarities, want 10", count)
+	}
+
+	if len(engine.dirty) != 0 {
+		t.Errorf("users still marked after a pass: %v", engine.dirty)
+	}
+}
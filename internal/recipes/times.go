@@ -0,0 +1,72 @@
+package recipes
+
+import "strings"
+
+// normalizeTimes parses the prep and cook times into minutes so recipes can be sorted and
+// filtered by time. The text is kept as written, since ranges like "20-25 mins" only parse
+// to their upper bound, but placeholders like "N/A" are cleared.
+func (r *Recipe) normalizeTimes() {
+	r.PrepMinutes = ParseMinutes(r.PrepTime)
+	r.CookMinutes = ParseMinutes(r.CookTime)
+
+	if r.PrepMinutes == nil && missingTimePattern.MatchString(strings.TrimSpace(r.PrepTime)) {
+		r.PrepTime = ""
+	}
+
+	if r.CookMinutes == nil && missingTimePattern.MatchString(strings.TrimSpace(r.CookTime)) {
+		r.CookTime = ""
+	}
+
+	// Recipes give either prep and cook times or active and inactive times; prefer the former
+	r.TotalMinutes = addMinutes(r.PrepMinutes, r.CookMinutes)
+	if r.TotalMinutes == nil {
+		r.TotalMinutes = addMinutes(r.ActiveMinutes, r.InactiveMinutes)
+	}
+}
+
+// PrepTimeText is the prep time as shown on the page: consistently formatted if it could be
+// parsed, and as written otherwise. Ranges are kept as written too, since their minutes are
+// only the upper bound.
+func (r *Recipe) PrepTimeText() string {
+	return timeText(r.PrepMinutes, r.PrepTime)
+}
+
+func (r *Recipe) CookTimeText() string {
+	return timeText(r.CookMinutes, r.CookTime)
+}
+
+func (r *Recipe) ActiveTimeText() string {
+	return timeText(r.ActiveMinutes, "")
+}
+
+func (r *Recipe) InactiveTimeText() string {
+	return timeText(r.InactiveMinutes, "")
+}
+
+func (r *Recipe) TotalTimeText() string {
+	return timeText(r.TotalMinutes, "")
+}
+
+func timeText(minutes *int, text string) string {
+	if minutes == nil || isDurationRange(text) {
+		return strings.TrimSpace(text)
+	}
+
+	return FormatMinutes(*minutes)
+}
+
+// addMinutes adds up the times, or returns nil unless all of them are known, since a total
+// missing one of its parts would understate how long a recipe takes.
+func addMinutes(times ...*int) *int {
+	total := 0
+
+	for _, minutes := range times {
+		if minutes == nil {
+			return nil
+		}
+
+		total += *minutes
+	}
+
+	return &total
+}
@@ -0,0 +1,65 @@
+package recipes
+
+import "testing"
+
+func TestNormalizeTimesOnlyTotalsKnownParts(t *testing.T) {
+	minutes := func(m int) *int { return &m }
+
+	tests := []struct {
+		recipe Recipe
+		total  *int
+	}{
+		{Recipe{PrepTime: "15 mins", CookTime: "1 hour"}, minutes(75)},
+		{Recipe{PrepTime: "15 mins"}, nil},
+		{Recipe{PrepTime: "15 mins", CookTime: "until done"}, nil},
+		{Recipe{ActiveMinutes: minutes(20), InactiveMinutes: minutes(60)}, minutes(80)},
+		{Recipe{ActiveMinutes: minutes(20)}, nil},
+		{Recipe{PrepTime: "10 mins", ActiveMinutes: minutes(20), InactiveMinutes: minutes(5)}, minutes(25)},
+	}
+
+	for _, test := range tests {
+		recipe := test.recipe
+		recipe.normalizeTimes()
+
+		if (recipe.TotalMinutes == nil) != (test.total == nil) || test.total != nil && *recipe.TotalMinutes != *test.total {
+			t.Errorf("%+v: got total %v, want %v", test.recipe, recipe.TotalMinutes, test.total)
+		}
+	}
+}
+
+func TestTimeTextKeepsRangesAsWritten(t *testing.T) {
+	recipe := Recipe{PrepTime: "20-25 mins", CookTime: "1 hr 15 mins"}
+	recipe.normalizeTimes()
+
+	if text := recipe.PrepTimeText(); text != "20-25 mins" {
+		t.Errorf("prep time: got %q", text)
+	}
+
+	if *recipe.PrepMinutes != 25 {
+		t.Errorf("prep minutes: got %d, want the upper bound", *recipe.PrepMinutes)
+	}
+
+	if text, want := recipe.CookTimeText(), FormatMinutes(75); text != want {
+		t.Errorf("cook time: got %q, want %q", text, want)
+	}
+}
+
+func TestBackfillTimesClearsPartialTotals(t *testing.T) {
+	repo := newTestRepository(t)
+
+	recipe, err := repo.Create(&Recipe{UserID: 1, Title: "Soup", PrepTime: "15 mins", Ingredients: []string{}, Directions: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// As saved when totals added up whichever parts were known
+	repo.db.MustExec("UPDATE recipes SET total_minutes = 15 WHERE id = ?", recipe.ID)
+
+	if _, err := repo.BackfillTimes(); err != nil {
+		t.Fatal(err)
+	}
+
+	if recipe, _ = repo.Get(recipe.ID); recipe.TotalMinutes != nil {
+		t.Errorf("got total %d, want none", *recipe.TotalMinutes)
+	}
+}
@@ -0,0 +1,197 @@
+package recipes
+
+import (
+	"strconv"
+	"strings"
+)
+
+const PresetUseWhatIHave = "use-what-i-have"
+
+// TransformPreset is a change to a recipe that's asked for often enough to offer as a choice.
+// Instructions are what the LLM is told.
+type TransformPreset struct {
+	Name         string
+	Title        string
+	Instructions string
+}
+
+var TransformPresets = []TransformPreset{
+	{
+		Name:         "vegetarian",
+		Title:        "Make it vegetarian",
+		Instructions: "Make this recipe vegetarian. Replace meat, poultry, fish and seafood, and stocks and sauces made from them, with vegetarian ingredients that play the same part in the dish.",
+	},
+	{
+		Name:         "dairy-free",
+		Title:        "Make it dairy-free",
+		Instructions: "Make this recipe dairy-free. Replace milk, butter, cream, cheese, yogurt and anything else made from milk with dairy-free ingredients that play the same part in the dish.",
+	},
+	{
+		Name:         "halve-sugar",
+		Title:        "Halve the sugar",
+		Instructions: "Halve the sugar and other sweeteners in this recipe, and adjust anything that depends on them, like liquids or baking times.",
+	},
+	{
+		Name:         PresetUseWhatIHave,
+		Title:        "Use what I have",
+		Instructions: "Adapt this recipe to the ingredients I have on hand, listed below. Substitute ingredients I don't have with ones I do, or leave them out if the dish works without them.",
+	},
+	{
+		Name:         "instant-pot",
+		Title:        "Adapt for Instant Pot",
+		Instructions: "Adapt this recipe for an Instant Pot electric pressure cooker. Rewrite the directions with the functions, pressure levels, cooking times and release method to use, adjust liquids so nothing burns, and update the cook time.",
+	},
+}
+
+// TransformRequest is how the user asked for a recipe to change. Summary describes it to the
+// user and Instructions to the LLM.
+type TransformRequest struct {
+	Summary      string
+	Instructions string
+}
+
+// NewTransformRequest combines the preset, if one was picked, with what the user wrote.
+// "Use what I have" takes what they wrote to be the ingredients on hand, along with their
+// pantry staples. It fails when there's nothing to ask for.
+func NewTransformRequest(presetName, text string, staples []string) (*TransformRequest, bool) {
+	text = strings.TrimSpace(text)
+
+	var preset *TransformPreset
+	for i := range TransformPresets {
+		if TransformPresets[i].Name == presetName {
+			preset = &TransformPresets[i]
+		}
+	}
+
+	switch {
+	case preset == nil && text == "":
+		return nil, false
+	case preset == nil:
+		return &TransformRequest{Summary: text, Instructions: text}, true
+	case preset.Name == PresetUseWhatIHave:
+		if text == "" {
+			return nil, false
+		}
+
+		instructions := preset.Instructions + "\n\nIngredients on hand: " + text
+		if len(staples) > 0 {
+			instructions += "\nPantry staples, always on hand: " + strings.Join(staples, ", ")
+		}
+
+		return &TransformRequest{Summary: preset.Title + ": " + text, Instructions: instructions}, true
+	case text == "":
+		return &TransformRequest{Summary: preset.Title, Instructions: preset.Instructions}, true
+	default:
+		return &TransformRequest{
+			Summary:      preset.Title + ". " + text,
+			Instructions: preset.Instructions + "\n\nAlso: " + text,
+		}, true
+	}
+}
+
+// toLLMRecipe writes the recipe the way the LLM reads and writes recipes, with its sections
+// as heading lines. Components should have been expanded first.
+func (r *Recipe) toLLMRecipe() LLMRecipe {
+	return LLMRecipe{
+		Title:       r.Title,
+		Ingredients: WriteSections(r.Ingredients, r.IngredientSections),
+		Directions:  WriteSections(r.Directions, r.DirectionSections),
+		Notes:       r.Notes,
+		PrepTime:    r.PrepTimeText(),
+		CookTime:    r.CookTimeText(),
+		Servings:    r.Servings,
+	}
+}
+
+const (
+	DiffSame = iota
+	DiffAdded
+	DiffRemoved
+)
+
+// DiffLine is a line of one version of a list or the other, or of both when it's unchanged.
+type DiffLine struct {
+	Text string
+	Op   int
+}
+
+// DiffLines compares two versions of a list line by line. Lines they share, in order, are
+// unchanged, and the rest are removed from the first or added in the second; removals come
+// before the additions that replace them.
+func DiffLines(before, after []string) []DiffLine {
+	// common[i][j] is how many lines before[i:] and after[j:] share
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	lines := []DiffLine{}
+	i, j := 0, 0
+
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			lines = append(lines, DiffLine{Text: before[i], Op: DiffSame})
+			i++
+			j++
+		case i < len(before) && (j == len(after) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, DiffLine{Text: before[i], Op: DiffRemoved})
+			i++
+		default:
+			lines = append(lines, DiffLine{Text: after[j], Op: DiffAdded})
+			j++
+		}
+	}
+
+	return lines
+}
+
+// FieldChange is a single value of a recipe that changed, like its title.
+type FieldChange struct {
+	Name   string
+	Before string
+	After  string
+}
+
+// RecipeChanges is how a recipe adapted from another differs from it, for reviewing before
+// it's saved.
+type RecipeChanges struct {
+	Parent      *Recipe
+	Fields      []FieldChange
+	Ingredients []DiffLine
+	Directions  []DiffLine
+}
+
+// CompareRecipes works out what changed from the parent to the recipe adapted from it. Pass
+// it an expanded parent, since adapted recipes have their components written out.
+func CompareRecipes(parent, adapted *Recipe) *RecipeChanges {
+	changes := &RecipeChanges{
+		Parent:      parent,
+		Ingredients: DiffLines(WriteSections(parent.Ingredients, parent.IngredientSections), WriteSections(adapted.Ingredients, adapted.IngredientSections)),
+		Directions:  DiffLines(WriteSections(parent.Directions, parent.DirectionSections), WriteSections(adapted.Directions, adapted.DirectionSections)),
+	}
+
+	for _, field := range []FieldChange{
+		{Name: "Title", Before: parent.Title, After: adapted.Title},
+		{Name: "Prep time", Before: parent.PrepTimeText(), After: adapted.PrepTimeText()},
+		{Name: "Cook time", Before: parent.CookTimeText(), After: adapted.CookTimeText()},
+		{Name: "Servings", Before: strconv.Itoa(parent.Servings), After: strconv.Itoa(adapted.Servings)},
+		{Name: "Notes", Before: strings.TrimSpace(parent.Notes), After: strings.TrimSpace(adapted.Notes)},
+	} {
+		if field.Before != field.After {
+			changes.Fields = append(changes.Fields, field)
+		}
+	}
+
+	return changes
+}
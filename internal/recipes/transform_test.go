@@ -0,0 +1,130 @@
+package recipes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	same := func(text string) DiffLine { return DiffLine{Text: text, Op: DiffSame} }
+	added := func(text string) DiffLine { return DiffLine{Text: text, Op: DiffAdded} }
+	removed := func(text string) DiffLine { return DiffLine{Text: text, Op: DiffRemoved} }
+
+	tests := []struct {
+		before, after []string
+		want          []DiffLine
+	}{
+		{nil, nil, []DiffLine{}},
+		{[]string{"a", "b"}, []string{"a", "b"}, []DiffLine{same("a"), same("b")}},
+		{nil, []string{"a"}, []DiffLine{added("a")}},
+		{[]string{"a"}, nil, []DiffLine{removed("a")}},
+		// Replacements read as the old line, then the new one
+		{[]string{"flour", "butter", "sugar"}, []string{"flour", "margarine", "sugar"}, []DiffLine{same("flour"), removed("butter"), added("margarine"), same("sugar")}},
+		{[]string{"a", "b", "c"}, []string{"c", "a"}, []DiffLine{removed("a"), removed("b"), same("c"), added("a")}},
+		{[]string{"a", "b"}, []string{"b", "c"}, []DiffLine{removed("a"), same("b"), added("c")}},
+	}
+
+	for _, test := range tests {
+		if got := DiffLines(test.before, test.after); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q to %q:\n got %v\nwant %v", test.before, test.after, got, test.want)
+		}
+	}
+}
+
+func TestNewTransformRequest(t *testing.T) {
+	if _, ok := NewTransformRequest("", "  ", nil); ok {
+		t.Error("an empty request was accepted")
+	}
+
+	if _, ok := NewTransformRequest(PresetUseWhatIHave, "", nil); ok {
+		t.Error("use what I have was accepted without any ingredients")
+	}
+
+	request, ok := NewTransformRequest("", " Make it spicier ", nil)
+	if !ok || request.Summary != "Make it spicier" || request.Instructions != "Make it spicier" {
+		t.Errorf("free text: got %+v", request)
+	}
+
+	request, ok = NewTransformRequest("vegetarian", "", nil)
+	if !ok || request.Summary != "Make it vegetarian" || !strings.HasPrefix(request.Instructions, "Make this recipe vegetarian.") {
+		t.Errorf("preset: got %+v", request)
+	}
+
+	request, ok = NewTransformRequest("vegetarian", "No mushrooms", nil)
+	if !ok || request.Summary != "Make it vegetarian. No mushrooms" || !strings.HasSuffix(request.Instructions, "\n\nAlso: No mushrooms") {
+		t.Errorf("preset and text: got %+v", request)
+	}
+
+	request, ok = NewTransformRequest(PresetUseWhatIHave, "eggs, spinach", []string{"salt", "oil"})
+	if !ok || request.Summary != "Use what I have: eggs, spinach" {
+		t.Fatalf("use what I have: got %+v", request)
+	}
+
+	if !strings.Contains(request.Instructions, "Ingredients on hand: eggs, spinach") || !strings.Contains(request.Instructions, "always on hand: salt, oil") {
+		t.Errorf("use what I have: got instructions %q", request.Instructions)
+	}
+
+	if request, _ := NewTransformRequest(PresetUseWhatIHave, "eggs", nil); strings.Contains(request.Instructions, "Pantry staples") {
+		t.Errorf("use what I have without staples: got instructions %q", request.Instructions)
+	}
+
+	if request, ok := NewTransformRequest("no-such-preset", "Less salt", nil); !ok || request.Summary != "Less salt" {
+		t.Errorf("unknown preset: got %+v", request)
+	}
+}
+
+func TestCompareRecipes(t *testing.T) {
+	parent := &Recipe{
+		Title:              "Beef chili",
+		Ingredients:        []string{"1 lb ground beef", "1 onion", "1 can beans"},
+		IngredientSections: []Section{{Title: "Toppings", Start: 2}},
+		Directions:         []string{"Brown the beef.", "Simmer."},
+		CookTime:           "1 hour",
+		Servings:           4,
+		Notes:              "Better the next day.",
+	}
+	parent.normalizeTimes()
+
+	adapted := &Recipe{
+		Title:              "Vegetarian chili",
+		Ingredients:        []string{"1 lb lentils", "1 onion", "1 can beans"},
+		IngredientSections: []Section{{Title: "Toppings", Start: 2}},
+		Directions:         []string{"Rinse the lentils.", "Simmer."},
+		CookTime:           "60 minutes",
+		Servings:           4,
+		Notes:              "Better the next day. ",
+	}
+	adapted.normalizeTimes()
+
+	changes := CompareRecipes(parent, adapted)
+
+	if changes.Parent != parent {
+		t.Error("the parent wasn't kept")
+	}
+
+	// The cook time and notes are written differently but say the same
+	if want := []FieldChange{{Name: "Title", Before: "Beef chili", After: "Vegetarian chili"}}; !reflect.DeepEqual(changes.Fields, want) {
+		t.Errorf("fields: got %+v, want %+v", changes.Fields, want)
+	}
+
+	wantIngredients := []DiffLine{
+		{Text: "1 lb ground beef", Op: DiffRemoved},
+		{Text: "1 lb lentils", Op: DiffAdded},
+		{Text: "1 onion", Op: DiffSame},
+		{Text: SECTION_PREFIX + " Toppings", Op: DiffSame},
+		{Text: "1 can beans", Op: DiffSame},
+	}
+	if !reflect.DeepEqual(changes.Ingredients, wantIngredients) {
+		t.Errorf("ingredients: got %+v", changes.Ingredients)
+	}
+
+	wantDirections := []DiffLine{
+		{Text: "Brown the beef.", Op: DiffRemoved},
+		{Text: "Rinse the lentils.", Op: DiffAdded},
+		{Text: "Simmer.", Op: DiffSame},
+	}
+	if !reflect.DeepEqual(changes.Directions, wantDirections) {
+		t.Errorf("directions: got %+v", changes.Directions)
+	}
+}
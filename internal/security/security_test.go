@@ -0,0 +1,183 @@
+package security
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const testBaseURL = "https://recipes.example"
+
+func newTestApp() *fiber.App {
+	app := fiber.New()
+	store := NewSessionStore(nil, true)
+
+	app.Use(NewCORS(testBaseURL))
+	app.Use(NewCSRF(store, true))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		token, _ := c.Locals(CSRFContextKey).(string)
+		return c.SendString(token)
+	})
+
+	app.Post("/recipes", func(c *fiber.Ctx) error {
+		return c.SendString("created")
+	})
+
+	return app
+}
+
+// getToken loads a page the way a browser would, returning the token it was given and the
+// cookies to send it back with.
+func getToken(t *testing.T, app *fiber.App) (string, []*http.Cookie) {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, testBaseURL+"/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body) == 0 {
+		t.Fatal("no CSRF token was issued")
+	}
+
+	return string(body), resp.Cookies()
+}
+
+func post(t *testing.T, app *fiber.App, cookies []*http.Cookie, configure func(*http.Request)) int {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, testBaseURL+"/recipes", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set(fiber.HeaderReferer, testBaseURL+"/")
+
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	configure(req)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode
+}
+
+func TestCSRFAllowsSameOriginPostWithToken(t *testing.T) {
+	app := newTestApp()
+	token, cookies := getToken(t, app)
+
+	status := post(t, app, cookies, func(req *http.Request) {
+		req.Header.Set(CSRFHeaderName, token)
+	})
+
+	if status != fiber.StatusOK {
+		t.Errorf("header token: got status %d, want %d", status, fiber.StatusOK)
+	}
+
+	status = post(t, app, cookies, func(req *http.Request) {
+		form := url.Values{CSRFFormField: {token}}.Encode()
+		req.Body = io.NopCloser(strings.NewReader(form))
+		req.ContentLength = int64(len(form))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	})
+
+	if status != fiber.StatusOK {
+		t.Errorf("form token: got status %d, want %d", status, fiber.StatusOK)
+	}
+}
+
+func TestCSRFRejectsMissingOrWrongToken(t *testing.T) {
+	app := newTestApp()
+	token, cookies := getToken(t, app)
+
+	tests := []struct {
+		name      string
+		configure func(*http.Request)
+	}{
+		{"missing token", func(req *http.Request) {}},
+		{"wrong token", func(req *http.Request) { req.Header.Set(CSRFHeaderName, "not-"+token) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status := post(t, app, cookies, test.configure); status != fiber.StatusForbidden {
+				t.Errorf("got status %d, want %d", status, fiber.StatusForbidden)
+			}
+		})
+	}
+
+	// A token without the cookie it was issued with is as good as a guess
+	status := post(t, app, nil, func(req *http.Request) {
+		req.Header.Set(CSRFHeaderName, token)
+	})
+
+	if status != fiber.StatusForbidden {
+		t.Errorf("token without cookie: got status %d, want %d", status, fiber.StatusForbidden)
+	}
+}
+
+func TestCSRFRejectsCrossOriginPost(t *testing.T) {
+	app := newTestApp()
+	token, cookies := getToken(t, app)
+
+	// Even with a valid token, a write from another site is refused
+	status := post(t, app, cookies, func(req *http.Request) {
+		req.Header.Set(CSRFHeaderName, token)
+		req.Header.Set(fiber.HeaderOrigin, "https://evil.example")
+		req.Header.Set(fiber.HeaderReferer, "https://evil.example/attack")
+	})
+
+	if status != fiber.StatusForbidden {
+		t.Errorf("cross-origin referer: got status %d, want %d", status, fiber.StatusForbidden)
+	}
+
+	status = post(t, app, cookies, func(req *http.Request) {
+		req.Header.Set(CSRFHeaderName, token)
+		req.Header.Del(fiber.HeaderReferer)
+	})
+
+	if status != fiber.StatusForbidden {
+		t.Errorf("no referer: got status %d, want %d", status, fiber.StatusForbidden)
+	}
+}
+
+func TestCORSOnlyAllowsOwnOrigin(t *testing.T) {
+	app := newTestApp()
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{testBaseURL, true},
+		{"https://evil.example", false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodOptions, testBaseURL+"/recipes", nil)
+		req.Header.Set(fiber.HeaderOrigin, test.origin)
+		req.Header.Set(fiber.HeaderAccessControlRequestMethod, http.MethodPost)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		allowOrigin := resp.Header.Get(fiber.HeaderAccessControlAllowOrigin)
+		if allowed := allowOrigin == test.origin; allowed != test.allowed {
+			t.Errorf("origin %s: got Access-Control-Allow-Origin %q, want allowed=%v", test.origin, allowOrigin, test.allowed)
+		}
+	}
+}
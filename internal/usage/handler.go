@@ -0,0 +1,24 @@
+package usage
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	meter *Meter
+}
+
+func NewHandler(meter *Meter) *Handler {
+	return &Handler{meter: meter}
+}
+
+func (h *Handler) GetReport(c *fiber.Ctx) error {
+	report, err := h.meter.Report(c.Query("period"))
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	component := ReportView(report)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
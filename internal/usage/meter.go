@@ -0,0 +1,100 @@
+package usage
+
+import (
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+type Meter struct {
+	repo    *Repository
+	quota   Quota
+	pricing Pricing
+	now     func() time.Time
+}
+
+func NewMeter(repo *Repository, quota Quota, pricing Pricing) *Meter {
+	return &Meter{
+		repo:    repo,
+		quota:   quota,
+		pricing: pricing,
+		now:     time.Now,
+	}
+}
+
+// Check returns a *QuotaExceededError if the user has no tokens left in the current day or month.
+func (m *Meter) Check(userID int) error {
+	now := m.now().UTC()
+
+	if m.quota.DailyTokens > 0 {
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+		used, err := m.repo.TotalTokensSince(userID, startOfDay)
+		if err != nil {
+			return err
+		}
+
+		if used >= m.quota.DailyTokens {
+			return &QuotaExceededError{Period: "daily", Limit: m.quota.DailyTokens, ResetsAt: startOfDay.AddDate(0, 0, 1)}
+		}
+	}
+
+	if m.quota.MonthlyTokens > 0 {
+		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		used, err := m.repo.TotalTokensSince(userID, startOfMonth)
+		if err != nil {
+			return err
+		}
+
+		if used >= m.quota.MonthlyTokens {
+			return &QuotaExceededError{Period: "monthly", Limit: m.quota.MonthlyTokens, ResetsAt: startOfMonth.AddDate(0, 1, 0)}
+		}
+	}
+
+	return nil
+}
+
+func (m *Meter) Record(userID int, model string, tokens openai.Usage) error {
+	return m.repo.Create(&Usage{
+		UserID:           userID,
+		Model:            model,
+		PromptTokens:     tokens.PromptTokens,
+		CompletionTokens: tokens.CompletionTokens,
+		TotalTokens:      tokens.TotalTokens,
+	})
+}
+
+func (m *Meter) Report(period string) (*Report, error) {
+	now := m.now().UTC()
+
+	var since time.Time
+	switch period {
+	case "today":
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	case "all":
+		since = time.Time{}
+	default:
+		period = "month"
+		since = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	rows, err := m.repo.ReportSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Period: period, Rows: rows}
+
+	for _, row := range rows {
+		row.EstimatedCost = m.pricing.Cost(row.PromptTokens, row.CompletionTokens)
+
+		report.Totals.Requests += row.Requests
+		report.Totals.PromptTokens += row.PromptTokens
+		report.Totals.CompletionTokens += row.CompletionTokens
+		report.Totals.TotalTokens += row.TotalTokens
+		report.Totals.EstimatedCost += row.EstimatedCost
+	}
+
+	return report, nil
+}
@@ -0,0 +1,133 @@
+package usage
+
+import (
+	"errors"
+	"path/filepath"
+	"sourdough/internal/database"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func newTestMeter(t *testing.T, quota Quota, now time.Time) *Meter {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	meter := NewMeter(NewRepository(db), quota, Pricing{PromptPerMillion: 1, CompletionPerMillion: 2})
+	meter.now = func() time.Time { return now }
+
+	return meter
+}
+
+// recordAt saves usage as if it had been recorded at the given time, the way SQLite's
+// CURRENT_TIMESTAMP would have written it.
+func recordAt(t *testing.T, meter *Meter, userID, tokens int, at time.Time) {
+	t.Helper()
+
+	meter.repo.db.MustExec(
+		"INSERT INTO llm_usage (user_id, model, prompt_tokens, completion_tokens, total_tokens, created_at) VALUES (?, 'model', ?, 0, ?, ?)",
+		userID, tokens, tokens, at.UTC().Format(sqliteTimeFormat),
+	)
+}
+
+func TestCheckDailyQuota(t *testing.T) {
+	// Early in the morning east of UTC, which is still the previous day in UTC
+	now := time.Date(2026, 3, 10, 1, 0, 0, 0, time.FixedZone("UTC+5", 5*60*60))
+	startOfDay := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+
+	meter := newTestMeter(t, Quota{DailyTokens: 100}, now)
+
+	recordAt(t, meter, 1, 500, startOfDay.Add(-time.Second))
+	recordAt(t, meter, 1, 60, startOfDay)
+	recordAt(t, meter, 2, 500, startOfDay)
+
+	if err := meter.Check(1); err != nil {
+		t.Fatalf("under quota: got %v", err)
+	}
+
+	recordAt(t, meter, 1, 40, startOfDay.Add(time.Hour))
+
+	var exceeded *QuotaExceededError
+	if err := meter.Check(1); !errors.As(err, &exceeded) {
+		t.Fatalf("at quota: got %v, want a QuotaExceededError", err)
+	}
+
+	if exceeded.Period != "daily" || !exceeded.ResetsAt.Equal(startOfDay.AddDate(0, 0, 1)) {
+		t.Errorf("got %+v", exceeded)
+	}
+}
+
+func TestCheckMonthlyQuota(t *testing.T) {
+	now := time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC)
+	startOfMonth := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	meter := newTestMeter(t, Quota{DailyTokens: 1000, MonthlyTokens: 1000}, now)
+
+	recordAt(t, meter, 1, 5000, startOfMonth.Add(-time.Second))
+	recordAt(t, meter, 1, 600, startOfMonth)
+
+	if err := meter.Check(1); err != nil {
+		t.Fatalf("under quota: got %v", err)
+	}
+
+	recordAt(t, meter, 1, 400, time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC))
+
+	var exceeded *QuotaExceededError
+	if err := meter.Check(1); !errors.As(err, &exceeded) {
+		t.Fatalf("at quota: got %v, want a QuotaExceededError", err)
+	}
+
+	if exceeded.Period != "monthly" || !exceeded.ResetsAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %+v", exceeded)
+	}
+}
+
+func TestCheckWithoutQuota(t *testing.T) {
+	meter := newTestMeter(t, Quota{}, time.Now())
+	recordAt(t, meter, 1, 1_000_000, time.Now())
+
+	if err := meter.Check(1); err != nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestRecordAndReport(t *testing.T) {
+	meter := newTestMeter(t, Quota{}, time.Now())
+
+	for _, userID := range []int{1, 1, 2} {
+		if err := meter.Record(userID, "model", openai.Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000, TotalTokens: 1_500_000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Last month's usage only counts towards all time
+	recordAt(t, meter, 1, 1000, time.Now().UTC().AddDate(0, -1, -1))
+
+	report, err := meter.Report("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Period != "month" || len(report.Rows) != 2 {
+		t.Fatalf("got %+v", report)
+	}
+
+	if report.Rows[0].UserID != 1 || report.Rows[0].Requests != 2 || report.Rows[0].EstimatedCost != 4 {
+		t.Errorf("top row: got %+v", report.Rows[0])
+	}
+
+	if report.Totals.Requests != 3 || report.Totals.TotalTokens != 4_500_000 || report.Totals.EstimatedCost != 6 {
+		t.Errorf("totals: got %+v", report.Totals)
+	}
+
+	if report, err := meter.Report("all"); err != nil || report.Totals.Requests != 4 {
+		t.Errorf("all time: got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,65 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+)
+
+type Usage struct {
+	ID               int       `db:"id"`
+	UserID           int       `db:"user_id"`
+	Model            string    `db:"model"`
+	PromptTokens     int       `db:"prompt_tokens"`
+	CompletionTokens int       `db:"completion_tokens"`
+	TotalTokens      int       `db:"total_tokens"`
+	CreatedAt        time.Time `db:"created_at"`
+}
+
+type ReportRow struct {
+	UserID           int     `db:"user_id"`
+	ProviderUserID   string  `db:"provider_user_id"`
+	Model            string  `db:"model"`
+	Requests         int     `db:"requests"`
+	PromptTokens     int     `db:"prompt_tokens"`
+	CompletionTokens int     `db:"completion_tokens"`
+	TotalTokens      int     `db:"total_tokens"`
+	EstimatedCost    float64 `db:"-"`
+}
+
+type Report struct {
+	Period string
+	Rows   []*ReportRow
+	Totals ReportRow
+}
+
+// Pricing is the provider's price in USD per million tokens.
+type Pricing struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+func (p Pricing) Cost(promptTokens, completionTokens int) float64 {
+	return float64(promptTokens)/1_000_000*p.PromptPerMillion +
+		float64(completionTokens)/1_000_000*p.CompletionPerMillion
+}
+
+// Quota limits the number of tokens a single user can spend. A zero limit means unlimited.
+type Quota struct {
+	DailyTokens   int
+	MonthlyTokens int
+}
+
+type QuotaExceededError struct {
+	Period   string
+	Limit    int
+	ResetsAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf(
+		"You've used your %s allowance of %d AI tokens. It resets %s.",
+		e.Period,
+		e.Limit,
+		e.ResetsAt.Format("Jan 2 at 3:04 PM MST"),
+	)
+}
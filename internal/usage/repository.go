@@ -0,0 +1,70 @@
+package usage
+
+import (
+	"sourdough/internal/database"
+	"time"
+)
+
+// SQLite stores CURRENT_TIMESTAMP as UTC text, so bounds must be compared in the same format.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (repo *Repository) Create(usage *Usage) error {
+	_, err := repo.db.NamedExec(
+		"INSERT INTO llm_usage (user_id, model, prompt_tokens, completion_tokens, total_tokens) VALUES (:user_id, :model, :prompt_tokens, :completion_tokens, :total_tokens)",
+		usage,
+	)
+
+	return err
+}
+
+func (repo *Repository) TotalTokensSince(userID int, since time.Time) (int, error) {
+	var total int
+
+	err := repo.db.Get(
+		&total,
+		"SELECT COALESCE(SUM(total_tokens), 0) FROM llm_usage WHERE user_id = ? AND created_at >= ?",
+		userID,
+		since.UTC().Format(sqliteTimeFormat),
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (repo *Repository) ReportSince(since time.Time) ([]*ReportRow, error) {
+	var rows []*ReportRow
+
+	err := repo.db.Select(&rows, `
+		SELECT
+			llm_usage.user_id,
+			COALESCE(users.user_id, '') AS provider_user_id,
+			llm_usage.model,
+			COUNT(*) AS requests,
+			SUM(llm_usage.prompt_tokens) AS prompt_tokens,
+			SUM(llm_usage.completion_tokens) AS completion_tokens,
+			SUM(llm_usage.total_tokens) AS total_tokens
+		FROM llm_usage
+		LEFT JOIN users ON users.id = llm_usage.user_id
+		WHERE llm_usage.created_at >= ?
+		GROUP BY llm_usage.user_id, llm_usage.model
+		ORDER BY total_tokens DESC`,
+		since.UTC().Format(sqliteTimeFormat),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
@@ -6,16 +6,23 @@ import (
 	"io/fs"
 	"log"
 	"net/http"
+	"os"
 	"sourdough/internal/auth"
+	"sourdough/internal/cookbook"
+	"sourdough/internal/cooklang"
 	"sourdough/internal/database"
+	"sourdough/internal/exporter"
+	"sourdough/internal/importer"
+	"sourdough/internal/ratelimit"
 	"sourdough/internal/recipes"
+	"sourdough/internal/security"
+	"sourdough/internal/usage"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/gofiber/fiber/v2/middleware/session"
 	"github.com/gofiber/storage/sqlite3/v2"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/providers/google"
@@ -36,6 +43,8 @@ func main() {
 	viper.SetDefault("DEV_MODE", false)
 	viper.SetDefault("DB_PATH", "./recipes.db")
 	viper.SetDefault("LLM_PROVIDER_BASE_URL", "https://openrouter.ai/api/v1")
+	viper.SetDefault("LLM_DAILY_TOKEN_QUOTA", 0)   // 0 means unlimited
+	viper.SetDefault("LLM_MONTHLY_TOKEN_QUOTA", 0) // 0 means unlimited
 
 	dbPath := viper.GetString("DB_PATH")
 
@@ -45,20 +54,29 @@ func main() {
 	}
 	defer db.Close()
 
-	useProviders()
+	if len(os.Args) > 1 {
+		if err := runCommand(db, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	baseURL := useBaseURL()
+	secureCookies := !viper.GetBool("DEV_MODE")
+
+	useProviders(baseURL)
 
 	// see here for more on what this does: https://github.com/gofiber/storage/blob/main/sqlite3/README.md
 	// ...and here for more on why we configure this way: https://docs.giber.io/api/middleware/session
-	sessionStore := session.New(session.Config{
-		Storage: sqlite3.New(sqlite3.Config{
-			Database: dbPath,
-		}),
-	})
+	sessionStore := security.NewSessionStore(sqlite3.New(sqlite3.Config{
+		Database: dbPath,
+	}), secureCookies)
 
 	goth_fiber.SessionStore = sessionStore
 
 	app := fiber.New(fiber.Config{
-		BodyLimit: 10 * 1024 * 1024, // 10MB limit for image uploads
+		BodyLimit:   30 * 1024 * 1024, // 30MB limit for multi-page image uploads
+		ProxyHeader: viper.GetString("PROXY_IP_HEADER"),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -71,11 +89,30 @@ func main() {
 
 	app.Use(recover.New())
 	app.Use(logger.New())
-	app.Use(cors.New())
+	app.Use(security.NewCORS(baseURL))
+	app.Use(security.NewCSRF(sessionStore, secureCookies))
 
 	userRepo := auth.NewRepository(db)
 	recipesRepo := recipes.NewRepository(db)
 
+	if updated, err := recipesRepo.BackfillTimes(); err != nil {
+		log.Printf("Failed to backfill recipe times: %v", err)
+	} else if updated > 0 {
+		log.Printf("Parsed the times of %d recipes", updated)
+	}
+
+	if updated, err := recipesRepo.BackfillDietary(); err != nil {
+		log.Printf("Failed to backfill dietary labels: %v", err)
+	} else if updated > 0 {
+		log.Printf("Detected the dietary labels of %d recipes", updated)
+	}
+
+	if deleted, err := recipesRepo.DeleteOrphanedSources(); err != nil {
+		log.Printf("Failed to delete orphaned sources: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Deleted %d sources that no recipe was imported from", deleted)
+	}
+
 	model := viper.GetString("LLM_PROVIDER_MODEL")
 	apiKey := viper.GetString("LLM_PROVIDER_API_KEY")
 	apiURL := viper.GetString("LLM_PROVIDER_BASE_URL")
@@ -88,25 +125,112 @@ func main() {
 	config.BaseURL = apiURL
 	openAIClient := openai.NewClientWithConfig(config)
 
-	llmService := recipes.NewLLMService(openAIClient, model)
-	recipesHandler := recipes.NewHandler(recipesRepo, llmService)
+	usageRepo := usage.NewRepository(db)
+	meter := usage.NewMeter(
+		usageRepo,
+		usage.Quota{
+			DailyTokens:   viper.GetInt("LLM_DAILY_TOKEN_QUOTA"),
+			MonthlyTokens: viper.GetInt("LLM_MONTHLY_TOKEN_QUOTA"),
+		},
+		usage.Pricing{
+			PromptPerMillion:     viper.GetFloat64("LLM_PROMPT_COST_PER_MILLION"),
+			CompletionPerMillion: viper.GetFloat64("LLM_COMPLETION_COST_PER_MILLION"),
+		},
+	)
+
+	llmCache := recipes.NewLLMCache(db)
+	if purged, err := llmCache.DeleteStale(recipes.PromptVersions()); err != nil {
+		log.Printf("Failed to purge stale LLM cache entries: %v", err)
+	} else if purged > 0 {
+		log.Printf("Purged %d LLM cache entries from old prompts", purged)
+	}
+
+	llmService := recipes.NewLLMService(openAIClient, model, meter, llmCache)
+
+	// Without an embeddings model, search falls back to a local embedder that only matches words
+	var embedder recipes.Embedder = recipes.NewHashingEmbedder(recipes.HASHING_EMBEDDING_DIMENSIONS)
+	if embeddingsModel := viper.GetString("EMBEDDINGS_MODEL"); embeddingsModel != "" {
+		embedder = recipes.NewOpenAIEmbedder(openAIClient, embeddingsModel, meter)
+	}
+
+	embeddingIndex := recipes.NewEmbeddingIndex(recipesRepo, embedder)
+	embeddingIndex.Start()
+	recipesRepo.OnSave(embeddingIndex.Enqueue)
+
+	similarityEngine := recipes.NewSimilarityEngine(recipesRepo)
+	similarityEngine.Start()
+	recipesRepo.OnSave(similarityEngine.Enqueue)
+	recipesRepo.OnDelete(similarityEngine.Enqueue)
+
+	recipesHandler := recipes.NewHandler(recipesRepo, llmService, embeddingIndex)
+	usageHandler := usage.NewHandler(meter)
+	importHandler := importer.NewHandler(importer.NewImporter(recipesRepo))
+	exportHandler := exporter.NewHandler(exporter.NewExporter(recipesRepo), recipesRepo)
+	cooklangHandler := cooklang.NewHandler(recipesRepo)
+	cookbookHandler := cookbook.NewHandler(cookbook.NewBuilder(recipesRepo), recipesRepo)
 	authHandler := auth.NewHandler(userRepo, sessionStore)
-	authMiddleware := auth.NewMiddleware(authHandler)
+	authMiddleware := auth.NewMiddleware(authHandler, strings.Split(viper.GetString("ADMIN_USER_IDS"), ","))
+	limiter := ratelimit.NewLimiter(ratelimit.NewRepository(db))
+	limiter.Start()
 
 	app.Get("/", authMiddleware.RequireAuth, recipesHandler.GetAllRecipes)
 
 	app.Get("/search", authMiddleware.RequireAuth, recipesHandler.SearchRecipes)
+	app.Get("/pantry", authMiddleware.RequireAuth, recipesHandler.GetPantry)
+	app.Post("/pantry", authMiddleware.RequireAuth, recipesHandler.UpdatePantry)
+
+	app.Get("/drafts/:id", authMiddleware.RequireAuth, recipesHandler.GetDraft)
+	app.Get("/drafts/:id/source/:index", authMiddleware.RequireAuth, recipesHandler.GetDraftSource)
+	app.Post("/drafts/:id", authMiddleware.RequireAuth, recipesHandler.ConfirmDraft)
+	app.Delete("/drafts/:id", authMiddleware.RequireAuth, recipesHandler.DeleteDraft)
+
+	app.Get("/sources/:id", authMiddleware.RequireAuth, recipesHandler.GetSource)
+	app.Get("/sources/:id/pages", authMiddleware.RequireAuth, recipesHandler.GetPDFPages)
+	app.Get("/sources/:id/pages/:page/image", authMiddleware.RequireAuth, recipesHandler.GetPDFPageImage)
+	app.Post("/sources/:id/pages", authMiddleware.RequireAuth, limiter.Handler(ratelimit.ImportPolicy), recipesHandler.ImportPDFPages)
 
 	app.Get("/recipes/:id", authMiddleware.RequireAuth, recipesHandler.GetRecipe)
 	app.Get("/recipes/:id/edit", authMiddleware.RequireAuth, recipesHandler.EditRecipe)
+	app.Get("/recipes/:id/cook", authMiddleware.RequireAuth, recipesHandler.CookRecipe)
+	app.Post("/recipes/:id/log", authMiddleware.RequireAuth, recipesHandler.AddCookLogEntry)
+	app.Delete("/recipes/:id/log/:entryId", authMiddleware.RequireAuth, recipesHandler.DeleteCookLogEntry)
+	app.Get("/recipes/:id/log/:entryId/photo", authMiddleware.RequireAuth, recipesHandler.GetCookLogPhoto)
+	app.Post("/recipes/:id/nutrition", authMiddleware.RequireAuth, limiter.Handler(ratelimit.AssistPolicy), recipesHandler.LookUpNutrition)
+	app.Put("/recipes/:id/dietary", authMiddleware.RequireAuth, recipesHandler.UpdateDietaryLabels)
+	app.Post("/recipes/:id/transform", authMiddleware.RequireAuth, limiter.Handler(ratelimit.AssistPolicy), recipesHandler.TransformRecipe)
+	app.Post("/recipes/:id/dietary/check", authMiddleware.RequireAuth, limiter.Handler(ratelimit.AssistPolicy), recipesHandler.CheckDietaryLabels)
+	app.Put("/recipes/:id/favorite", authMiddleware.RequireAuth, recipesHandler.FavoriteRecipe)
+	app.Delete("/recipes/:id/favorite", authMiddleware.RequireAuth, recipesHandler.UnfavoriteRecipe)
+	app.Put("/recipes/:id/collections", authMiddleware.RequireAuth, recipesHandler.UpdateRecipeCollections)
+	app.Post("/recipes/:id/collections", authMiddleware.RequireAuth, recipesHandler.AddRecipeToNewCollection)
+	app.Get("/recipes/:id/photos/:photoId", authMiddleware.RequireAuth, recipesHandler.GetPhoto)
+	app.Get("/recipes/:id/cooklang", authMiddleware.RequireAuth, cooklangHandler.Export)
 
 	app.Delete("/recipes/:id", authMiddleware.RequireAuth, recipesHandler.DeleteRecipe)
 	app.Patch("/recipes/:id", authMiddleware.RequireAuth, recipesHandler.UpdateRecipe)
-	app.Post("/recipes", authMiddleware.RequireAuth, recipesHandler.CreateRecipe)
+	app.Post("/recipes", authMiddleware.RequireAuth, limiter.Handler(ratelimit.ImportPolicy), recipesHandler.CreateRecipe)
+
+	app.Get("/collections", authMiddleware.RequireAuth, recipesHandler.GetCollections)
+	app.Post("/collections", authMiddleware.RequireAuth, recipesHandler.CreateCollection)
+	app.Get("/collections/:id", authMiddleware.RequireAuth, recipesHandler.GetCollection)
+	app.Patch("/collections/:id", authMiddleware.RequireAuth, recipesHandler.RenameCollection)
+	app.Delete("/collections/:id", authMiddleware.RequireAuth, recipesHandler.DeleteCollection)
+	app.Post("/collections/:id/order", authMiddleware.RequireAuth, recipesHandler.ReorderCollection)
+	app.Delete("/collections/:id/recipes/:recipeId", authMiddleware.RequireAuth, recipesHandler.RemoveFromCollection)
+
+	app.Get("/cookbook", authMiddleware.RequireAuth, cookbookHandler.GetCookbookForm)
+	app.Get("/cookbook/pdf", authMiddleware.RequireAuth, cookbookHandler.GetCookbookPDF)
+
+	app.Get("/settings", authMiddleware.RequireAuth, importHandler.Settings)
+	app.Post("/settings/import", authMiddleware.RequireAuth, limiter.Handler(ratelimit.BulkImportPolicy), importHandler.Import)
+	app.Get("/export", authMiddleware.RequireAuth, exportHandler.Export)
+
+	app.Get("/admin/usage", authMiddleware.RequireAuth, authMiddleware.RequireAdmin, usageHandler.GetReport)
+	app.Get("/admin/llm-cache", authMiddleware.RequireAuth, authMiddleware.RequireAdmin, recipesHandler.GetCacheStats)
 
 	app.Get("/login", authHandler.LoginPage)
-	app.Get("/auth/:provider", authHandler.Login)
-	app.Get("/auth/:provider/callback", authHandler.Callback)
+	app.Get("/auth/:provider", limiter.Handler(ratelimit.AuthPolicy), authHandler.Login)
+	app.Get("/auth/:provider/callback", limiter.Handler(ratelimit.AuthCallbackPolicy), authHandler.Callback)
 	app.Get("/logout", authHandler.Logout)
 
 	if viper.GetBool("DEV_MODE") {
@@ -128,9 +252,7 @@ func main() {
 	log.Fatal(app.Listen(":" + port))
 }
 
-func useProviders() {
-	googleClientID := viper.GetString("GOOGLE_CLIENT_ID")
-	googleClientSecret := viper.GetString("GOOGLE_CLIENT_SECRET")
+func useBaseURL() string {
 	baseURL := viper.GetString("BASE_URL")
 
 	if baseURL == "" {
@@ -141,6 +263,13 @@ func useProviders() {
 		baseURL = fmt.Sprintf("%s:%s", baseURL, viper.GetString("PORT"))
 	}
 
+	return baseURL
+}
+
+func useProviders(baseURL string) {
+	googleClientID := viper.GetString("GOOGLE_CLIENT_ID")
+	googleClientSecret := viper.GetString("GOOGLE_CLIENT_SECRET")
+
 	var providers []goth.Provider
 
 	if googleClientID != "" && googleClientSecret != "" {